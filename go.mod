@@ -2,4 +2,8 @@ module github.com/miguel250/streaming-setup
 
 go 1.13
 
-require github.com/miguel250/kuma v0.0.0-20200914005832-16b4722b4a08
+require (
+	github.com/gorilla/websocket v1.4.2
+	github.com/miguel250/kuma v0.0.0-20200914005832-16b4722b4a08
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
+)
@@ -1,25 +1,42 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/miguel250/kuma/http/server"
 	"github.com/miguel250/streaming-setup/server/api/auth"
+	"github.com/miguel250/streaming-setup/server/api/channel"
 	"github.com/miguel250/streaming-setup/server/api/goals"
+	"github.com/miguel250/streaming-setup/server/api/status"
 	"github.com/miguel250/streaming-setup/server/api/triggers"
 	"github.com/miguel250/streaming-setup/server/cache"
 	"github.com/miguel250/streaming-setup/server/chat/commands"
+	"github.com/miguel250/streaming-setup/server/chat/emotes"
 	"github.com/miguel250/streaming-setup/server/config"
+	"github.com/miguel250/streaming-setup/server/eventsub"
 	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/live"
+	"github.com/miguel250/streaming-setup/server/protection"
 	"github.com/miguel250/streaming-setup/server/refresher"
+	"github.com/miguel250/streaming-setup/server/rewards"
 	"github.com/miguel250/streaming-setup/server/stream"
 	"github.com/miguel250/streaming-setup/server/twitch"
 	"github.com/miguel250/streaming-setup/server/twitchemotes"
 )
 
+// Delays between attempts to create the EventSub webhook subscriptions.
+const (
+	eventSubRetryDelay    = 30 * time.Second
+	maxEventSubRetryDelay = 10 * time.Minute
+)
+
 func main() {
 
 	mux := http.NewServeMux()
@@ -56,49 +73,104 @@ func main() {
 		AuthURL:     conf.Twitch.AuthURL,
 		TwitchURL:   conf.Twitch.APIURL,
 		RedirectURL: fmt.Sprintf("%s/api/auth", srv.Addr),
-		BadgeURL:    conf.Twitch.BadgesURL,
 		ClientID:    conf.Twitch.ClientID,
 		Secret:      conf.Twitch.Secret,
 	}
 
 	c := cache.New()
+	if conf.Cache.Path != "" {
+		c, err = cache.Open(conf.Cache.Path, conf.Cache.TokenPath, os.Getenv(cache.KeyEnv))
+		if err != nil {
+			log.Fatalf("Failed to load cache with %s", err)
+		}
+	}
+
 	apiClient, err := twitch.New(twitchConf, c)
 	if err != nil {
 		log.Fatalf("Failed to create Twitch API client with %s", err)
 	}
 
-	globalBadges, err := apiClient.GetGlobalBadges()
+	globalBadges, err := apiClient.GetGlobalBadges(context.Background())
 
+	// Helix needs a token for badges, chat works without them until the
+	// account is authenticated
 	if err != nil {
-		log.Fatalf("Failed to load badges for channel %s", err)
+		log.Printf("Failed to load global badges with %s", err)
+		globalBadges = make(map[string]*twitch.BadgeVersion)
 	}
 
-	channelBadges, err := apiClient.Channel.GetBadges(conf.Twitch.ChannelID)
+	channelBadges, err := apiClient.Channel.GetBadges(context.Background(), conf.Twitch.ChannelID)
 
 	if err != nil {
-		log.Fatalf("Failed to load badges for channel %s", err)
+		log.Printf("Failed to load badges for channel %s", err)
 	}
 
 	for key, val := range channelBadges {
 		globalBadges[key] = val
 	}
 
-	worker := refresher.New(conf, c, apiClient, event)
+	tracker := live.New(event)
+	worker := refresher.New(conf, c, apiClient, event, tracker)
 	worker.Refresher()
 
-	mux.Handle("/api/goals", goals.New(conf, c))
+	goalsAPI := goals.New(conf, c, event)
+	goalsAPI.Start()
+	defer goalsAPI.Close()
+	mux.Handle("/api/goals", goalsAPI)
+	mux.Handle("/api/stream", status.New(tracker))
 	mux.Handle("/api/auth", auth.New(conf, apiClient, c))
 	mux.Handle("/api/triggers/", triggers.New(event, conf))
-	emotesAPI, err := twitchemotes.New(conf.Twitch.Emote.URL)
 
+	channelAPI := channel.New(conf, apiClient)
+	mux.Handle("/api/channel", channelAPI)
+	mux.Handle("/api/channel/", channelAPI)
+
+	// chat notices are only forwarded when EventSub isn't sending the
+	// same events
+	eventSubEnabled := !conf.Twitch.UsePolling()
+	forwarder := eventsub.NewForwarder(event, c)
+	forwarder.TrackStream(tracker)
+	forwarder.ReportAnnounced(worker)
+
+	switch conf.Twitch.EventSource {
+	case "", config.PollingSource:
+	case config.WebhookSource:
+		webhook, err := eventsub.NewWebhook(conf.Twitch.EventSub.Secret, forwarder)
+		if err != nil {
+			log.Fatalf("Failed to create EventSub webhook with %s", err)
+		}
+
+		mux.Handle("/eventsub", webhook)
+
+		// the server is already listening for the verification request,
+		// follows and subscriptions need the user token
+		go func() {
+			<-worker.Authenticated()
+			subscribeEventSub(conf, apiClient)
+		}()
+	case config.WebSocketSource:
+		ws := eventsub.NewWebSocket(conf.Twitch.EventSub.WebSocketURL, apiClient, conf.Twitch.ChannelID, forwarder)
+		defer ws.Close()
+
+		// subscriptions over WebSocket need the user token
+		go func() {
+			<-worker.Authenticated()
+			ws.Start()
+		}()
+	default:
+		log.Fatalf("Unknown event source %s, use polling, webhook or websocket", conf.Twitch.EventSource)
+	}
+
+	emoteStore, err := loadEmoteStore(conf)
 	if err != nil {
-		log.Fatalf("Failed to create instance of emote API with %s", err)
+		log.Fatalf("Failed to create third-party emote providers with %s", err)
 	}
+	defer emoteStore.Close()
 
 	ircConf := conf.Twitch.IRC
 	ircConf.Badges = globalBadges
 	ircConf.TwitchAPI = apiClient
-	ircConf.TwitchEmotes = emotesAPI
+	ircConf.EmoteStore = emoteStore
 	chatClient, err := irc.New(&ircConf)
 	if err != nil {
 		log.Fatalf("Failed to connect to twitch server: %s", err)
@@ -109,22 +181,67 @@ func main() {
 		log.Fatalf("Failed to connect to Twitch chat server with %s", err)
 	}
 
+	go func() {
+		for state := range chatClient.OnStateChange {
+			log.Printf("Chat connection is %s\n", state)
+		}
+	}()
+
 	log.Println("Chat auth")
-	err = chatClient.Auth()
+	authCtx, cancelAuth := context.WithTimeout(context.Background(), 30*time.Second)
+	err = chatClient.Auth(authCtx)
+	cancelAuth()
+	if errors.Is(err, irc.ErrLoginFailed) {
+		log.Fatalf("Twitch chat server rejected the oauth token in twitch.irc.auth")
+	}
+
 	if err != nil {
 		log.Fatalf("Failed to auth against Twitch chat server with %s", err)
 	}
 
-	commandConfig, err := commands.NewConfig("commands.json")
-	if err != nil {
-		log.Fatalf("Failed to load command configuration with %s", err)
+	for _, chatChannel := range chatClient.Channels() {
+		commandConfig, err := loadCommandConfig(chatChannel)
+		if err != nil {
+			log.Fatalf("Failed to load command configuration for channel %s with %s", chatChannel, err)
+		}
+
+		cmd := commands.NewForChannel(chatClient, chatChannel, commandConfig)
+
+		// the user token can only change the stream of twitch.channel_id
+		if chatChannel == chatClient.DefaultChannel() {
+			cmd.AddChannelCommands(apiClient, conf.Twitch.ChannelID)
+			cmd.AddUptimeCommand(tracker)
+		}
+		cmd.Start()
+		defer cmd.Close()
 	}
 
-	cmd := commands.New(chatClient, commandConfig)
-	cmd.Start()
-	defer cmd.Close()
+	forwarder.HandleRedemptions(rewards.New(
+		conf.Twitch.Rewards,
+		apiClient,
+		event,
+		chatClient,
+		conf.Twitch.ChannelID,
+		conf.Twitch.IRC.Channel,
+	))
+
+	if conf.Twitch.Protection.Enabled() {
+		guard := protection.New(&conf.Twitch.Protection, event, chatClient, chatClient.DefaultChannel(), apiClient.Channel, conf.Twitch.ChannelID)
+		defer guard.Close()
+
+		worker.HandleFollows(guard)
+		forwarder.HandleFollows(guard)
+	}
+
+	go forwardUserNotices(chatClient.UserNoticeListener(), event, c, worker, chatClient.DefaultChannel(), eventSubEnabled)
+	go forwardRoomState(chatClient.RoomStateListener(), event, chatClient.DefaultChannel())
+	go forwardModeration(chatClient.ClearChatListener(), chatClient.ClearMessageListener(), event)
 
 	messageChannel := chatClient.MessageListener()
+	comboDetector := emotes.NewComboDetector(
+		conf.Twitch.Emote.ComboUsers,
+		time.Duration(conf.Twitch.Emote.ComboSeconds)*time.Second,
+	)
 
 	go func() {
 		for {
@@ -145,6 +262,13 @@ func main() {
 			}
 
 			event.Message <- e
+
+			// cheers in the other joined channels aren't for this stream
+			if msg.Bits > 0 && !eventSubEnabled && msg.Channel == chatClient.DefaultChannel() {
+				event.Send(stream.NewCheer, string(b))
+			}
+
+			forwardEmotes(msg, comboDetector, event)
 		}
 	}()
 
@@ -152,3 +276,215 @@ func main() {
 		log.Fatalf("http server failed with %s", err)
 	}
 }
+
+// forwardUserNotices sends chat subscriptions, raids and other notices to
+// the overlays as soon as they happen instead of waiting for the refresher.
+// Subscriptions, gifts and raids are skipped when eventSub sends them.
+// Only notices from channel are forwarded, the overlays are for that
+// stream.
+func forwardUserNotices(notices chan irc.UserNoticeEvent, event *stream.Event, c *cache.Cache, announcer eventsub.Announcer, channel string, eventSub bool) {
+	for notice := range notices {
+		var eventType stream.EventType
+
+		if notice.Notice().Channel != channel {
+			continue
+		}
+
+		switch notice.(type) {
+		case *irc.SubEvent, *irc.ResubEvent, *irc.MysteryGiftEvent, *irc.GiftSubEvent, *irc.RaidEvent:
+			if eventSub {
+				continue
+			}
+		}
+
+		switch n := notice.(type) {
+		case *irc.SubEvent:
+			// the refresher would alert again for the same subscriber
+			announcer.Announced(cache.SubscriberBackfillKey, n.UserID)
+			c.Set(cache.LastSubscribeIDKey, n.UserID)
+			c.Set(cache.LastSubscribeNameKey, n.DisplayName)
+			event.Send(stream.NewSubscriber, n.DisplayName)
+			continue
+		case *irc.ResubEvent:
+			announcer.Announced(cache.SubscriberBackfillKey, n.UserID)
+			eventType = stream.NewResubscription
+		case *irc.GiftSubEvent:
+			// the gift alert is the recipient's only alert
+			announcer.Announced(cache.SubscriberBackfillKey, n.RecipientID)
+			eventType = stream.NewGiftSubscription
+		case *irc.MysteryGiftEvent:
+			eventType = stream.NewMysteryGift
+		case *irc.RaidEvent:
+			eventType = stream.NewRaid
+		case *irc.RitualEvent:
+			eventType = stream.NewRitual
+		case *irc.BitsBadgeTierEvent:
+			eventType = stream.NewBitsBadgeTier
+		default:
+			continue
+		}
+
+		b, err := json.Marshal(notice)
+		if err != nil {
+			log.Printf("failed to encode user notice with %s\n", err)
+			continue
+		}
+		event.Send(eventType, string(b))
+	}
+}
+
+// forwardModeration removes messages from the chat overlay when they are
+// deleted or their author is banned or timed out.
+func forwardModeration(clearChat chan *irc.ClearChat, clearMessage chan *irc.ClearMessage, event *stream.Event) {
+	for {
+		select {
+		case msg, ok := <-clearChat:
+			if !ok {
+				return
+			}
+
+			b, err := json.Marshal(msg)
+			if err != nil {
+				log.Printf("failed to encode clear chat with %s\n", err)
+				continue
+			}
+
+			if msg.IsChatCleared() {
+				event.Send(stream.ClearChat, string(b))
+				continue
+			}
+			event.Send(stream.UserBanned, string(b))
+		case msg, ok := <-clearMessage:
+			if !ok {
+				return
+			}
+
+			b, err := json.Marshal(map[string][]string{
+				"message_ids": {msg.MessageID},
+			})
+			if err != nil {
+				log.Printf("failed to encode deleted message with %s\n", err)
+				continue
+			}
+			event.Send(stream.DeleteMessage, string(b))
+		}
+	}
+}
+
+// forwardEmotes sends every emote in a message to the emote wall overlay.
+func forwardEmotes(msg *irc.Message, detector *emotes.ComboDetector, event *stream.Event) {
+	for _, usage := range emotes.Count(msg) {
+		b, err := json.Marshal(usage)
+		if err != nil {
+			log.Printf("failed to encode emote usage with %s\n", err)
+			continue
+		}
+		event.Send(stream.EmoteUsed, string(b))
+
+		combo, ok := detector.Add(msg.DisplayName, usage)
+		if !ok {
+			continue
+		}
+
+		b, err = json.Marshal(combo)
+		if err != nil {
+			log.Printf("failed to encode emote combo with %s\n", err)
+			continue
+		}
+		event.Send(stream.EmoteCombo, string(b))
+	}
+}
+
+// forwardRoomState lets the overlays show when slow, followers-only or other
+// chat modes are on in channel.
+func forwardRoomState(states chan *irc.RoomState, event *stream.Event, channel string) {
+	for state := range states {
+		if state.Channel != channel {
+			continue
+		}
+
+		b, err := json.Marshal(state)
+		if err != nil {
+			log.Printf("failed to encode room state with %s\n", err)
+			continue
+		}
+		event.Send(stream.RoomStateChanged, string(b))
+	}
+}
+
+// subscribeEventSub subscribes the webhook to the channel events, the
+// callback has to be reachable for Twitch to enable the subscriptions. It
+// retries until every subscription is created, the ones that already exist
+// are skipped.
+func subscribeEventSub(conf *config.Config, apiClient *twitch.API) {
+	delay := eventSubRetryDelay
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := eventsub.Subscribe(ctx, apiClient, conf.Twitch.ChannelID, twitch.EventSubTransport{
+			Method:   twitch.WebhookMethod,
+			Callback: conf.Twitch.EventSub.CallbackURL,
+			Secret:   conf.Twitch.EventSub.Secret,
+		})
+		cancel()
+
+		if err == nil {
+			return
+		}
+
+		log.Printf("Failed to subscribe to EventSub with %s, retrying in %s", err, delay)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxEventSubRetryDelay {
+			delay = maxEventSubRetryDelay
+		}
+	}
+}
+
+// loadEmoteStore loads BTTV, FFZ and 7TV emotes for the channel and keeps
+// them up to date. Failing to reach a service only logs, chat still works
+// without its emotes.
+func loadEmoteStore(conf *config.Config) (*twitchemotes.Store, error) {
+	emoteConf := conf.Twitch.Emote
+	providers := make([]twitchemotes.Provider, 0, 3)
+
+	if emoteConf.BTTVURL != "" {
+		provider, err := twitchemotes.NewBTTV(emoteConf.BTTVURL)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	if emoteConf.FFZURL != "" {
+		provider, err := twitchemotes.NewFFZ(emoteConf.FFZURL)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	if emoteConf.SevenTVURL != "" {
+		provider, err := twitchemotes.NewSevenTV(emoteConf.SevenTVURL)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	store := twitchemotes.NewStore(providers, conf.Twitch.ChannelID)
+
+	if err := store.Load(); err != nil {
+		log.Println(err)
+	}
+
+	store.Start(time.Duration(emoteConf.RefreshMinutes) * time.Minute)
+	return store, nil
+}
+
+// loadCommandConfig loads commands_<channel>.json so every channel has its
+// own commands, the shared commands.json is only copied on the first run.
+func loadCommandConfig(channel string) (*commands.Config, error) {
+	return commands.NewConfigFrom(fmt.Sprintf("commands_%s.json", channel), "commands.json")
+}
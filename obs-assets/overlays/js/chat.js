@@ -6,6 +6,108 @@
     stack.push(JSON.parse(e.data))
   })
 
+  const removeMessages = (ids) => {
+    if (!ids) {
+      return
+    }
+
+    ids.forEach((id) => {
+      const index = stack.findIndex((data) => data.id === id);
+      if (index !== -1) {
+        stack.splice(index, 1);
+      }
+
+      document.querySelectorAll(`.box[data-id="${id}"]`).forEach((box) => {
+        box.remove();
+      });
+    });
+  };
+
+  events.addEventListener("delete_message", async (e) => {
+    removeMessages(JSON.parse(e.data).message_ids);
+  })
+
+  events.addEventListener("user_banned", async (e) => {
+    removeMessages(JSON.parse(e.data).message_ids);
+  })
+
+  events.addEventListener("clear_chat", async (e) => {
+    const channel = JSON.parse(e.data).channel;
+
+    for (let i = stack.length - 1; i >= 0; i--) {
+      if (stack[i].channel === channel) {
+        stack.splice(i, 1);
+      }
+    }
+
+    document.querySelectorAll(`.box[data-channel="${channel}"]`).forEach((box) => {
+      box.remove();
+    });
+  })
+
+  const roomState = document.createElement("div");
+  roomState.classList.add("room-state", "room-state-hide");
+  document.body.appendChild(roomState);
+
+  events.addEventListener("room_state", async (e) => {
+    const data = JSON.parse(e.data);
+    const modes = [];
+
+    if (data.slow > 0) {
+      modes.push(`Slow mode ${data.slow}s`);
+    }
+
+    if (data.followers_only >= 0) {
+      modes.push("Followers-only");
+    }
+
+    if (data.subs_only) {
+      modes.push("Subs-only");
+    }
+
+    if (data.emote_only) {
+      modes.push("Emote-only");
+    }
+
+    if (data.r9k) {
+      modes.push("Unique chat");
+    }
+
+    roomState.innerText = modes.join(" · ");
+    roomState.classList.toggle("room-state-hide", modes.length === 0);
+  })
+
+  const renderFragment = (fragment) => {
+    switch (fragment.type) {
+      case "emote": {
+        const img = document.createElement("img");
+        img.src = fragment.url;
+        img.alt = fragment.text;
+        img.classList.add("emote");
+        return img;
+      }
+      case "cheermote": {
+        const cheer = document.createElement("span");
+        const img = document.createElement("img");
+        img.src = fragment.url;
+        const bits = document.createElement("span");
+        bits.style.color = fragment.color;
+        bits.innerText = fragment.bits;
+        cheer.append(img, bits);
+        return cheer;
+      }
+      case "mention":
+      case "link": {
+        const span = document.createElement("span");
+        span.classList.add(fragment.type);
+        span.innerText = fragment.text;
+        return span;
+      }
+      default:
+        return document.createTextNode(fragment.text);
+    }
+  };
+
   const showChatMessage = () => {
     setTimeout(() => {
       const data = stack.pop();
@@ -17,6 +119,8 @@
 
       const box = document.createElement("div");
       box.classList.add('box')
+      box.dataset.id = data.id;
+      box.dataset.channel = data.channel;
 
       if (data.profile_image !== "") {
         const profileImage = document.createElement("div");
@@ -31,7 +135,13 @@
       const message = document.createElement("div");
       message.classList.add('message')
 
-      message.innerHTML = `${data.message}`;
+      if (data.fragments) {
+        data.fragments.forEach((fragment) => {
+          message.appendChild(renderFragment(fragment));
+        });
+      } else {
+        message.innerText = data.message;
+      }
 
       const username = document.createElement('div');
 
@@ -0,0 +1,51 @@
+(() => {
+  const events = new EventSource("/events");
+  const maxEmotes = 50;
+  const combo = document.body.getElementsByClassName("combo")[0];
+  const comboEmote = document.body.getElementsByClassName("combo-emote")[0];
+  const comboCount = document.body.getElementsByClassName("combo-count")[0];
+  let comboTimeout;
+
+  const showEmote = (url) => {
+    if (document.querySelectorAll(".emote").length >= maxEmotes) {
+      return;
+    }
+
+    const img = document.createElement("img");
+    img.src = url;
+    img.classList.add("emote");
+    img.style.left = `${Math.random() * 90}vw`;
+    img.style.animationDuration = `${4 + Math.random() * 3}s`;
+    img.addEventListener("animationend", () => {
+      img.remove();
+    });
+    document.body.appendChild(img);
+  };
+
+  events.addEventListener("emote_used", async (e) => {
+    const data = JSON.parse(e.data);
+
+    for (let i = 0; i < data.count; i++) {
+      setTimeout(() => showEmote(data.url), i * 150);
+    }
+  });
+
+  events.addEventListener("emote_combo", async (e) => {
+    const data = JSON.parse(e.data);
+
+    comboEmote.src = data.url;
+    comboEmote.alt = data.code;
+    comboCount.innerText = `x${data.users} combo!`;
+    combo.classList.remove("combo-hide");
+
+    // restart the animation when the combo grows
+    combo.classList.remove("combo-bump");
+    void combo.offsetWidth;
+    combo.classList.add("combo-bump");
+
+    clearTimeout(comboTimeout);
+    comboTimeout = setTimeout(() => {
+      combo.classList.add("combo-hide");
+    }, 8000);
+  });
+})()
@@ -4,13 +4,15 @@
     return response.json();
   };
 
-  const updateHeader = async () => {
-    const data = await getFollowerData();
+  const updateHeader = (data) => {
     const followerNameElem = document.body.getElementsByClassName("new_follower_name")[0];
     const followerCounterElem = document.body.getElementsByClassName("follower_counter")[0];
     followerNameElem.innerText = data.follower_name;
     if (data.disable_follower_goal) {
-      document.body.getElementsByClassName("follower-goal")[0].remove();
+      const followerGoal = document.body.getElementsByClassName("follower-goal")[0];
+      if (followerGoal) {
+        followerGoal.remove();
+      }
       return
     }
     followerCounterElem.innerText = `${data.follower_total} / ${data.follower_goal}`
@@ -23,13 +25,9 @@
   }
 
   const events = new EventSource("/events");
-  events.addEventListener("new_follower", async (e) => {
-    await updateHeader();
+  events.addEventListener("goals_updated", (e) => {
+    updateHeader(JSON.parse(e.data));
   });
 
-  events.addEventListener("new_subscriber", async (e) => {
-    await updateHeader();
-  });
-
-  await updateHeader();
+  updateHeader(await getFollowerData());
 })();
@@ -19,6 +19,36 @@
     stack.push(obj);
   });
 
+  events.addEventListener("new_cheer", async (e) => {
+    const data = JSON.parse(e.data);
+    const obj = {
+      displayName: data["display-name"],
+      message: `Cheered ${data.bits} bits!`,
+      eventType: "user_notice",
+    }
+    stack.push(obj);
+  });
+
+  const noticeMessages = {
+    new_resubscription: (data) => `Resubscribed for ${data.cumulative_months} months!`,
+    new_gift_subscription: (data) => `Gifted a sub to ${data.recipient_display_name}!`,
+    new_mystery_gift: (data) => `Gifted ${data.count} subs!`,
+    new_raid: (data) => `Is raiding with ${data.viewer_count} viewers!`,
+    new_ritual: (data) => data.system_message,
+    new_bits_badge_tier: (data) => `Unlocked the ${data.threshold} bits badge!`,
+  };
+
+  Object.keys(noticeMessages).forEach((eventType) => {
+    events.addEventListener(eventType, async (e) => {
+      const data = JSON.parse(e.data);
+      const obj = {
+        displayName: data.display_name,
+        message: noticeMessages[eventType](data),
+        eventType: "user_notice",
+      }
+      stack.push(obj);
+    });
+  });
 
   const showNotification = () => {
     setTimeout(() => {
@@ -41,6 +71,12 @@
         displayNameElem = document.body.getElementsByClassName("sub-display-name")[0];
       }
 
+      if (obj.eventType === "user_notice") {
+        elem = document.body.getElementsByClassName("user-notice")[0];
+        displayNameElem = document.body.getElementsByClassName("notice-display-name")[0];
+        document.body.getElementsByClassName("notice-message")[0].innerText = obj.message;
+      }
+
       if (elem != null) {
         elem.classList.remove("show");
         displayNameElem.innerText = obj.displayName;
@@ -48,7 +84,7 @@
 
         const newElem = elem.cloneNode(true);
         elem.parentNode.replaceChild(newElem, elem);
-        if (obj.eventType === "new_subscriber") {
+        if (obj.eventType === "new_subscriber" || obj.eventType === "user_notice") {
           audioSubscriberElem.currentTime = 0;
           audioSubscriberElem.volume = 1;
           audioSubscriberElem.play().then().catch(() => {
@@ -0,0 +1,54 @@
+(() => {
+  const events = new EventSource("/events");
+  const queue = [];
+  const redemption = document.body.getElementsByClassName("redemption")[0];
+  const animationElem = document.body.getElementsByClassName("redemption-animation")[0];
+  const title = document.body.getElementsByClassName("redemption-title")[0];
+  const user = document.body.getElementsByClassName("redemption-user")[0];
+  let showing = false;
+
+  const showNext = () => {
+    const data = queue.shift();
+
+    if (!data) {
+      showing = false;
+      return;
+    }
+
+    showing = true;
+    const action = data.action || {};
+    let animation;
+
+    title.innerText = data.reward.title;
+    user.innerText = data.user_input ? `${data.user_name}: ${data.user_input}` : data.user_name;
+
+    if (action.type === "overlay" && action.overlay) {
+      animation = Animation(animationElem, action.overlay);
+    }
+
+    if (action.type === "sound" && action.sound) {
+      new Audio(action.sound).play();
+    }
+
+    redemption.classList.remove("redemption-hide");
+
+    setTimeout(() => {
+      redemption.classList.add("redemption-hide");
+
+      setTimeout(() => {
+        if (animation) {
+          animation.destroy();
+        }
+        showNext();
+      }, 1000);
+    }, 6000);
+  };
+
+  events.addEventListener("new_redemption", async (e) => {
+    queue.push(JSON.parse(e.data));
+
+    if (!showing) {
+      showNext();
+    }
+  });
+})()
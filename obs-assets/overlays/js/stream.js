@@ -0,0 +1,39 @@
+(async () => {
+  let state = { online: false };
+
+  const pad = (n) => n.toString().padStart(2, "0");
+
+  const render = () => {
+    document.body.classList.toggle("stream-online", state.online);
+    document.body.classList.toggle("stream-offline", !state.online);
+
+    for (const elem of document.body.getElementsByClassName("viewer_count")) {
+      elem.innerText = state.online ? state.viewer_count : "";
+    }
+
+    for (const elem of document.body.getElementsByClassName("uptime")) {
+      if (!state.online || !state.started_at) {
+        elem.innerText = "";
+        continue;
+      }
+
+      const seconds = Math.max(0, Math.floor((Date.now() - Date.parse(state.started_at)) / 1000));
+      elem.innerText = `${Math.floor(seconds / 3600)}:${pad(Math.floor(seconds / 60) % 60)}:${pad(seconds % 60)}`;
+    }
+  };
+
+  const update = (e) => {
+    state = JSON.parse(e.data);
+    render();
+  };
+
+  const events = new EventSource("/events");
+  events.addEventListener("stream_online", update);
+  events.addEventListener("stream_offline", update);
+  events.addEventListener("viewer_count", update);
+
+  const response = await fetch("/api/stream");
+  state = await response.json();
+  render();
+  setInterval(render, 1000);
+})();
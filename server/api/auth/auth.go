@@ -24,7 +24,7 @@ func (api *Auth) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resp, err := api.twitchAPI.AuthUser(code)
+	resp, err := api.twitchAPI.AuthUser(req.Context(), code)
 
 	if err != nil {
 		log.Printf("Failed to get access token with %s", err)
@@ -0,0 +1,139 @@
+package channel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/miguel250/streaming-setup/server/config"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// Channel shows and changes the title, category and tags of the stream.
+//
+//	GET   /api/channel                     current information
+//	PATCH /api/channel                     {"title": "", "game": "", "tags": []}
+//	GET   /api/channel/categories?query=   categories matching query
+type Channel struct {
+	conf      *config.Config
+	twitchAPI *twitch.API
+}
+
+type updateRequest struct {
+	Title string    `json:"title"`
+	Game  string    `json:"game"`
+	Tags  *[]string `json:"tags"`
+}
+
+func (api *Channel) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	path := strings.TrimSuffix(req.URL.Path, "/")
+
+	switch {
+	case path == "/api/channel/categories" && req.Method == "GET":
+		api.searchCategories(rw, req)
+	case path == "/api/channel" && req.Method == "GET":
+		api.information(rw, req)
+	case path == "/api/channel" && req.Method == "PATCH":
+		api.update(rw, req)
+	case path == "/api/channel" || path == "/api/channel/categories":
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(rw, "unknown action", http.StatusNotFound)
+	}
+}
+
+func (api *Channel) information(rw http.ResponseWriter, req *http.Request) {
+	info, err := api.twitchAPI.Channel.GetInformation(req.Context(), api.conf.Twitch.ChannelID)
+	if err != nil {
+		log.Printf("Failed to get channel information with %s", err)
+		http.Error(rw, "invalid response from twitch API", http.StatusBadGateway)
+		return
+	}
+	writeJSON(rw, info)
+}
+
+// update only takes JSON, browsers can't send it to another origin without
+// a preflight so other pages can't change the stream.
+func (api *Channel) update(rw http.ResponseWriter, req *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		http.Error(rw, "content type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := &updateRequest{}
+	if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+		http.Error(rw, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	update := &twitch.ChannelUpdate{
+		Title: strings.TrimSpace(body.Title),
+		Tags:  body.Tags,
+	}
+
+	if game := strings.TrimSpace(body.Game); game != "" {
+		category, err := api.twitchAPI.FindCategory(req.Context(), game)
+		var notFound *twitch.CategoryNotFoundError
+		if errors.As(err, &notFound) {
+			http.Error(rw, fmt.Sprintf("unknown category, closest matches: %s", strings.Join(notFound.MatchNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+
+		if err != nil {
+			log.Printf("Failed to find category with %s", err)
+			http.Error(rw, "invalid response from twitch API", http.StatusBadGateway)
+			return
+		}
+		update.GameID = category.ID
+	}
+
+	if update.Title == "" && update.GameID == "" && update.Tags == nil {
+		http.Error(rw, "nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	err = api.twitchAPI.Channel.ModifyInformation(req.Context(), api.conf.Twitch.ChannelID, update)
+	if err != nil {
+		log.Printf("Failed to update channel information with %s", err)
+		http.Error(rw, "invalid response from twitch API", http.StatusBadGateway)
+		return
+	}
+	api.information(rw, req)
+}
+
+func (api *Channel) searchCategories(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query().Get("query")
+	if query == "" {
+		http.Error(rw, "missing query", http.StatusBadRequest)
+		return
+	}
+
+	categories, err := api.twitchAPI.SearchCategories(req.Context(), query, 20)
+	if err != nil {
+		log.Printf("Failed to search categories with %s", err)
+		http.Error(rw, "invalid response from twitch API", http.StatusBadGateway)
+		return
+	}
+	writeJSON(rw, categories)
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Printf("failed to encode json with %s", err)
+		http.Error(rw, "Server error", http.StatusInternalServerError)
+	}
+}
+
+func New(conf *config.Config, twitchAPI *twitch.API) *Channel {
+	return &Channel{
+		conf,
+		twitchAPI,
+	}
+}
@@ -4,65 +4,89 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/miguel250/streaming-setup/server/cache"
 	"github.com/miguel250/streaming-setup/server/config"
+	"github.com/miguel250/streaming-setup/server/stream"
 )
 
+// goalKeys are the cache keys shown by the goals.
+var goalKeys = []string{
+	cache.TotalFollowerKey,
+	cache.LastFollowerNameKey,
+	cache.TotalSubscribersKey,
+	cache.LastSubscribeNameKey,
+}
+
 type Goals struct {
-	conf  *config.Config
-	cache *cache.Cache
+	conf         *config.Config
+	cache        *cache.Cache
+	event        *stream.Event
+	subscription *cache.Subscription
 }
 
-func (api *Goals) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	cacheFollowerCount, _ := api.cache.Get(cache.TotalFollowerKey)
-	totalFollowerCount, _ := strconv.Atoi(cacheFollowerCount)
-	followerName, _ := api.cache.Get(cache.LastFollowerNameKey)
-	disableFollowerGoal := false
+type response struct {
+	DisableSubscriberGoal bool   `json:"disable_subscriber_follower_goal"`
+	DisableFollowerGoal   bool   `json:"disable_follower_goal"`
+	FollowerName          string `json:"follower_name"`
+	SubscriberName        string `json:"subscriber_name"`
+	FollowerGoal          int    `json:"follower_goal"`
+	FollowerTotal         int    `json:"follower_total"`
+	SubscriberGoal        int    `json:"subscriber_goal"`
+	SubscriberTotal       int    `json:"subscriber_total"`
+}
 
-	if api.conf.Twitch.FollowerGoalTotal == 0 {
-		disableFollowerGoal = true
+func (api *Goals) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if err := json.NewEncoder(rw).Encode(api.goals()); err != nil {
+		log.Printf("failed to encode json with %s", err)
+		http.Error(rw, "Server error", http.StatusInternalServerError)
 	}
+}
 
-	cacheTotalSubscribers, _ := api.cache.Get(cache.TotalSubscribersKey)
-	totalSubscribers, _ := strconv.Atoi(cacheTotalSubscribers)
-	subscriberName, _ := api.cache.Get(cache.LastSubscribeNameKey)
-	disableSubscriberGoal := false
+// Start sends goals_updated to the overlays every time a goal changes.
+func (api *Goals) Start() {
+	api.subscription = api.cache.Subscribe(goalKeys...)
 
-	if api.conf.Twitch.SubscriberGoalTotal == 0 {
-		disableSubscriberGoal = true
-	}
+	go func() {
+		for range api.subscription.C {
+			b, err := json.Marshal(api.goals())
+			if err != nil {
+				log.Printf("failed to encode goals with %s", err)
+				continue
+			}
+			api.event.Send(stream.GoalsUpdated, string(b))
+		}
+	}()
+}
 
-	response := struct {
-		DisableSubscriberGoal bool   `json:"disable_subscriber_follower_goal"`
-		DisableFollowerGoal   bool   `json:"disable_follower_goal"`
-		FollowerName          string `json:"follower_name"`
-		SubscriberName        string `json:"subscriber_name"`
-		FollowerGoal          int    `json:"follower_goal"`
-		FollowerTotal         int    `json:"follower_total"`
-		SubscriberGoal        int    `json:"subscriber_goal"`
-		SubscriberTotal       int    `json:"subscriber_total"`
-	}{
-		disableSubscriberGoal,
-		disableFollowerGoal,
-		followerName,
-		subscriberName,
-		api.conf.Twitch.FollowerGoalTotal,
-		totalFollowerCount,
-		api.conf.Twitch.SubscriberGoalTotal,
-		totalSubscribers,
+func (api *Goals) Close() {
+	if api.subscription != nil {
+		api.subscription.Close()
 	}
+}
 
-	if err := json.NewEncoder(rw).Encode(response); err != nil {
-		log.Printf("failed to encode json with %s", err)
-		http.Error(rw, "Server error", http.StatusInternalServerError)
+func (api *Goals) goals() *response {
+	totalFollowerCount, _ := api.cache.GetInt(cache.TotalFollowerKey)
+	followerName, _ := api.cache.Get(cache.LastFollowerNameKey)
+	totalSubscribers, _ := api.cache.GetInt(cache.TotalSubscribersKey)
+	subscriberName, _ := api.cache.Get(cache.LastSubscribeNameKey)
+
+	return &response{
+		DisableSubscriberGoal: api.conf.Twitch.SubscriberGoalTotal == 0,
+		DisableFollowerGoal:   api.conf.Twitch.FollowerGoalTotal == 0,
+		FollowerName:          followerName,
+		SubscriberName:        subscriberName,
+		FollowerGoal:          api.conf.Twitch.FollowerGoalTotal,
+		FollowerTotal:         totalFollowerCount,
+		SubscriberGoal:        api.conf.Twitch.SubscriberGoalTotal,
+		SubscriberTotal:       totalSubscribers,
 	}
 }
 
-func New(conf *config.Config, cache *cache.Cache) *Goals {
+func New(conf *config.Config, cache *cache.Cache, event *stream.Event) *Goals {
 	return &Goals{
-		conf,
-		cache,
+		conf:  conf,
+		cache: cache,
+		event: event,
 	}
 }
@@ -0,0 +1,31 @@
+package status
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/miguel250/streaming-setup/server/live"
+)
+
+// Status returns whether the stream is live, when it started, the viewer
+// count and the category.
+type Status struct {
+	tracker *live.Tracker
+}
+
+func (api *Status) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(rw).Encode(api.tracker.State())
+	if err != nil {
+		log.Printf("failed to encode json with %s", err)
+		http.Error(rw, "Server error", http.StatusInternalServerError)
+	}
+}
+
+func New(tracker *live.Tracker) *Status {
+	return &Status{
+		tracker,
+	}
+}
@@ -1,7 +1,11 @@
 package cache
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -16,17 +20,114 @@ const (
 	UserAccessCode       = "user_access_code"
 	UserAccessExpiresAt  = "user_access_expires_at"
 	UserRefreshCode      = "user_refresh_code"
+	// FollowerBackfillKey and SubscriberBackfillKey are the users seen by
+	// the last refresh, as JSON
+	FollowerBackfillKey   = "follower_backfill"
+	SubscriberBackfillKey = "subscriber_backfill"
 )
 
+// legacyTimeLayout is how token expiry was saved before SetTime, caches
+// saved to disk by older versions still have it.
+const legacyTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// subscriptionBuffer is how many changes a subscriber can fall behind
+// before the oldest ones are dropped.
+const subscriptionBuffer = 16
+
+var ErrNotFound = errors.New("cache: key not found")
+
+// Change is sent to subscribers when a key is set to a different value or
+// removed.
+type Change struct {
+	Key     string
+	Value   string
+	Deleted bool
+}
+
+// Subscription receives the changes of the keys it was created with.
+type Subscription struct {
+	C     <-chan Change
+	ch    chan Change
+	keys  map[string]bool
+	cache *Cache
+	once  sync.Once
+}
+
+// Close stops the subscription and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.cache.Lock()
+		defer s.cache.Unlock()
+		delete(s.cache.subscriptions, s)
+		close(s.ch)
+	})
+}
+
+// send never blocks, a subscriber that is behind loses its oldest change
+// so the latest value is always delivered.
+func (s *Subscription) send(change Change) {
+	if len(s.keys) > 0 && !s.keys[change.Key] {
+		return
+	}
+
+	for {
+		select {
+		case s.ch <- change:
+			return
+		default:
+		}
+
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
 type Cache struct {
 	sync.RWMutex
-	data map[string]string
+	data          map[string]string
+	expiresAt     map[string]time.Time
+	timers        map[string]*time.Timer
+	subscriptions map[*Subscription]struct{}
+	store         *store
+	now           func() time.Time
+}
+
+// entry is a change to a key, ttl is zero for keys that don't expire.
+type entry struct {
+	value  string
+	ttl    time.Duration
+	delete bool
+	// expired only deletes the key when its TTL has passed, the timer
+	// may fire after the key was set again
+	expired bool
 }
 
 func (c *Cache) Set(key, value string) {
-	c.Lock()
-	c.data[key] = value
-	c.Unlock()
+	c.apply(map[string]entry{key: {value: value}})
+}
+
+// SetWithTTL sets a key that is removed after ttl. Keys with a TTL are
+// only kept in memory.
+func (c *Cache) SetWithTTL(key, value string, ttl time.Duration) {
+	c.apply(map[string]entry{key: {value: value, ttl: ttl}})
+}
+
+// Expire removes an existing key after ttl, setting the key again
+// without a TTL keeps it forever.
+func (c *Cache) Expire(key string, ttl time.Duration) error {
+	value, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+
+	c.apply(map[string]entry{key: {value: value, ttl: ttl}})
+	return nil
+}
+
+func (c *Cache) Delete(key string) {
+	c.apply(map[string]entry{key: {delete: true}})
 }
 
 func (c *Cache) Get(key string) (string, error) {
@@ -35,22 +136,209 @@ func (c *Cache) Get(key string) (string, error) {
 
 	v, ok := c.data[key]
 
-	if !ok {
-		return "", fmt.Errorf("failed to find key %s", key)
+	if !ok || c.isExpired(key) {
+		return "", fmt.Errorf("failed to find key %s with %w", key, ErrNotFound)
 	}
 
 	return v, nil
 }
 
+func (c *Cache) SetInt(key string, value int) {
+	c.Set(key, strconv.Itoa(value))
+}
+
+func (c *Cache) GetInt(key string) (int, error) {
+	v, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse key %s as int with %w", key, err)
+	}
+	return i, nil
+}
+
+func (c *Cache) SetTime(key string, value time.Time) {
+	c.Set(key, value.Format(time.RFC3339Nano))
+}
+
+func (c *Cache) GetTime(key string) (time.Time, error) {
+	v, err := c.Get(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err == nil {
+		return t, nil
+	}
+
+	t, legacyErr := time.Parse(legacyTimeLayout, v)
+	if legacyErr != nil {
+		return time.Time{}, fmt.Errorf("failed to parse key %s as time with %w", key, err)
+	}
+	return t, nil
+}
+
+// SetJSON saves v encoded as JSON.
+func (c *Cache) SetJSON(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode key %s with %w", key, err)
+	}
+
+	c.Set(key, string(b))
+	return nil
+}
+
+// GetJSON decodes the value saved by SetJSON into v.
+func (c *Cache) GetJSON(key string, v interface{}) error {
+	value, err := c.Get(key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return fmt.Errorf("failed to parse key %s as json with %w", key, err)
+	}
+	return nil
+}
+
+// Subscribe sends the changes of keys to the subscription, every key is
+// sent when keys is empty.
+func (c *Cache) Subscribe(keys ...string) *Subscription {
+	ch := make(chan Change, subscriptionBuffer)
+	s := &Subscription{
+		C:     ch,
+		ch:    ch,
+		keys:  make(map[string]bool, len(keys)),
+		cache: c,
+	}
+
+	for _, key := range keys {
+		s.keys[key] = true
+	}
+
+	c.Lock()
+	c.subscriptions[s] = struct{}{}
+	c.Unlock()
+	return s
+}
+
 func (c *Cache) SetAccessToken(token, refreshToken string, expiresIn int64) {
 	expires := time.Now().Add(time.Duration(expiresIn) * time.Second)
-	c.Set(UserAccessCode, token)
-	c.Set(UserAccessExpiresAt, expires.Local().String())
-	c.Set(UserRefreshCode, refreshToken)
+	c.apply(map[string]entry{
+		UserAccessCode:      {value: token},
+		UserAccessExpiresAt: {value: expires.Format(time.RFC3339Nano)},
+		UserRefreshCode:     {value: refreshToken},
+	})
+}
+
+// apply changes all keys at once, so the tokens are written to disk
+// together.
+func (c *Cache) apply(entries map[string]entry) {
+	if c.store != nil {
+		c.store.Lock()
+		defer c.store.Unlock()
+	}
+
+	c.Lock()
+	// dirty has the files to write, keyed by tokenKeys
+	dirty := map[bool]bool{}
+
+	for key, e := range entries {
+		key := key
+		old, present := c.data[key]
+		ok := present && !c.isExpired(key)
+		_, hadTTL := c.expiresAt[key]
+
+		if e.delete {
+			if e.expired && (!hadTTL || !c.isExpired(key)) {
+				continue
+			}
+
+			c.clearTTL(key)
+			delete(c.data, key)
+
+			// expired keys are announced when they are removed
+			if present {
+				c.notify(Change{Key: key, Deleted: true})
+			}
+
+			if !hadTTL {
+				dirty[tokenKeys[key]] = true
+			}
+			continue
+		}
+
+		c.data[key] = e.value
+		c.clearTTL(key)
+
+		if e.ttl > 0 {
+			c.expiresAt[key] = c.now().Add(e.ttl)
+			c.timers[key] = time.AfterFunc(e.ttl, func() {
+				c.apply(map[string]entry{key: {delete: true, expired: true}})
+			})
+		}
+
+		changed := !ok || old != e.value
+		if changed {
+			c.notify(Change{Key: key, Value: e.value})
+		}
+
+		if changed || hadTTL != (e.ttl > 0) {
+			dirty[tokenKeys[key]] = true
+		}
+	}
+
+	if c.store == nil || len(dirty) == 0 {
+		c.Unlock()
+		return
+	}
+
+	// keys with a TTL are gone by the next start
+	snapshot := make(map[string]string, len(c.data))
+	for key, value := range c.data {
+		if _, ok := c.expiresAt[key]; !ok {
+			snapshot[key] = value
+		}
+	}
+	c.Unlock()
+
+	for isToken := range dirty {
+		if err := c.store.save(isToken, snapshot); err != nil {
+			log.Printf("Failed to save cache to disk with %s", err)
+		}
+	}
+}
+
+func (c *Cache) isExpired(key string) bool {
+	at, ok := c.expiresAt[key]
+	return ok && !c.now().Before(at)
+}
+
+func (c *Cache) clearTTL(key string) {
+	if timer, ok := c.timers[key]; ok {
+		timer.Stop()
+	}
+	delete(c.timers, key)
+	delete(c.expiresAt, key)
+}
+
+func (c *Cache) notify(change Change) {
+	for s := range c.subscriptions {
+		s.send(change)
+	}
 }
 
 func New() *Cache {
 	return &Cache{
-		data: make(map[string]string),
+		data:          make(map[string]string),
+		expiresAt:     make(map[string]time.Time),
+		timers:        make(map[string]*time.Timer),
+		subscriptions: make(map[*Subscription]struct{}),
+		now:           time.Now,
 	}
 }
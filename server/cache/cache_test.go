@@ -1,9 +1,12 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestGetSet(t *testing.T) {
@@ -57,3 +60,171 @@ func TestParallel(t *testing.T) {
 	close(start)
 	wg.Wait()
 }
+
+func TestGetMissing(t *testing.T) {
+	c := New()
+
+	if _, err := c.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	c := New()
+
+	c.SetInt(TotalFollowerKey, 42)
+	if got, err := c.GetInt(TotalFollowerKey); err != nil || got != 42 {
+		t.Errorf("int doesn't match want: 42, got: %d, %v", got, err)
+	}
+
+	c.Set("name", "miguelcodetv")
+	if _, err := c.GetInt("name"); err == nil {
+		t.Error("expected an error parsing a string as int")
+	}
+
+	want := time.Date(2021, 3, 10, 15, 4, 21, 123, time.UTC)
+	c.SetTime(UserAccessExpiresAt, want)
+	if got, err := c.GetTime(UserAccessExpiresAt); err != nil || !got.Equal(want) {
+		t.Errorf("time doesn't match want: %s, got: %s, %v", want, got, err)
+	}
+
+	// caches saved by older versions have the time in the old layout
+	c.Set(UserAccessExpiresAt, want.Local().String())
+	if got, err := c.GetTime(UserAccessExpiresAt); err != nil || !got.Equal(want) {
+		t.Errorf("legacy time doesn't match want: %s, got: %s, %v", want, got, err)
+	}
+
+	type goal struct {
+		Name  string `json:"name"`
+		Total int    `json:"total"`
+	}
+
+	if err := c.SetJSON("goal", &goal{"followers", 100}); err != nil {
+		t.Fatalf("failed to set json with %s", err)
+	}
+
+	got := &goal{}
+	if err := c.GetJSON("goal", got); err != nil || got.Name != "followers" || got.Total != 100 {
+		t.Errorf("json doesn't match got: %+v, %v", got, err)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c := New()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.SetWithTTL("raid", "cool_user", time.Minute)
+	c.Set("forever", "value")
+
+	if err := c.Expire("forever", time.Hour); err != nil {
+		t.Fatalf("failed to expire key with %s", err)
+	}
+
+	if err := c.Expire("missing", time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+
+	if got, err := c.Get("raid"); err != nil || got != "cool_user" {
+		t.Errorf("key should be there before the ttl, got: %s, %v", got, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, err := c.Get("raid"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("key should expire after the ttl, got: %v", err)
+	}
+
+	if _, err := c.Get("forever"); err != nil {
+		t.Errorf("key shouldn't expire before its ttl, got: %v", err)
+	}
+
+	// setting the key again without a ttl keeps it
+	c.Set("forever", "value")
+	now = now.Add(2 * time.Hour)
+
+	if _, err := c.Get("forever"); err != nil {
+		t.Errorf("key shouldn't expire without ttl, got: %v", err)
+	}
+}
+
+func TestTTLTimer(t *testing.T) {
+	c := New()
+	sub := c.Subscribe("raid")
+	defer sub.Close()
+
+	c.SetWithTTL("raid", "cool_user", 10*time.Millisecond)
+
+	for _, want := range []Change{
+		{Key: "raid", Value: "cool_user"},
+		{Key: "raid", Deleted: true},
+	} {
+		select {
+		case got := <-sub.C:
+			if got != want {
+				t.Errorf("change doesn't match want: %+v, got: %+v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %+v", want)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c := New()
+	sub := c.Subscribe(TotalFollowerKey)
+	all := c.Subscribe()
+
+	c.SetInt(TotalFollowerKey, 1)
+	// the same value isn't a change
+	c.SetInt(TotalFollowerKey, 1)
+	c.Set(LastFollowerNameKey, "cool_user")
+	c.Delete(TotalFollowerKey)
+
+	want := []Change{
+		{Key: TotalFollowerKey, Value: "1"},
+		{Key: TotalFollowerKey, Deleted: true},
+	}
+
+	sub.Close()
+	got := []Change{}
+	for change := range sub.C {
+		got = append(got, change)
+	}
+
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("changes don't match want: %+v, got: %+v", want, got)
+	}
+
+	all.Close()
+	count := 0
+	for range all.C {
+		count++
+	}
+
+	if count != 3 {
+		t.Errorf("expected 3 changes for every key, got: %d", count)
+	}
+
+	// closed subscriptions don't receive changes
+	c.SetInt(TotalFollowerKey, 2)
+}
+
+func TestSubscribeKeepsLatest(t *testing.T) {
+	c := New()
+	sub := c.Subscribe(TotalFollowerKey)
+	defer sub.Close()
+
+	for i := 0; i < subscriptionBuffer*2; i++ {
+		c.SetInt(TotalFollowerKey, i)
+	}
+
+	var last Change
+	for len(sub.C) > 0 {
+		last = <-sub.C
+	}
+
+	if want := strconv.Itoa(subscriptionBuffer*2 - 1); last.Value != want {
+		t.Errorf("latest change doesn't match want: %s, got: %+v", want, last)
+	}
+}
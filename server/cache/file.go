@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyEnv is the environment variable with the passphrase used to encrypt
+// the token file, tokens are saved in plain text when it is empty.
+const KeyEnv = "STREAMING_CACHE_KEY"
+
+// encryptedPrefix marks token files written with a key, the salt for the
+// key and the sealed tokens follow it separated by a colon.
+const encryptedPrefix = "encrypted:v1:"
+
+// Parameters for deriving the encryption key from the passphrase with
+// scrypt, the recommended ones for interactive logins.
+const (
+	saltSize   = 16
+	scryptN    = 32768
+	scryptR    = 8
+	scryptP    = 1
+	aesKeySize = 32
+)
+
+var (
+	ErrMissingKey = errors.New("cache: token file is encrypted but no key was given")
+	ErrInvalidKey = errors.New("cache: failed to decrypt token file, the key doesn't match")
+)
+
+// tokenKeys are saved to the token file instead of the state file.
+var tokenKeys = map[string]bool{
+	UserAccessCode:      true,
+	UserAccessExpiresAt: true,
+	UserRefreshCode:     true,
+}
+
+// store writes the cache to disk, every write replaces the whole file so
+// a crash never leaves half a file behind. The lock is held from changing
+// the data until it is written, so an older copy never replaces a newer
+// one.
+type store struct {
+	sync.Mutex
+	path      string
+	tokenPath string
+	key       string
+	// salt is the one from the token file, the key is only derived again
+	// when a file with another salt is read
+	salt []byte
+	aead cipher.AEAD
+}
+
+// Open loads the cache saved at path and saves every change back to it.
+// The tokens are kept in tokenPath, or path with a .tokens suffix when
+// tokenPath is empty, and encrypted when key isn't empty.
+func Open(path, tokenPath, key string) (*Cache, error) {
+	if tokenPath == "" {
+		tokenPath = path + ".tokens"
+	}
+
+	s := &store{
+		path:      path,
+		tokenPath: tokenPath,
+		key:       key,
+	}
+
+	c := New()
+
+	state, err := s.read(path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.read(tokenPath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// there was no encrypted token file to take the salt from
+	if key != "" && s.aead == nil {
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("failed to create salt with %w", err)
+		}
+
+		if err := s.useSalt(salt); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range state {
+		if !tokenKeys[key] {
+			c.data[key] = value
+		}
+	}
+
+	for key, value := range tokens {
+		if tokenKeys[key] {
+			c.data[key] = value
+		}
+	}
+
+	c.store = s
+	return c, nil
+}
+
+// save writes data to the token file when isToken is set, or to the state
+// file otherwise.
+func (s *store) save(isToken bool, data map[string]string) error {
+	values := make(map[string]string)
+	for k, v := range data {
+		if tokenKeys[k] == isToken {
+			values[k] = v
+		}
+	}
+
+	body, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cache with %w", err)
+	}
+
+	if !isToken {
+		return writeFile(s.path, body, 0644)
+	}
+
+	if s.aead != nil {
+		body, err = s.encrypt(body)
+		if err != nil {
+			return err
+		}
+	}
+	return writeFile(s.tokenPath, body, 0600)
+}
+
+func (s *store) read(path string, isToken bool) (map[string]string, error) {
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file with %w", err)
+	}
+
+	if isToken && strings.HasPrefix(string(body), encryptedPrefix) {
+		body, err = s.decrypt(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse cache file %s with %w", path, err)
+	}
+	return values, nil
+}
+
+func (s *store) encrypt(body []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to create nonce with %w", err)
+	}
+
+	sealed := s.aead.Seal(nonce, nonce, body, nil)
+	encoded := base64.StdEncoding.EncodeToString(s.salt) + ":" + base64.StdEncoding.EncodeToString(sealed)
+	return []byte(encryptedPrefix + encoded), nil
+}
+
+func (s *store) decrypt(body []byte) ([]byte, error) {
+	if s.key == "" {
+		return nil, ErrMissingKey
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(string(body), encryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidKey
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token file with %w", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token file with %w", err)
+	}
+
+	if err := s.useSalt(salt); err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, ErrInvalidKey
+	}
+
+	nonce, sealed := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return plain, nil
+}
+
+// useSalt derives the key from the passphrase and salt, the token file is
+// written with the same salt from then on.
+func (s *store) useSalt(salt []byte) error {
+	aead, err := newAEAD(s.key, salt)
+	if err != nil {
+		return err
+	}
+
+	s.salt = salt
+	s.aead = aead
+	return nil
+}
+
+// newAEAD derives an AES-256 key from the passphrase with scrypt, so a
+// weak passphrase still takes long to guess.
+func newAEAD(key string, salt []byte) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(key), salt, scryptN, scryptR, scryptP, aesKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key with %w", err)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher with %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher with %w", err)
+	}
+	return aead, nil
+}
+
+// writeFile writes to a temporary file in the same directory and renames
+// it over path.
+func writeFile(path string, body []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file with %w", err)
+	}
+
+	// the rename failed or never happened
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file with %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file with %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file with %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to set cache file permissions with %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace cache file with %w", err)
+	}
+	return nil
+}
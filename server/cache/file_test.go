@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("failed to create temp dir with %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestOpenPersists(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		key  string
+	}{
+		{"plain text", ""},
+		{"encrypted", "correct horse battery staple"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := tempDir(t)
+			path := filepath.Join(dir, "cache.json")
+
+			c, err := Open(path, "", test.key)
+			if err != nil {
+				t.Fatalf("failed to open cache with %s", err)
+			}
+
+			c.Set(LastFollowerIDKey, "1234")
+			c.SetAccessToken("access_token", "refresh_token", 3600)
+
+			c, err = Open(path, "", test.key)
+			if err != nil {
+				t.Fatalf("failed to reopen cache with %s", err)
+			}
+
+			for key, want := range map[string]string{
+				LastFollowerIDKey: "1234",
+				UserAccessCode:    "access_token",
+				UserRefreshCode:   "refresh_token",
+			} {
+				if got, _ := c.Get(key); got != want {
+					t.Errorf("key %s doesn't match want: %s, got: %s", key, want, got)
+				}
+			}
+
+			state, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read state file with %s", err)
+			}
+
+			if strings.Contains(string(state), "access_token") {
+				t.Errorf("state file shouldn't have the tokens, got: %s", state)
+			}
+
+			tokens, err := ioutil.ReadFile(path + ".tokens")
+			if err != nil {
+				t.Fatalf("failed to read token file with %s", err)
+			}
+
+			if encrypted := test.key != ""; encrypted == strings.Contains(string(tokens), "refresh_token") {
+				t.Errorf("token file doesn't match encrypted: %t, got: %s", encrypted, tokens)
+			}
+
+			info, err := os.Stat(path + ".tokens")
+			if err != nil {
+				t.Fatalf("failed to stat token file with %s", err)
+			}
+
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("token file permissions don't match want: 0600, got: %o", perm)
+			}
+
+			files, _ := ioutil.ReadDir(dir)
+			if len(files) != 2 {
+				t.Errorf("temporary files were left behind, got: %d files", len(files))
+			}
+		})
+	}
+}
+
+func TestOpenKeyErrors(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "cache.json")
+	tokenPath := filepath.Join(dir, "tokens.json")
+
+	c, err := Open(path, tokenPath, "secret")
+	if err != nil {
+		t.Fatalf("failed to open cache with %s", err)
+	}
+	c.SetAccessToken("access_token", "refresh_token", 3600)
+
+	if _, err := Open(path, tokenPath, ""); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("expected missing key error, got: %v", err)
+	}
+
+	if _, err := Open(path, tokenPath, "wrong"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected invalid key error, got: %v", err)
+	}
+}
+
+func TestOpenMissingFiles(t *testing.T) {
+	c, err := Open(filepath.Join(tempDir(t), "cache.json"), "", "")
+	if err != nil {
+		t.Fatalf("failed to open cache with %s", err)
+	}
+
+	if _, err := c.Get(UserAccessCode); err == nil {
+		t.Error("expected an empty cache")
+	}
+}
+
+func TestOpenSaltPerFile(t *testing.T) {
+	dir := tempDir(t)
+	salts := make([]string, 0, 2)
+
+	for _, name := range []string{"first.json", "second.json"} {
+		path := filepath.Join(dir, name)
+
+		c, err := Open(path, "", "secret")
+		if err != nil {
+			t.Fatalf("failed to open cache with %s", err)
+		}
+		c.SetAccessToken("access_token", "refresh_token", 3600)
+
+		tokens, err := ioutil.ReadFile(path + ".tokens")
+		if err != nil {
+			t.Fatalf("failed to read token file with %s", err)
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(string(tokens), encryptedPrefix), ":", 2)
+		if len(parts) != 2 {
+			t.Fatalf("token file is missing the salt, got: %s", tokens)
+		}
+		salts = append(salts, parts[0])
+	}
+
+	if salts[0] == salts[1] {
+		t.Errorf("token files with the same key should have different salts, got: %s", salts[0])
+	}
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// twitchRequestTimeout bounds the Twitch API calls made by commands.
+const twitchRequestTimeout = 10 * time.Second
+
+// AddChannelCommands adds !title and !game to change the stream of
+// channelID, the account needs the channel:manage:broadcast scope.
+func (a *AvailableCommands) AddChannelCommands(api *twitch.API, channelID string) {
+	a.Lock()
+	defer a.Unlock()
+	a.commands["title"] = a.title(api, channelID)
+	a.commands["game"] = a.game(api, channelID)
+}
+
+// !title Building a chat bot in Go
+func (a *AvailableCommands) title(api *twitch.API, channelID string) *Command {
+	return &Command{
+		Description: "Change the stream title",
+		AllowRoles: []string{
+			"broadcaster",
+			"moderator",
+		},
+		Action: func(client *irc.Client, msg *irc.Message, allowRoles AllowRoles) error {
+			if err := allowRoles.Allow(msg); err != nil {
+				return fmt.Errorf("user is not allow to use command: %s", msg.DisplayName)
+			}
+
+			title := commandArgs(msg.Message)
+			if title == "" {
+				reply(client, msg, "Missing title !title <text>")
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), twitchRequestTimeout)
+			defer cancel()
+
+			err := api.Channel.ModifyInformation(ctx, channelID, &twitch.ChannelUpdate{Title: title})
+			if err != nil {
+				reply(client, msg, "Failed to update the title")
+				return fmt.Errorf("failed to update title with %w", err)
+			}
+
+			reply(client, msg, fmt.Sprintf("Title updated to: %s", title))
+			return nil
+		},
+	}
+}
+
+// !game Science & Technology
+func (a *AvailableCommands) game(api *twitch.API, channelID string) *Command {
+	return &Command{
+		Description: "Change the stream category",
+		AllowRoles: []string{
+			"broadcaster",
+			"moderator",
+		},
+		Action: func(client *irc.Client, msg *irc.Message, allowRoles AllowRoles) error {
+			if err := allowRoles.Allow(msg); err != nil {
+				return fmt.Errorf("user is not allow to use command: %s", msg.DisplayName)
+			}
+
+			name := commandArgs(msg.Message)
+			if name == "" {
+				reply(client, msg, "Missing category !game <name>")
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), twitchRequestTimeout)
+			defer cancel()
+
+			category, err := api.FindCategory(ctx, name)
+			var notFound *twitch.CategoryNotFoundError
+			if errors.As(err, &notFound) && len(notFound.Matches) > 0 {
+				reply(client, msg, fmt.Sprintf("Unable to find category %s, did you mean: %s", name, strings.Join(notFound.MatchNames(), ", ")))
+				return fmt.Errorf("failed to find category with %w", err)
+			}
+
+			if err != nil {
+				reply(client, msg, fmt.Sprintf("Unable to find category %s", name))
+				return fmt.Errorf("failed to find category with %w", err)
+			}
+
+			err = api.Channel.ModifyInformation(ctx, channelID, &twitch.ChannelUpdate{GameID: category.ID})
+			if err != nil {
+				reply(client, msg, "Failed to update the category")
+				return fmt.Errorf("failed to update category with %w", err)
+			}
+
+			reply(client, msg, fmt.Sprintf("Category updated to: %s", category.Name))
+			return nil
+		},
+	}
+}
+
+// commandArgs returns everything after the command name.
+func commandArgs(message string) string {
+	parts := strings.SplitN(message, " ", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+	"github.com/miguel250/streaming-setup/server/twitch"
+	twitch_util "github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+// newChannelAPI stands in for Helix and records the channel updates.
+func newChannelAPI(t *testing.T) (*twitch.API, func() []*twitch.ChannelUpdate) {
+	var (
+		mu      sync.Mutex
+		updates []*twitch.ChannelUpdate
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/search/categories", func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(rw, `{"data":[{"id":"33214","name":"Fortnite"},{"id":"509670","name":"Science & Technology"}]}`)
+	})
+
+	mux.HandleFunc("/helix/channels", func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != "PATCH" || req.URL.Query().Get("broadcaster_id") != "558843277" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+
+		update := &twitch.ChannelUpdate{}
+		json.NewDecoder(req.Body).Decode(update)
+
+		mu.Lock()
+		updates = append(updates, update)
+		mu.Unlock()
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	api, _ := twitch_util.TestCreateClientWithMux(t, mux, nil)
+
+	return api, func() []*twitch.ChannelUpdate {
+		mu.Lock()
+		defer mu.Unlock()
+		return updates
+	}
+}
+
+func TestChannelInformationCommands(t *testing.T) {
+	moderator := []*twitch.Badge{{Title: "Moderator"}}
+
+	for _, test := range []struct {
+		name        string
+		message     string
+		badges      []*twitch.Badge
+		wantReply   string
+		wantUpdate  *twitch.ChannelUpdate
+		wantErr     bool
+		wantNoReply bool
+	}{
+		{
+			name:       "title",
+			message:    "!title Building a chat bot in Go",
+			badges:     moderator,
+			wantReply:  "Title updated to: Building a chat bot in Go",
+			wantUpdate: &twitch.ChannelUpdate{Title: "Building a chat bot in Go"},
+		},
+		{
+			name:      "missing title",
+			message:   "!title ",
+			badges:    moderator,
+			wantReply: "Missing title !title <text>",
+		},
+		{
+			name:       "game",
+			message:    "!game science & technology",
+			badges:     moderator,
+			wantReply:  "Category updated to: Science & Technology",
+			wantUpdate: &twitch.ChannelUpdate{GameID: "509670"},
+		},
+		{
+			name:      "game without exact match",
+			message:   "!game science",
+			badges:    moderator,
+			wantReply: "Unable to find category science, did you mean: Fortnite, Science & Technology",
+			wantErr:   true,
+		},
+		{
+			name:      "missing game",
+			message:   "!game",
+			badges:    []*twitch.Badge{{Title: "Broadcaster"}},
+			wantReply: "Missing category !game <name>",
+		},
+		{
+			name:        "viewers can't change the title",
+			message:     "!title Free subs",
+			badges:      []*twitch.Badge{{Title: "Subscriber"}},
+			wantErr:     true,
+			wantNoReply: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			api, updates := newChannelAPI(t)
+			client, _ := util.CreateMockChatClient(t)
+			client.Start()
+
+			commands := New(client, &Config{})
+			commands.AddChannelCommands(api, "558843277")
+
+			msgChannel := client.MessageListener()
+
+			err := commands.parseMsg(&irc.Message{
+				Badges:      test.badges,
+				DisplayName: "AttackKopter",
+				Message:     test.message,
+				Channel:     "miguelcodetv",
+			})
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error doesn't match want error: %t, got: %v", test.wantErr, err)
+			}
+
+			if !test.wantNoReply {
+				if msg := <-msgChannel; msg.Message != test.wantReply {
+					t.Errorf("reply doesn't match want: %s, got: %s", test.wantReply, msg.Message)
+				}
+			}
+
+			got := updates()
+			if test.wantUpdate == nil {
+				if len(got) != 0 {
+					t.Errorf("channel shouldn't be updated, got: %v", got)
+				}
+				return
+			}
+
+			if len(got) != 1 || got[0].Title != test.wantUpdate.Title || got[0].GameID != test.wantUpdate.GameID {
+				t.Errorf("update doesn't match want: %+v, got: %v", test.wantUpdate, got)
+			}
+		})
+	}
+}
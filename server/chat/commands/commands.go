@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 
@@ -48,13 +49,22 @@ type AvailableCommands struct {
 	sync.RWMutex
 	conf     *Config
 	client   *irc.Client
+	channel  string
 	commands map[string]*Command
 	shutdown chan struct{}
 }
 
 func (a *AvailableCommands) Start() {
-	log.Println("Handling chat commands")
-	messageChannel := a.client.MessageListener()
+	var messageChannel chan *irc.Message
+
+	if a.channel != "" {
+		log.Printf("Handling chat commands for channel %s\n", a.channel)
+		messageChannel = a.client.ChannelMessageListener(a.channel)
+	} else {
+		log.Println("Handling chat commands")
+		messageChannel = a.client.MessageListener()
+	}
+
 	go func() {
 		for {
 			select {
@@ -88,6 +98,11 @@ func (a *AvailableCommands) parseMsg(msg *irc.Message) error {
 	return val.Action(a.client, msg, val.AllowRoles)
 }
 
+// reply sends a message back to the channel where msg came from.
+func reply(client *irc.Client, msg *irc.Message, text string) error {
+	return client.SendMessageTo(msg.Channel, text)
+}
+
 func (a *AvailableCommands) Close() {
 	a.shutdown <- struct{}{}
 }
@@ -97,14 +112,26 @@ func (a *AvailableCommands) printHelpCommand() *Command {
 		Description: "Print all chat bot commands",
 		Action: func(client *irc.Client, msg *irc.Message, allowRoles AllowRoles) error {
 			hiMsg := "Hi, here is a list of commands"
-			err := client.SendMessage(hiMsg)
+			err := reply(client, msg, hiMsg)
 			if err != nil {
 				log.Printf("Unable to send message with %s\n", err)
 			}
 
-			for key, value := range a.commands {
-				msg := fmt.Sprintf("- !%s - %s", key, value.Description)
-				err := client.SendMessage(msg)
+			a.RLock()
+			names := make([]string, 0, len(a.commands))
+			for key := range a.commands {
+				names = append(names, key)
+			}
+			a.RUnlock()
+			sort.Strings(names)
+
+			for _, key := range names {
+				a.RLock()
+				value := a.commands[key]
+				a.RUnlock()
+
+				helpMsg := fmt.Sprintf("- !%s - %s", key, value.Description)
+				err := reply(client, msg, helpMsg)
 				if err != nil {
 					log.Printf("Failed to send help command with %s\n", err)
 				}
@@ -130,18 +157,18 @@ func (a *AvailableCommands) shoutout() *Command {
 			msgSlice := strings.Split(msg.Message, " ")
 
 			if len(msgSlice) == 1 {
-				client.SendMessage("Missing username @example")
+				reply(client, msg, "Missing username @example")
 				return nil
 			}
 
 			username := msgSlice[1]
 			if username[0] != '@' {
-				client.SendMessage("username doesn't include @")
+				reply(client, msg, "username doesn't include @")
 				return nil
 			}
 
 			shoutoutMsg := fmt.Sprintf("Go checkout - http://twitch.tv/%s", username[1:])
-			client.SendMessage(shoutoutMsg)
+			reply(client, msg, shoutoutMsg)
 			return nil
 		},
 	}
@@ -163,14 +190,14 @@ func (a *AvailableCommands) addcmd() *Command {
 			msgSlice := strings.Split(msg.Message, "-")
 
 			if len(msgSlice) < 3 {
-				client.SendMessage("addcmd needs 3 args marked by '-'")
-				client.SendMessage("- !addcmd discord - description - Please join our discord server - url")
+				reply(client, msg, "addcmd needs 3 args marked by '-'")
+				reply(client, msg, "- !addcmd discord - description - Please join our discord server - url")
 				return nil
 			}
 
 			commandSlice := strings.Split(strings.Trim(msgSlice[0], " "), " ")
 			if len(commandSlice) != 2 {
-				client.SendMessage("Unable to parse command name")
+				reply(client, msg, "Unable to parse command name")
 				return nil
 			}
 
@@ -186,19 +213,19 @@ func (a *AvailableCommands) addcmd() *Command {
 
 			err := a.conf.Save()
 			if err != nil {
-				client.SendMessage("Failed to save command")
+				reply(client, msg, "Failed to save command")
 				return fmt.Errorf("failed to save command with %s", err)
 			}
 
-			client.SendMessage(fmt.Sprintf("Command (!%s - %s - %s) was added successfully.", commandName, description, message))
+			reply(client, msg, fmt.Sprintf("Command (!%s - %s - %s) was added successfully.", commandName, description, message))
 			return nil
 		},
 	}
 }
 
 func (a *AvailableCommands) AddCommand(cmd, message, description string) *Command {
-	action := func(client *irc.Client, _ *irc.Message, _ AllowRoles) error {
-		err := client.SendMessage(message)
+	action := func(client *irc.Client, msg *irc.Message, _ AllowRoles) error {
+		err := reply(client, msg, message)
 		if err != nil {
 			log.Printf("Failed to %s help command with %s\n", cmd, err)
 		}
@@ -215,10 +242,19 @@ func (a *AvailableCommands) AddCommand(cmd, message, description string) *Comman
 	return command
 }
 
+// New creates a chat bot that handles commands from every channel the client
+// is joined to.
 func New(client *irc.Client, conf *Config) *AvailableCommands {
+	return NewForChannel(client, "", conf)
+}
+
+// NewForChannel creates a chat bot with its own set of commands that only
+// handles messages from channel.
+func NewForChannel(client *irc.Client, channel string, conf *Config) *AvailableCommands {
 	available := &AvailableCommands{
 		conf:     conf,
 		client:   client,
+		channel:  channel,
 		commands: make(map[string]*Command),
 		shutdown: make(chan struct{}),
 	}
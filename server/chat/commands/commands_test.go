@@ -196,6 +196,53 @@ func TestDynamicAddedCommand(t *testing.T) {
 	testCompareGoldenFiles("testdata/dynamic_discord_command_result.json", receiveMessages, t)
 }
 
+func TestChannelCommands(t *testing.T) {
+	client, _ := util.CreateMockChatClient(t)
+	client.Start()
+
+	err := client.JoinChannel("attackkopter")
+	if err != nil {
+		t.Fatalf("failed to join channel with %s", err)
+	}
+
+	conf := &Config{
+		Commands: map[string]CommandConfig{
+			"hello": {
+				Description: "Say hello",
+				Message:     "Hello from attackkopter",
+			},
+		},
+	}
+
+	commands := NewForChannel(client, "attackkopter", conf)
+	commands.Start()
+	defer commands.Close()
+
+	msgChannel := client.ChannelMessageListener("attackkopter")
+
+	err = client.SendMessageTo("test_channel", "!hello")
+	if err != nil {
+		t.Fatalf("failed to send message with %s", err)
+	}
+
+	err = client.SendMessageTo("attackkopter", "!hello")
+	if err != nil {
+		t.Fatalf("failed to send message with %s", err)
+	}
+
+	for _, want := range []string{"!hello", "Hello from attackkopter"} {
+		msg := <-msgChannel
+
+		if msg.Channel != "attackkopter" {
+			t.Errorf("Channel doesn't match got: %s, want: attackkopter", msg.Channel)
+		}
+
+		if msg.Message != want {
+			t.Errorf("Message doesn't match got: %s, want: %s", msg.Message, want)
+		}
+	}
+}
+
 func testCompareGoldenFiles(wantFilename string, v interface{}, t *testing.T) {
 	got, err := json.Marshal(v)
 
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"sync"
 )
 
@@ -59,3 +60,23 @@ func NewConfig(path string) (*Config, error) {
 
 	return conf, nil
 }
+
+// NewConfigFrom loads path, the first time it doesn't exist it starts with
+// the commands from seedPath so commands added to it aren't shared with
+// every config seeded from the same file.
+func NewConfigFrom(path, seedPath string) (*Config, error) {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return NewConfig(path)
+	}
+
+	conf, err := NewConfig(seedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.path = path
+	if err := conf.Save(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewConfigFromSeed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commands")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir with %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	seedPath := filepath.Join(dir, "commands.json")
+	err = ioutil.WriteFile(seedPath, []byte(`{"commands":{"discord":{"description":"discord link","message":"join us"}}}`), 0644)
+	if err != nil {
+		t.Fatalf("failed to write seed configuration with %s", err)
+	}
+
+	first, err := NewConfigFrom(filepath.Join(dir, "commands_first.json"), seedPath)
+	if err != nil {
+		t.Fatalf("failed to load first channel configuration with %s", err)
+	}
+
+	if _, ok := first.Commands["discord"]; !ok {
+		t.Fatalf("seeded commands are missing, got: %v", first.Commands)
+	}
+
+	first.AddCommand("lurk", CommandConfig{Message: "enjoy the lurk"})
+	if err := first.Save(); err != nil {
+		t.Fatalf("failed to save first channel configuration with %s", err)
+	}
+
+	second, err := NewConfigFrom(filepath.Join(dir, "commands_second.json"), seedPath)
+	if err != nil {
+		t.Fatalf("failed to load second channel configuration with %s", err)
+	}
+
+	if _, ok := second.Commands["lurk"]; ok {
+		t.Error("commands added in one channel shouldn't show up in another")
+	}
+
+	reloaded, err := NewConfigFrom(filepath.Join(dir, "commands_first.json"), seedPath)
+	if err != nil {
+		t.Fatalf("failed to reload first channel configuration with %s", err)
+	}
+
+	if _, ok := reloaded.Commands["lurk"]; !ok {
+		t.Error("added command should be kept in the channel configuration")
+	}
+}
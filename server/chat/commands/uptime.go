@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/live"
+)
+
+// AddUptimeCommand adds !uptime with the time since the stream went live.
+func (a *AvailableCommands) AddUptimeCommand(tracker *live.Tracker) {
+	a.Lock()
+	defer a.Unlock()
+	a.commands["uptime"] = a.uptime(tracker)
+}
+
+// !uptime
+func (a *AvailableCommands) uptime(tracker *live.Tracker) *Command {
+	return &Command{
+		Description: "How long the stream has been live",
+		Action: func(client *irc.Client, msg *irc.Message, allowRoles AllowRoles) error {
+			uptime, ok := tracker.Uptime()
+			if !ok {
+				reply(client, msg, "The stream is offline")
+				return nil
+			}
+
+			reply(client, msg, fmt.Sprintf("Live for %s", formatUptime(uptime)))
+			return nil
+		},
+	}
+}
+
+// formatUptime returns the duration as 1h 2m 3s, leaving out the hours
+// when there are none.
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm %ds", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%dm %ds", minutes, seconds)
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+	"github.com/miguel250/streaming-setup/server/live"
+	"github.com/miguel250/streaming-setup/server/stream"
+)
+
+func TestUptimeCommand(t *testing.T) {
+	event := stream.New()
+	tracker := live.New(event)
+
+	client, _ := util.CreateMockChatClient(t)
+	client.Start()
+
+	commands := New(client, &Config{})
+	commands.AddUptimeCommand(tracker)
+	msgChannel := client.MessageListener()
+
+	uptime := func() string {
+		err := commands.parseMsg(&irc.Message{
+			DisplayName: "AttackKopter",
+			Message:     "!uptime",
+			Channel:     "miguelcodetv",
+		})
+
+		if err != nil {
+			t.Fatalf("failed to run command with %s", err)
+		}
+		return (<-msgChannel).Message
+	}
+
+	if got := uptime(); got != "The stream is offline" {
+		t.Errorf("offline reply doesn't match got: %s", got)
+	}
+
+	go tracker.Online(time.Now().Add(-2 * time.Hour))
+	<-event.Message
+
+	if got := uptime(); !strings.HasPrefix(got, "Live for 2h 0m") {
+		t.Errorf("online reply doesn't match got: %s", got)
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	for _, test := range []struct {
+		uptime time.Duration
+		want   string
+	}{
+		{42 * time.Second, "0m 42s"},
+		{5*time.Minute + 1500*time.Millisecond, "5m 2s"},
+		{3*time.Hour + 4*time.Minute + 5*time.Second, "3h 4m 5s"},
+		{26 * time.Hour, "26h 0m 0s"},
+	} {
+		if got := formatUptime(test.uptime); got != test.want {
+			t.Errorf("uptime doesn't match want: %s, got: %s", test.want, got)
+		}
+	}
+}
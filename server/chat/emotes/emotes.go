@@ -0,0 +1,147 @@
+package emotes
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+)
+
+const (
+	DefaultComboUsers  = 3
+	DefaultComboWindow = 30 * time.Second
+)
+
+// Usage is an emote used in a chat message, Count is how many times it
+// appears in the message.
+type Usage struct {
+	ID       string `json:"id"`
+	Code     string `json:"code"`
+	URL      string `json:"url"`
+	Provider string `json:"provider"`
+	Channel  string `json:"channel"`
+	Count    int    `json:"count"`
+}
+
+// Combo is sent when the same emote is used by many users in a short time.
+type Combo struct {
+	Usage
+	// Users is how many different users are part of the combo
+	Users int `json:"users"`
+}
+
+// Count groups the emotes of a message in the order they first appear.
+func Count(msg *irc.Message) []*Usage {
+	usages := make([]*Usage, 0)
+	seen := make(map[string]*Usage)
+
+	for _, fragment := range msg.Fragments {
+		if fragment.Type != irc.EmoteFragment {
+			continue
+		}
+
+		key := fragment.Provider + "/" + fragment.ID
+		if usage, ok := seen[key]; ok {
+			usage.Count++
+			continue
+		}
+
+		provider := fragment.Provider
+		if provider == "" {
+			provider = "twitch"
+		}
+
+		usage := &Usage{
+			ID:       fragment.ID,
+			Code:     fragment.Text,
+			URL:      fragment.URL,
+			Provider: provider,
+			Channel:  msg.Channel,
+			Count:    1,
+		}
+		seen[key] = usage
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+type combo struct {
+	users    map[string]struct{}
+	lastUsed time.Time
+}
+
+// ComboDetector finds emotes spammed by many users. A combo keeps growing
+// while users keep using the emote within the window of the last use.
+type ComboDetector struct {
+	sync.Mutex
+	users  int
+	window time.Duration
+	combos map[string]*combo
+	now    func() time.Time
+}
+
+// NewComboDetector creates a detector that fires once users different users
+// send the same emote with less than window between them.
+func NewComboDetector(users int, window time.Duration) *ComboDetector {
+	if users <= 0 {
+		users = DefaultComboUsers
+	}
+
+	if window <= 0 {
+		window = DefaultComboWindow
+	}
+
+	return &ComboDetector{
+		users:  users,
+		window: window,
+		combos: make(map[string]*combo),
+		now:    time.Now,
+	}
+}
+
+// Add records a use of the emote by user, it returns the combo when the
+// user made it reach the threshold or grow past it. A user using the emote
+// again doesn't grow the combo.
+func (d *ComboDetector) Add(user string, usage *Usage) (*Combo, bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	user = strings.ToLower(user)
+	key := usage.Channel + "/" + usage.Provider + "/" + usage.ID
+
+	current, ok := d.combos[key]
+	if !ok || now.Sub(current.lastUsed) > d.window {
+		current = &combo{users: make(map[string]struct{})}
+		d.combos[key] = current
+	}
+	current.lastUsed = now
+
+	d.expire(now)
+
+	if _, ok := current.users[user]; ok {
+		return nil, false
+	}
+	current.users[user] = struct{}{}
+
+	if len(current.users) < d.users {
+		return nil, false
+	}
+
+	result := &Combo{
+		Usage: *usage,
+		Users: len(current.users),
+	}
+	return result, true
+}
+
+// expire removes combos that ended so the map doesn't keep every emote
+// ever used.
+func (d *ComboDetector) expire(now time.Time) {
+	for key, current := range d.combos {
+		if now.Sub(current.lastUsed) > d.window {
+			delete(d.combos, key)
+		}
+	}
+}
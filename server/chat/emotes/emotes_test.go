@@ -0,0 +1,104 @@
+package emotes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+)
+
+var kappa = &Usage{
+	ID:       "25",
+	Code:     "Kappa",
+	URL:      "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0",
+	Provider: "twitch",
+	Channel:  "miguelcodetv",
+	Count:    1,
+}
+
+func TestCount(t *testing.T) {
+	msg := &irc.Message{
+		Channel: "miguelcodetv",
+		Fragments: []*irc.Fragment{
+			{Type: irc.EmoteFragment, Text: "Kappa", ID: "25", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0"},
+			{Type: irc.TextFragment, Text: " hi "},
+			{Type: irc.EmoteFragment, Text: "catJAM", ID: "5e76d338d6581c3724c0f0b2", URL: "https://cdn.betterttv.net/emote/5e76d338d6581c3724c0f0b2/2x", Provider: "bttv"},
+			{Type: irc.TextFragment, Text: " "},
+			{Type: irc.EmoteFragment, Text: "Kappa", ID: "25", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0"},
+		},
+	}
+
+	want := []*Usage{
+		{ID: "25", Code: "Kappa", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0", Provider: "twitch", Channel: "miguelcodetv", Count: 2},
+		{ID: "5e76d338d6581c3724c0f0b2", Code: "catJAM", URL: "https://cdn.betterttv.net/emote/5e76d338d6581c3724c0f0b2/2x", Provider: "bttv", Channel: "miguelcodetv", Count: 1},
+	}
+
+	got := Count(msg)
+
+	if !reflect.DeepEqual(got, want) {
+		for _, usage := range got {
+			t.Logf("got: %+v", usage)
+		}
+		t.Errorf("emote usage doesn't match")
+	}
+}
+
+func TestComboDetector(t *testing.T) {
+	now := time.Unix(1601065308, 0)
+	detector := NewComboDetector(3, 10*time.Second)
+	detector.now = func() time.Time {
+		return now
+	}
+
+	for _, step := range []struct {
+		name      string
+		user      string
+		after     time.Duration
+		wantCombo bool
+		wantUsers int
+	}{
+		{"first user", "ronni", 0, false, 0},
+		{"same user doesn't count twice", "Ronni", time.Second, false, 0},
+		{"second user", "attackkopter", time.Second, false, 0},
+		{"third user starts the combo", "sanjayshr", time.Second, true, 3},
+		{"combo grows", "erikdotdev", 9 * time.Second, true, 4},
+		{"users in the combo don't fire again", "ronni", time.Second, false, 0},
+		{"combo ends after the window", "mr_woodchuck", 11 * time.Second, false, 0},
+		{"new combo needs three users again", "tww2", time.Second, false, 0},
+	} {
+		now = now.Add(step.after)
+		combo, ok := detector.Add(step.user, kappa)
+
+		if ok != step.wantCombo {
+			t.Fatalf("%s: combo doesn't match want: %t, got: %t", step.name, step.wantCombo, ok)
+		}
+
+		if ok && combo.Users != step.wantUsers {
+			t.Errorf("%s: users don't match want: %d, got: %d", step.name, step.wantUsers, combo.Users)
+		}
+
+		if ok && combo.Code != "Kappa" {
+			t.Errorf("%s: combo emote doesn't match want: Kappa, got: %s", step.name, combo.Code)
+		}
+	}
+}
+
+func TestComboDetectorSeparatesChannels(t *testing.T) {
+	detector := NewComboDetector(2, time.Minute)
+
+	other := *kappa
+	other.Channel = "attackkopter"
+
+	if _, ok := detector.Add("ronni", kappa); ok {
+		t.Fatal("first use shouldn't be a combo")
+	}
+
+	if _, ok := detector.Add("sanjayshr", &other); ok {
+		t.Fatal("uses in different channels shouldn't be part of the same combo")
+	}
+
+	if _, ok := detector.Add("sanjayshr", kappa); !ok {
+		t.Fatal("second user in the same channel should start a combo")
+	}
+}
@@ -7,10 +7,22 @@ import (
 
 	"github.com/miguel250/streaming-setup/server/chat/commands"
 	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/protection"
+	"github.com/miguel250/streaming-setup/server/rewards"
 )
 
 type Config struct {
 	Twitch *Twitch `json:"twitch"`
+	Cache  Cache   `json:"cache"`
+}
+
+// Cache saves the last follower, subscriber and the Twitch tokens between
+// restarts, nothing is saved when Path is empty.
+type Cache struct {
+	Path string `json:"path"`
+	// TokenPath is where the tokens are saved with 0600 permissions, they
+	// are encrypted when STREAMING_CACHE_KEY is set
+	TokenPath string `json:"token_path"`
 }
 
 type Twitch struct {
@@ -22,14 +34,58 @@ type Twitch struct {
 	APIURL              string     `json:"api_url"`
 	AuthURL             string     `json:"auth_url"`
 	RedirectURL         string     `json:"redirect_url"`
-	BadgesURL           string     `json:"badges_url"`
 	IRC                 irc.Config `json:"irc"`
 	Emote               Emote      `json:"emote"`
 	Bot                 Bot        `json:"bot"`
+	EventSub            EventSub   `json:"eventsub"`
+	// EventSource is polling, webhook or websocket, polling is used when
+	// it is empty
+	EventSource string `json:"event_source"`
+	// Rewards are the actions for channel point rewards, redemptions are
+	// only received from EventSub
+	Rewards []*rewards.Action `json:"rewards"`
+	// Protection holds follow alerts during follow bot waves
+	Protection protection.Config `json:"protection"`
+}
+
+// Event sources for follows and subscriptions, polling only notices the
+// latest follower and subscriber.
+const (
+	PollingSource   = "polling"
+	WebhookSource   = "webhook"
+	WebSocketSource = "websocket"
+)
+
+// UsePolling reports if the refresher should poll followers and
+// subscribers instead of waiting for EventSub.
+func (t *Twitch) UsePolling() bool {
+	return t.EventSource == "" || t.EventSource == PollingSource
 }
 
 type Emote struct {
-	URL string `json:"url"`
+	// BTTVURL, FFZURL and SevenTVURL enable third-party emotes, a provider
+	// without URL is skipped
+	BTTVURL    string `json:"bttv_url"`
+	FFZURL     string `json:"ffz_url"`
+	SevenTVURL string `json:"seventv_url"`
+	// RefreshMinutes is how often third-party emotes are reloaded
+	RefreshMinutes int `json:"refresh_minutes"`
+	// ComboUsers different users sending the same emote within
+	// ComboSeconds of each other start an emote combo
+	ComboUsers   int `json:"combo_users"`
+	ComboSeconds int `json:"combo_seconds"`
+}
+
+// EventSub receives follows, subscriptions, cheers and raids from Twitch
+// as they happen.
+type EventSub struct {
+	// CallbackURL is the public HTTPS URL forwarded to /eventsub, only
+	// used by the webhook source
+	CallbackURL string `json:"callback_url"`
+	// Secret signs the notifications, between 10 and 100 characters
+	Secret string `json:"secret"`
+	// WebSocketURL replaces the Twitch EventSub WebSocket server
+	WebSocketURL string `json:"websocket_url"`
 }
 
 type Bot struct {
@@ -31,3 +31,21 @@ func TestMissingFile(t *testing.T) {
 	}
 
 }
+
+func TestUsePolling(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		want   bool
+	}{
+		{"", true},
+		{PollingSource, true},
+		{WebhookSource, false},
+		{WebSocketSource, false},
+	} {
+		conf := &Twitch{EventSource: test.source}
+
+		if got := conf.UsePolling(); got != test.want {
+			t.Errorf("UsePolling for %q doesn't match got: %t, want: %t", test.source, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/protection"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// Subscription types forwarded to the overlays, see
+// https://dev.twitch.tv/docs/eventsub/eventsub-subscription-types
+const (
+	FollowType              = "channel.follow"
+	SubscribeType           = "channel.subscribe"
+	SubscriptionGiftType    = "channel.subscription.gift"
+	SubscriptionMessageType = "channel.subscription.message"
+	CheerType               = "channel.cheer"
+	RaidType                = "channel.raid"
+	RedemptionType          = "channel.channel_points_custom_reward_redemption.add"
+	StreamOnlineType        = "stream.online"
+	StreamOfflineType       = "stream.offline"
+)
+
+var (
+	ErrUnsupportedType = errors.New("eventsub: unsupported subscription type")
+	// ErrInvalidNotification is returned for notifications that can never
+	// be forwarded, sending them again doesn't help.
+	ErrInvalidNotification = errors.New("eventsub: invalid notification")
+)
+
+// Notification is the body Twitch sends for every event. Challenge is only
+// set when a webhook is verified.
+type Notification struct {
+	Subscription *twitch.EventSubSubscription `json:"subscription"`
+	Event        json.RawMessage              `json:"event"`
+	Challenge    string                       `json:"challenge,omitempty"`
+}
+
+type user struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+}
+
+type followEvent struct {
+	user
+	FollowedAt string `json:"followed_at"`
+}
+
+type subscribeEvent struct {
+	user
+	Tier   string `json:"tier"`
+	IsGift bool   `json:"is_gift"`
+}
+
+type subscriptionGiftEvent struct {
+	user
+	Total           int    `json:"total"`
+	Tier            string `json:"tier"`
+	CumulativeTotal int    `json:"cumulative_total"`
+	IsAnonymous     bool   `json:"is_anonymous"`
+}
+
+type subscriptionMessageEvent struct {
+	user
+	Tier    string `json:"tier"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	CumulativeMonths int `json:"cumulative_months"`
+	StreakMonths     int `json:"streak_months"`
+}
+
+type cheerEvent struct {
+	user
+	IsAnonymous bool   `json:"is_anonymous"`
+	Message     string `json:"message"`
+	Bits        int    `json:"bits"`
+}
+
+type streamOnlineEvent struct {
+	Type      string    `json:"type"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type raidEvent struct {
+	FromBroadcasterUserID    string `json:"from_broadcaster_user_id"`
+	FromBroadcasterUserLogin string `json:"from_broadcaster_user_login"`
+	FromBroadcasterUserName  string `json:"from_broadcaster_user_name"`
+	Viewers                  int    `json:"viewers"`
+}
+
+// Subscriptions returns the subscriptions for every event Forward knows
+// about, the transport has to be set before creating them.
+func Subscriptions(channelID string) []*twitch.EventSubSubscription {
+	broadcaster := map[string]string{"broadcaster_user_id": channelID}
+
+	return []*twitch.EventSubSubscription{
+		{
+			Type:    FollowType,
+			Version: "2",
+			Condition: map[string]string{
+				"broadcaster_user_id": channelID,
+				"moderator_user_id":   channelID,
+			},
+		},
+		{Type: SubscribeType, Version: "1", Condition: broadcaster},
+		{Type: SubscriptionGiftType, Version: "1", Condition: broadcaster},
+		{Type: SubscriptionMessageType, Version: "1", Condition: broadcaster},
+		{Type: CheerType, Version: "1", Condition: broadcaster},
+		{Type: RaidType, Version: "1", Condition: map[string]string{"to_broadcaster_user_id": channelID}},
+		{Type: RedemptionType, Version: "1", Condition: broadcaster},
+		{Type: StreamOnlineType, Version: "1", Condition: broadcaster},
+		{Type: StreamOfflineType, Version: "1", Condition: broadcaster},
+	}
+}
+
+// Subscribe creates the subscriptions from Subscriptions with transport.
+// Webhook subscriptions survive restarts so the ones already enabled or
+// pending for the same callback are skipped.
+func Subscribe(ctx context.Context, api *twitch.API, channelID string, transport twitch.EventSubTransport) error {
+	existing := make(map[string]bool)
+
+	if transport.Method == twitch.WebhookMethod {
+		subs, err := api.EventSubSubscriptions(ctx, transport.Method, "")
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range subs {
+			if sub.Transport.Callback != transport.Callback {
+				continue
+			}
+
+			if sub.Status == "enabled" || sub.Status == "webhook_callback_verification_pending" {
+				existing[sub.Type] = true
+			}
+		}
+	}
+
+	var lastErr error
+	for _, sub := range Subscriptions(channelID) {
+		if existing[sub.Type] {
+			continue
+		}
+
+		sub.Transport = transport
+		if _, err := api.CreateEventSubSubscription(ctx, sub); err != nil {
+			log.Printf("Failed to subscribe to %s with %s", sub.Type, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// RedemptionHandler runs the action for a channel point redemption.
+type RedemptionHandler interface {
+	Handle(r *twitch.Redemption) error
+}
+
+// StreamTracker keeps whether the stream is live.
+type StreamTracker interface {
+	Online(startedAt time.Time)
+	Offline()
+}
+
+// Announcer records the users alerted for here, so polling followers and
+// subscribers doesn't alert for them again.
+type Announcer interface {
+	Announced(key, id string)
+}
+
+// Forwarder sends notifications to the overlays with the same events and
+// payloads as the chat notices.
+type Forwarder struct {
+	sync.RWMutex
+	event       *stream.Event
+	cache       *cache.Cache
+	redemptions RedemptionHandler
+	follows     protection.FollowHandler
+	tracker     StreamTracker
+	announcer   Announcer
+}
+
+// HandleRedemptions sends redemptions to h instead of straight to the
+// overlays.
+func (f *Forwarder) HandleRedemptions(h RedemptionHandler) {
+	f.Lock()
+	defer f.Unlock()
+	f.redemptions = h
+}
+
+// HandleFollows sends follow alerts to h instead of straight to the
+// overlays.
+func (f *Forwarder) HandleFollows(h protection.FollowHandler) {
+	f.Lock()
+	defer f.Unlock()
+	f.follows = h
+}
+
+// ReportAnnounced tells a about every follower and subscriber alerted for.
+func (f *Forwarder) ReportAnnounced(a Announcer) {
+	f.Lock()
+	defer f.Unlock()
+	f.announcer = a
+}
+
+// TrackStream sends stream online and offline notifications to t, they
+// are dropped until a tracker is set.
+func (f *Forwarder) TrackStream(t StreamTracker) {
+	f.Lock()
+	defer f.Unlock()
+	f.tracker = t
+}
+
+func NewForwarder(event *stream.Event, c *cache.Cache) *Forwarder {
+	return &Forwarder{
+		event: event,
+		cache: c,
+	}
+}
+
+// Forward sends the notification to the overlays.
+func (f *Forwarder) Forward(n *Notification) error {
+	if n.Subscription == nil {
+		return fmt.Errorf("failed to forward notification without subscription with %w", ErrInvalidNotification)
+	}
+
+	switch n.Subscription.Type {
+	case FollowType:
+		event := &followEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		// the refresher would alert again for the same follower
+		f.announced(cache.FollowerBackfillKey, event.UserID)
+		f.cache.Set(cache.LastFollowerIDKey, event.UserID)
+		f.cache.Set(cache.LastFollowerNameKey, event.UserName)
+
+		f.RLock()
+		h := f.follows
+		f.RUnlock()
+
+		if h != nil {
+			h.Follow(event.UserName, 1)
+			return nil
+		}
+
+		f.event.Send(stream.NewFollower, event.UserName)
+		return nil
+	case SubscribeType:
+		event := &subscribeEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		f.announced(cache.SubscriberBackfillKey, event.UserID)
+
+		// gifted subscriptions are announced by the gift event
+		if event.IsGift {
+			return nil
+		}
+
+		f.cache.Set(cache.LastSubscribeIDKey, event.UserID)
+		f.cache.Set(cache.LastSubscribeNameKey, event.UserName)
+		f.event.Send(stream.NewSubscriber, event.UserName)
+		return nil
+	case SubscriptionGiftType:
+		event := &subscriptionGiftEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		return f.send(stream.NewMysteryGift, &irc.MysteryGiftEvent{
+			UserNotice:  event.notice("submysterygift", event.IsAnonymous),
+			Plan:        event.Tier,
+			Count:       event.Total,
+			SenderTotal: event.CumulativeTotal,
+			Anonymous:   event.IsAnonymous,
+		})
+	case SubscriptionMessageType:
+		event := &subscriptionMessageEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		f.announced(cache.SubscriberBackfillKey, event.UserID)
+
+		notice := event.notice("resub", false)
+		notice.Message = event.Message.Text
+
+		return f.send(stream.NewResubscription, &irc.ResubEvent{
+			UserNotice:       notice,
+			Plan:             event.Tier,
+			CumulativeMonths: event.CumulativeMonths,
+			StreakMonths:     event.StreakMonths,
+			ShareStreak:      event.StreakMonths > 0,
+		})
+	case CheerType:
+		event := &cheerEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		return f.send(stream.NewCheer, &irc.Message{
+			DisplayName: event.notice("", event.IsAnonymous).DisplayName,
+			Message:     event.Message,
+			Bits:        event.Bits,
+		})
+	case RaidType:
+		event := &raidEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		return f.send(stream.NewRaid, &irc.RaidEvent{
+			UserNotice: irc.UserNotice{
+				Type:        "raid",
+				UserID:      event.FromBroadcasterUserID,
+				Login:       event.FromBroadcasterUserLogin,
+				DisplayName: event.FromBroadcasterUserName,
+			},
+			ViewerCount: event.Viewers,
+		})
+	case RedemptionType:
+		redemption := &twitch.Redemption{}
+		if err := n.decodeEvent(redemption); err != nil {
+			return err
+		}
+
+		f.RLock()
+		h := f.redemptions
+		f.RUnlock()
+
+		if h == nil {
+			return f.send(stream.NewRedemption, redemption)
+		}
+
+		// actions wait on chat and Twitch, other notifications shouldn't
+		go func() {
+			if err := h.Handle(redemption); err != nil {
+				log.Printf("Failed to handle redemption %s with %s", redemption.ID, err)
+			}
+		}()
+		return nil
+	case StreamOnlineType:
+		event := &streamOnlineEvent{}
+		if err := n.decodeEvent(event); err != nil {
+			return err
+		}
+
+		// reruns and premieres aren't the streamer going live
+		if event.Type != "live" {
+			return nil
+		}
+
+		if t := f.streamTracker(); t != nil {
+			t.Online(event.StartedAt)
+		}
+		return nil
+	case StreamOfflineType:
+		if t := f.streamTracker(); t != nil {
+			t.Offline()
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to forward %s with %w", n.Subscription.Type, ErrUnsupportedType)
+}
+
+// decodeEvent parses the event of the notification into event.
+func (n *Notification) decodeEvent(event interface{}) error {
+	if err := json.Unmarshal(n.Event, event); err != nil {
+		return fmt.Errorf("failed to parse %s event (%s) with %w", n.Subscription.Type, err, ErrInvalidNotification)
+	}
+	return nil
+}
+
+func (f *Forwarder) announced(key, id string) {
+	f.RLock()
+	a := f.announcer
+	f.RUnlock()
+
+	if a != nil {
+		a.Announced(key, id)
+	}
+}
+
+func (f *Forwarder) streamTracker() StreamTracker {
+	f.RLock()
+	defer f.RUnlock()
+	return f.tracker
+}
+
+func (f *Forwarder) send(eventType stream.EventType, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s with %w", stream.EventTypeToString[eventType], err)
+	}
+
+	f.event.Send(eventType, string(b))
+	return nil
+}
+
+// notice returns the user as the chat notice fields, Twitch doesn't send
+// the user for anonymous gifts and cheers.
+func (u *user) notice(noticeType string, anonymous bool) irc.UserNotice {
+	if anonymous {
+		return irc.UserNotice{Type: noticeType, DisplayName: "Anonymous"}
+	}
+
+	return irc.UserNotice{
+		Type:        noticeType,
+		UserID:      u.UserID,
+		Login:       u.UserLogin,
+		DisplayName: u.UserName,
+	}
+}
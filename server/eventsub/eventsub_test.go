@@ -0,0 +1,281 @@
+package eventsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+func readNotification(t *testing.T, name string) *Notification {
+	body, err := ioutil.ReadFile(fmt.Sprintf("testdata/%s.json", name))
+	if err != nil {
+		t.Fatalf("failed to read testdata with %s", err)
+	}
+
+	n := &Notification{}
+	if err := json.Unmarshal(body, n); err != nil {
+		t.Fatalf("failed to parse testdata with %s", err)
+	}
+	return n
+}
+
+// forward returns the messages sent to the overlays while forwarding n.
+func forward(t *testing.T, f *Forwarder, n *Notification) ([]stream.Message, error) {
+	done := make(chan error)
+	go func() {
+		done <- f.Forward(n)
+	}()
+
+	messages := make([]stream.Message, 0)
+	for {
+		select {
+		case msg := <-f.event.Message:
+			messages = append(messages, msg)
+		case err := <-done:
+			return messages, err
+		case <-time.After(time.Second):
+			t.Fatal("timed out forwarding notification")
+		}
+	}
+}
+
+func TestForward(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		want          []stream.Message
+		wantKeys      map[string]string
+		wantAnnounced []string
+	}{
+		{
+			name: "follow",
+			want: []stream.Message{{Type: stream.NewFollower, Text: "Cool_User"}},
+			wantKeys: map[string]string{
+				cache.LastFollowerIDKey:   "1234",
+				cache.LastFollowerNameKey: "Cool_User",
+			},
+			wantAnnounced: []string{cache.FollowerBackfillKey + ": 1234"},
+		},
+		{
+			name: "subscribe",
+			want: []stream.Message{{Type: stream.NewSubscriber, Text: "Cool_User"}},
+			wantKeys: map[string]string{
+				cache.LastSubscribeIDKey:   "1234",
+				cache.LastSubscribeNameKey: "Cool_User",
+			},
+			wantAnnounced: []string{cache.SubscriberBackfillKey + ": 1234"},
+		},
+		{
+			name:          "subscribe_gift",
+			want:          []stream.Message{},
+			wantAnnounced: []string{cache.SubscriberBackfillKey + ": 1234"},
+		},
+		{
+			name: "subscription_gift",
+			want: []stream.Message{{
+				Type: stream.NewMysteryGift,
+				Text: `{"id":"","type":"submysterygift","channel":"","user_id":"1234","login":"cool_user","display_name":"Cool_User","system_message":"","message":"","timestamp":0,"plan":"1000","count":2,"sender_total":284,"anonymous":false}`,
+			}},
+		},
+		{
+			name: "subscription_message",
+			want: []stream.Message{{
+				Type: stream.NewResubscription,
+				Text: `{"id":"","type":"resub","channel":"","user_id":"1234","login":"cool_user","display_name":"Cool_User","system_message":"","message":"Love the stream! FevziGG","timestamp":0,"plan":"1000","plan_name":"","cumulative_months":15,"streak_months":1,"share_streak":true}`,
+			}},
+			wantAnnounced: []string{cache.SubscriberBackfillKey + ": 1234"},
+		},
+		{
+			name: "cheer",
+			want: []stream.Message{{
+				Type: stream.NewCheer,
+				Text: `{"id":"","badges":null,"display-name":"Anonymous","message":"pogchamp","html":"","fragments":null,"profile_image":"","channel":"","bits":1000}`,
+			}},
+		},
+		{
+			name: "raid",
+			want: []stream.Message{{
+				Type: stream.NewRaid,
+				Text: `{"id":"","type":"raid","channel":"","user_id":"1234","login":"cool_user","display_name":"Cool_User","system_message":"","message":"","timestamp":0,"viewer_count":9001,"profile_image_url":""}`,
+			}},
+		},
+		{
+			name: "redemption",
+			want: []stream.Message{{
+				Type: stream.NewRedemption,
+				Text: `{"id":"17fa2df1-ad76-4804-bfa5-a40ef63efe63","user_id":"9001","user_login":"cooler_user","user_name":"Cooler_User","user_input":"pogchamp","status":"unfulfilled","redeemed_at":"2020-07-15T17:16:03.17106713Z","reward":{"id":"92af127c-7326-4483-a52b-b0da0be61c01","title":"title","prompt":"reward prompt","cost":100}}`,
+			}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := cache.New()
+			f := NewForwarder(stream.New(), c)
+			announced := &announceRecorder{}
+			f.ReportAnnounced(announced)
+
+			got, err := forward(t, f, readNotification(t, test.name))
+			if err != nil {
+				t.Fatalf("failed to forward notification with %s", err)
+			}
+
+			if len(got) != len(test.want) {
+				t.Fatalf("messages don't match want: %v, got: %v", test.want, got)
+			}
+
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("message doesn't match\nwant: %v\n got: %v", test.want[i], got[i])
+				}
+			}
+
+			for key, want := range test.wantKeys {
+				if val, _ := c.Get(key); val != want {
+					t.Errorf("cache key %s doesn't match want: %s, got: %s", key, want, val)
+				}
+			}
+
+			if fmt.Sprint(announced.users) != fmt.Sprint(test.wantAnnounced) {
+				t.Errorf("announced users don't match want: %v, got: %v", test.wantAnnounced, announced.users)
+			}
+		})
+	}
+}
+
+// announceRecorder records users as "key: id".
+type announceRecorder struct {
+	users []string
+}
+
+func (a *announceRecorder) Announced(key, id string) {
+	a.users = append(a.users, key+": "+id)
+}
+
+type redemptionRecorder chan *twitch.Redemption
+
+func (r redemptionRecorder) Handle(redemption *twitch.Redemption) error {
+	r <- redemption
+	return nil
+}
+
+func TestForwardRedemptionHandler(t *testing.T) {
+	f := NewForwarder(stream.New(), cache.New())
+	recorder := make(redemptionRecorder, 1)
+	f.HandleRedemptions(recorder)
+
+	messages, err := forward(t, f, readNotification(t, "redemption"))
+	if err != nil {
+		t.Fatalf("failed to forward notification with %s", err)
+	}
+
+	if len(messages) != 0 {
+		t.Errorf("the handler should send the event, got: %v", messages)
+	}
+
+	select {
+	case redemption := <-recorder:
+		if redemption.Reward.ID != "92af127c-7326-4483-a52b-b0da0be61c01" || redemption.UserInput != "pogchamp" {
+			t.Errorf("redemption doesn't match got: %+v", redemption)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the redemption handler")
+	}
+}
+
+type followRecorder []string
+
+func (r *followRecorder) Follow(alert string, follows int) {
+	*r = append(*r, fmt.Sprintf("%s/%d", alert, follows))
+}
+
+func TestForwardFollowHandler(t *testing.T) {
+	f := NewForwarder(stream.New(), cache.New())
+	recorder := &followRecorder{}
+	f.HandleFollows(recorder)
+
+	messages, err := forward(t, f, readNotification(t, "follow"))
+	if err != nil {
+		t.Fatalf("failed to forward notification with %s", err)
+	}
+
+	if len(messages) != 0 {
+		t.Errorf("the handler should send the alert, got: %v", messages)
+	}
+
+	if len(*recorder) != 1 || (*recorder)[0] != "Cool_User/1" {
+		t.Errorf("follows don't match want: [Cool_User/1], got: %v", *recorder)
+	}
+}
+
+type streamRecorder []string
+
+func (r *streamRecorder) Online(startedAt time.Time) {
+	*r = append(*r, "online "+startedAt.Format(time.RFC3339))
+}
+
+func (r *streamRecorder) Offline() {
+	*r = append(*r, "offline")
+}
+
+func TestForwardStreamTracker(t *testing.T) {
+	f := NewForwarder(stream.New(), cache.New())
+
+	// without a tracker the notifications are dropped
+	if _, err := forward(t, f, readNotification(t, "stream_online")); err != nil {
+		t.Fatalf("failed to forward notification with %s", err)
+	}
+
+	recorder := &streamRecorder{}
+	f.TrackStream(recorder)
+
+	for _, name := range []string{"stream_online", "stream_offline"} {
+		messages, err := forward(t, f, readNotification(t, name))
+		if err != nil {
+			t.Fatalf("failed to forward %s with %s", name, err)
+		}
+
+		if len(messages) != 0 {
+			t.Errorf("the tracker should send the event, got: %v", messages)
+		}
+	}
+
+	want := []string{"online 2020-10-11T10:11:12Z", "offline"}
+	if len(*recorder) != len(want) || (*recorder)[0] != want[0] || (*recorder)[1] != want[1] {
+		t.Errorf("tracker calls don't match want: %v, got: %v", want, *recorder)
+	}
+}
+
+func TestForwardUnsupportedType(t *testing.T) {
+	f := NewForwarder(stream.New(), cache.New())
+
+	_, err := forward(t, f, &Notification{
+		Subscription: &twitch.EventSubSubscription{Type: "channel.update"},
+		Event:        json.RawMessage(`{}`),
+	})
+
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected unsupported type error, got: %v", err)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	for _, sub := range Subscriptions("1337") {
+		key := "broadcaster_user_id"
+		if sub.Type == RaidType {
+			key = "to_broadcaster_user_id"
+		}
+
+		if sub.Condition[key] != "1337" {
+			t.Errorf("%s condition doesn't have the channel, got: %v", sub.Type, sub.Condition)
+		}
+
+		if sub.Version == "" {
+			t.Errorf("%s doesn't have a version", sub.Type)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package eventsub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Headers and message types sent with every webhook request, see
+// https://dev.twitch.tv/docs/eventsub/handling-webhook-events
+const (
+	messageIDHeader        = "Twitch-Eventsub-Message-Id"
+	messageTimestampHeader = "Twitch-Eventsub-Message-Timestamp"
+	messageSignatureHeader = "Twitch-Eventsub-Message-Signature"
+	messageTypeHeader      = "Twitch-Eventsub-Message-Type"
+
+	notificationMessage = "notification"
+	verificationMessage = "webhook_callback_verification"
+	revocationMessage   = "revocation"
+)
+
+const (
+	// maxMessageAge is how old a message can be before it is treated as a
+	// replay, message IDs are only remembered for as long.
+	maxMessageAge = 10 * time.Minute
+	maxBodySize   = 1 << 20
+)
+
+var (
+	ErrInvalidSecret    = errors.New("eventsub: secret must be between 10 and 100 characters")
+	ErrInvalidSignature = errors.New("eventsub: invalid message signature")
+	ErrStaleMessage     = errors.New("eventsub: message is too old")
+)
+
+// Webhook receives EventSub notifications, it must be reachable by Twitch
+// over HTTPS on port 443.
+type Webhook struct {
+	secret    []byte
+	forwarder *Forwarder
+	forward   func(n *Notification) error
+	ids       *messageIDs
+	now       func() time.Time
+}
+
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(rw, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := w.verify(req.Header, body); err != nil {
+		log.Printf("Rejected eventsub message with %s", err)
+		http.Error(rw, "invalid message", http.StatusForbidden)
+		return
+	}
+
+	// Twitch resends messages it doesn't think were delivered
+	id := req.Header.Get(messageIDHeader)
+	if w.ids.isDuplicate(id, w.now()) {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	n := &Notification{}
+	if err := json.Unmarshal(body, n); err != nil {
+		w.ids.forget(id)
+		http.Error(rw, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Header.Get(messageTypeHeader) {
+	case verificationMessage:
+		rw.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(rw, n.Challenge)
+		return
+	case revocationMessage:
+		if n.Subscription != nil {
+			log.Printf("Twitch revoked the %s subscription with status %s", n.Subscription.Type, n.Subscription.Status)
+		}
+	case notificationMessage:
+		err := w.forward(n)
+
+		// Twitch would send it again forever and revoke the subscription
+		// when it keeps failing
+		if isPermanent(err) {
+			log.Printf("Dropping eventsub notification %s with %s", id, err)
+			break
+		}
+
+		if err != nil {
+			log.Printf("Failed to forward eventsub notification with %s", err)
+
+			// the retry from Twitch gets another chance
+			w.ids.forget(id)
+			http.Error(rw, "failed to handle notification", http.StatusInternalServerError)
+			return
+		}
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// isPermanent reports errors that happen again for the same notification.
+func isPermanent(err error) bool {
+	return errors.Is(err, ErrInvalidNotification) || errors.Is(err, ErrUnsupportedType)
+}
+
+// verify checks the HMAC of the message ID, timestamp and body with the
+// secret used to create the subscriptions, and rejects old messages.
+func (w *Webhook) verify(header http.Header, body []byte) error {
+	id := header.Get(messageIDHeader)
+	timestamp := header.Get(messageTimestampHeader)
+
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write([]byte(id))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(want), []byte(header.Get(messageSignatureHeader))) {
+		return ErrInvalidSignature
+	}
+
+	sentAt, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to parse timestamp %s with %w", timestamp, err)
+	}
+
+	if w.now().Sub(sentAt) > maxMessageAge {
+		return ErrStaleMessage
+	}
+	return nil
+}
+
+// messageIDs remembers the IDs of recent messages, forgetting the ones
+// older than maxMessageAge since the webhook rejects them anyway.
+type messageIDs struct {
+	sync.Mutex
+	seen map[string]time.Time
+}
+
+func (m *messageIDs) isDuplicate(id string, now time.Time) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	for seenID, seenAt := range m.seen {
+		if now.Sub(seenAt) > maxMessageAge {
+			delete(m.seen, seenID)
+		}
+	}
+
+	if _, ok := m.seen[id]; ok {
+		return true
+	}
+	m.seen[id] = now
+	return false
+}
+
+// forget removes a message that failed, so it isn't a duplicate when
+// Twitch sends it again.
+func (m *messageIDs) forget(id string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.seen, id)
+}
+
+func newMessageIDs() *messageIDs {
+	return &messageIDs{seen: make(map[string]time.Time)}
+}
+
+// NewWebhook creates the handler for the callback, secret is the same one
+// used to create the subscriptions.
+func NewWebhook(secret string, forwarder *Forwarder) (*Webhook, error) {
+	if len(secret) < 10 || len(secret) > 100 {
+		return nil, ErrInvalidSecret
+	}
+
+	return &Webhook{
+		secret:    []byte(secret),
+		forwarder: forwarder,
+		forward:   forwarder.Forward,
+		ids:       newMessageIDs(),
+		now:       time.Now,
+	}, nil
+}
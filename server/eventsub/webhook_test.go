@@ -0,0 +1,203 @@
+package eventsub
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/stream"
+)
+
+const testSecret = "s3cre7s3cre7"
+
+var testNow = time.Date(2020, 7, 15, 18, 16, 11, 0, time.UTC)
+
+func newTestWebhook(t *testing.T) *Webhook {
+	w, err := NewWebhook(testSecret, NewForwarder(stream.New(), cache.New()))
+	if err != nil {
+		t.Fatalf("failed to create webhook with %s", err)
+	}
+
+	w.now = func() time.Time {
+		return testNow
+	}
+	return w
+}
+
+func newWebhookRequest(t *testing.T, name, messageType, id string, sentAt time.Time, secret string) *http.Request {
+	body, err := ioutil.ReadFile(fmt.Sprintf("testdata/%s.json", name))
+	if err != nil {
+		t.Fatalf("failed to read testdata with %s", err)
+	}
+
+	timestamp := sentAt.Format(time.RFC3339Nano)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id + timestamp))
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/eventsub", bytes.NewReader(body))
+	req.Header.Set(messageIDHeader, id)
+	req.Header.Set(messageTimestampHeader, timestamp)
+	req.Header.Set(messageSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set(messageTypeHeader, messageType)
+	return req
+}
+
+// serve returns the response and the messages sent to the overlays.
+func serve(t *testing.T, w *Webhook, req *http.Request) (*httptest.ResponseRecorder, []stream.Message) {
+	rec := httptest.NewRecorder()
+	done := make(chan bool)
+
+	go func() {
+		w.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	messages := make([]stream.Message, 0)
+	for {
+		select {
+		case msg := <-w.forwarder.event.Message:
+			messages = append(messages, msg)
+		case <-done:
+			return rec, messages
+		case <-time.After(time.Second):
+			t.Fatal("timed out serving request")
+		}
+	}
+}
+
+func TestWebhookVerification(t *testing.T) {
+	w := newTestWebhook(t)
+
+	rec, _ := serve(t, w, newWebhookRequest(t, "verification", verificationMessage, "1", testNow, testSecret))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code doesn't match want: 200, got: %d", rec.Code)
+	}
+
+	if got := rec.Body.String(); got != "pogchamp-kappa-360noscope-vohiyo" {
+		t.Errorf("challenge doesn't match got: %s", got)
+	}
+}
+
+func TestWebhookNotification(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		secret       string
+		sentAt       time.Time
+		wantCode     int
+		wantMessages int
+	}{
+		{"valid", testSecret, testNow, http.StatusNoContent, 1},
+		{"clock skew", testSecret, testNow.Add(-9 * time.Minute), http.StatusNoContent, 1},
+		{"wrong secret", "not-the-secret", testNow, http.StatusForbidden, 0},
+		{"stale", testSecret, testNow.Add(-11 * time.Minute), http.StatusForbidden, 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWebhook(t)
+
+			rec, messages := serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "1", test.sentAt, test.secret))
+
+			if rec.Code != test.wantCode {
+				t.Errorf("status code doesn't match want: %d, got: %d", test.wantCode, rec.Code)
+			}
+
+			if len(messages) != test.wantMessages {
+				t.Errorf("messages don't match want: %d, got: %v", test.wantMessages, messages)
+			}
+		})
+	}
+}
+
+func TestWebhookTamperedBody(t *testing.T) {
+	w := newTestWebhook(t)
+	req := newWebhookRequest(t, "follow", notificationMessage, "1", testNow, testSecret)
+
+	body, _ := ioutil.ReadAll(req.Body)
+	req.Body = ioutil.NopCloser(bytes.NewReader(bytes.Replace(body, []byte("Cool_User"), []byte("Evil_User"), 1)))
+
+	rec, messages := serve(t, w, req)
+
+	if rec.Code != http.StatusForbidden || len(messages) != 0 {
+		t.Errorf("tampered body should be rejected, got: %d, %v", rec.Code, messages)
+	}
+}
+
+func TestWebhookDuplicate(t *testing.T) {
+	w := newTestWebhook(t)
+
+	_, messages := serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "1", testNow, testSecret))
+	if len(messages) != 1 {
+		t.Fatalf("first message should be forwarded, got: %v", messages)
+	}
+
+	rec, messages := serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "1", testNow, testSecret))
+	if rec.Code != http.StatusNoContent || len(messages) != 0 {
+		t.Errorf("duplicate should be acknowledged without forwarding, got: %d, %v", rec.Code, messages)
+	}
+
+	_, messages = serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "2", testNow, testSecret))
+	if len(messages) != 1 {
+		t.Errorf("new message id should be forwarded, got: %v", messages)
+	}
+
+	// old IDs are forgotten once their messages would be stale
+	later := testNow.Add(maxMessageAge + time.Minute)
+	w.now = func() time.Time {
+		return later
+	}
+
+	_, messages = serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "1", later, testSecret))
+	if len(messages) != 1 {
+		t.Errorf("expired message id should be forwarded again, got: %v", messages)
+	}
+}
+
+func TestWebhookInvalidEventIsAcknowledged(t *testing.T) {
+	w := newTestWebhook(t)
+
+	rec, messages := serve(t, w, newWebhookRequest(t, "follow_invalid_event", notificationMessage, "1", testNow, testSecret))
+	if rec.Code != http.StatusNoContent || len(messages) != 0 {
+		t.Fatalf("invalid event shouldn't be retried, got: %d, %v", rec.Code, messages)
+	}
+
+	rec, messages = serve(t, w, newWebhookRequest(t, "follow_invalid_event", notificationMessage, "1", testNow, testSecret))
+	if rec.Code != http.StatusNoContent || len(messages) != 0 {
+		t.Errorf("resent invalid event should be a duplicate, got: %d, %v", rec.Code, messages)
+	}
+}
+
+func TestWebhookFailedMessageIsRetried(t *testing.T) {
+	w := newTestWebhook(t)
+
+	forward := w.forward
+	w.forward = func(n *Notification) error {
+		w.forward = forward
+		return errors.New("overlay is not ready")
+	}
+
+	rec, messages := serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "1", testNow, testSecret))
+	if rec.Code != http.StatusInternalServerError || len(messages) != 0 {
+		t.Fatalf("failed message should ask for a retry, got: %d, %v", rec.Code, messages)
+	}
+
+	rec, messages = serve(t, w, newWebhookRequest(t, "follow", notificationMessage, "1", testNow, testSecret))
+	if rec.Code != http.StatusNoContent || len(messages) != 1 {
+		t.Errorf("retry should be forwarded, got: %d, %v", rec.Code, messages)
+	}
+}
+
+func TestNewWebhookSecret(t *testing.T) {
+	if _, err := NewWebhook("short", nil); err != ErrInvalidSecret {
+		t.Errorf("expected invalid secret error, got: %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// DefaultWebSocketURL is the EventSub WebSocket server.
+const DefaultWebSocketURL = "wss://eventsub.wss.twitch.tv/ws"
+
+// Message types sent over the WebSocket, notifications and revocations use
+// the same names as webhooks.
+const (
+	welcomeMessage   = "session_welcome"
+	keepaliveMessage = "session_keepalive"
+	reconnectMessage = "session_reconnect"
+)
+
+const (
+	// defaultKeepalive is used when the welcome doesn't have the keepalive
+	// timeout, keepaliveMargin gives the server some slack before the
+	// connection is considered dead.
+	defaultKeepalive = 10 * time.Second
+	keepaliveMargin  = 5 * time.Second
+
+	// welcomeTimeout is how long a new connection waits for the session,
+	// subscriptions must be created within 10 seconds of the welcome.
+	welcomeTimeout   = 10 * time.Second
+	subscribeTimeout = 10 * time.Second
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 2 * time.Minute
+)
+
+type wsMessage struct {
+	Metadata struct {
+		MessageID        string `json:"message_id"`
+		MessageType      string `json:"message_type"`
+		MessageTimestamp string `json:"message_timestamp"`
+	} `json:"metadata"`
+	Payload struct {
+		Session      *session                     `json:"session"`
+		Subscription *twitch.EventSubSubscription `json:"subscription"`
+		Event        json.RawMessage              `json:"event"`
+	} `json:"payload"`
+}
+
+type session struct {
+	ID                      string `json:"id"`
+	Status                  string `json:"status"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectURL            string `json:"reconnect_url"`
+}
+
+func (s *session) keepalive() time.Duration {
+	if s.KeepaliveTimeoutSeconds <= 0 {
+		return defaultKeepalive + keepaliveMargin
+	}
+	return time.Duration(s.KeepaliveTimeoutSeconds)*time.Second + keepaliveMargin
+}
+
+// WebSocket receives EventSub notifications over a connection opened from
+// this machine, so it works without a public URL. Subscriptions are created
+// with the user token for every new session.
+type WebSocket struct {
+	sync.Mutex
+	url       string
+	api       *twitch.API
+	channelID string
+	forwarder *Forwarder
+	dialer    *websocket.Dialer
+	ids       *messageIDs
+	conn      *websocket.Conn
+	shutdown  chan struct{}
+	once      sync.Once
+
+	minDelay time.Duration
+	maxDelay time.Duration
+}
+
+// Start connects in the background and keeps reconnecting until Close.
+func (w *WebSocket) Start() {
+	go w.run()
+}
+
+// Close disconnects from the server.
+func (w *WebSocket) Close() {
+	w.once.Do(func() {
+		close(w.shutdown)
+
+		w.Lock()
+		defer w.Unlock()
+		if w.conn != nil {
+			w.conn.Close()
+		}
+	})
+}
+
+func (w *WebSocket) run() {
+	url := w.url
+	delay := w.minDelay
+	// session_reconnect moves the subscriptions to the new connection, the
+	// old one is closed after the new session is welcomed
+	var previous *websocket.Conn
+
+	for {
+		conn, s, err := w.connect(url)
+
+		if previous != nil {
+			previous.Close()
+			previous = nil
+		}
+
+		if err == nil {
+			if url == w.url {
+				w.subscribe(s)
+			}
+
+			delay = w.minDelay
+			url, err = w.read(conn, s)
+
+			if url != "" {
+				previous = conn
+				continue
+			}
+			conn.Close()
+		}
+
+		if w.isClosed() {
+			return
+		}
+
+		log.Printf("EventSub connection failed with %s, reconnecting in %s", err, delay)
+		url = w.url
+
+		select {
+		case <-time.After(delay):
+		case <-w.shutdown:
+			return
+		}
+
+		delay *= 2
+		if delay > w.maxDelay {
+			delay = w.maxDelay
+		}
+	}
+}
+
+// connect opens the connection and waits for the welcome with the session.
+func (w *WebSocket) connect(url string) (*websocket.Conn, *session, error) {
+	conn, _, err := w.dialer.Dial(url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s with %w", url, err)
+	}
+
+	w.Lock()
+	if w.isClosed() {
+		w.Unlock()
+		conn.Close()
+		return nil, nil, fmt.Errorf("eventsub: connection closed")
+	}
+	w.conn = conn
+	w.Unlock()
+
+	msg := &wsMessage{}
+	conn.SetReadDeadline(time.Now().Add(welcomeTimeout))
+
+	if err := conn.ReadJSON(msg); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to read welcome with %w", err)
+	}
+
+	if msg.Metadata.MessageType != welcomeMessage || msg.Payload.Session == nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("eventsub: expected %s, got %s", welcomeMessage, msg.Metadata.MessageType)
+	}
+	return conn, msg.Payload.Session, nil
+}
+
+// read forwards notifications until the connection fails or the server
+// asks to reconnect, it returns the URL to reconnect to.
+func (w *WebSocket) read(conn *websocket.Conn, s *session) (string, error) {
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.keepalive()))
+
+		msg := &wsMessage{}
+		if err := conn.ReadJSON(msg); err != nil {
+			return "", err
+		}
+
+		if w.ids.isDuplicate(msg.Metadata.MessageID, time.Now()) {
+			continue
+		}
+
+		switch msg.Metadata.MessageType {
+		case keepaliveMessage:
+		case notificationMessage:
+			err := w.forwarder.Forward(&Notification{
+				Subscription: msg.Payload.Subscription,
+				Event:        msg.Payload.Event,
+			})
+
+			if err != nil {
+				log.Printf("Failed to forward eventsub notification with %s", err)
+			}
+		case reconnectMessage:
+			if msg.Payload.Session != nil && msg.Payload.Session.ReconnectURL != "" {
+				return msg.Payload.Session.ReconnectURL, nil
+			}
+		case revocationMessage:
+			if msg.Payload.Subscription != nil {
+				log.Printf("Twitch revoked the %s subscription with status %s", msg.Payload.Subscription.Type, msg.Payload.Subscription.Status)
+			}
+		}
+	}
+}
+
+// subscribe creates the subscriptions for the session, Twitch closes
+// sessions without subscriptions so failures end up reconnecting.
+func (w *WebSocket) subscribe(s *session) {
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+
+	err := Subscribe(ctx, w.api, w.channelID, twitch.EventSubTransport{
+		Method:    twitch.WebSocketMethod,
+		SessionID: s.ID,
+	})
+
+	if err != nil {
+		log.Printf("Failed to subscribe to EventSub with %s", err)
+	}
+}
+
+func (w *WebSocket) isClosed() bool {
+	select {
+	case <-w.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewWebSocket creates the client, url is DefaultWebSocketURL unless it is
+// pointed to a test server.
+func NewWebSocket(url string, api *twitch.API, channelID string, forwarder *Forwarder) *WebSocket {
+	if url == "" {
+		url = DefaultWebSocketURL
+	}
+
+	return &WebSocket{
+		url:       url,
+		api:       api,
+		channelID: channelID,
+		forwarder: forwarder,
+		dialer:    websocket.DefaultDialer,
+		ids:       newMessageIDs(),
+		shutdown:  make(chan struct{}),
+		minDelay:  minReconnectDelay,
+		maxDelay:  maxReconnectDelay,
+	}
+}
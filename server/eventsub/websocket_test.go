@@ -0,0 +1,259 @@
+package eventsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+// subscriptionRecorder stands in for Helix and records the subscriptions
+// created by the client.
+type subscriptionRecorder struct {
+	sync.Mutex
+	subs    []*twitch.EventSubSubscription
+	created chan bool
+}
+
+func newTestAPI(t *testing.T, recorder *subscriptionRecorder) *twitch.API {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/eventsub/subscriptions", func(rw http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Authorization"); got != "Bearer test_access_token" {
+			t.Errorf("websockets should use the user token, got: %s", got)
+		}
+
+		sub := &twitch.EventSubSubscription{}
+		if err := json.NewDecoder(req.Body).Decode(sub); err != nil {
+			t.Errorf("failed to decode subscription with %s", err)
+		}
+
+		recorder.Lock()
+		recorder.subs = append(recorder.subs, sub)
+		recorder.Unlock()
+
+		sub.Status = "enabled"
+		rw.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(rw).Encode(map[string]interface{}{"data": []*twitch.EventSubSubscription{sub}})
+		recorder.created <- true
+	})
+
+	api, _ := util.TestCreateClientWithMux(t, mux, nil)
+	return api
+}
+
+// wsServer is a local stand-in for the EventSub WebSocket server, every
+// connection is handled by the script for its path.
+type wsServer struct {
+	*httptest.Server
+	connections chan string
+}
+
+func newWSServer(t *testing.T, scripts map[string]func(conn *websocket.Conn)) *wsServer {
+	ts := &wsServer{connections: make(chan string, 10)}
+	upgrader := websocket.Upgrader{}
+
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		script, ok := scripts[req.URL.Path]
+		if !ok {
+			http.NotFound(rw, req)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade connection with %s", err)
+			return
+		}
+		defer conn.Close()
+
+		ts.connections <- req.URL.Path
+		script(conn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *wsServer) url(path string) string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + path
+}
+
+func sendMessage(t *testing.T, conn *websocket.Conn, id, messageType, payload string) {
+	msg := fmt.Sprintf(`{"metadata":{"message_id":"%s","message_type":"%s","message_timestamp":"2023-07-19T14:56:51.634234626Z"},"payload":%s}`, id, messageType, payload)
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Errorf("failed to send %s with %s", messageType, err)
+	}
+}
+
+func welcome(t *testing.T, conn *websocket.Conn, sessionID string) {
+	sendMessage(t, conn, "welcome-"+sessionID, welcomeMessage, fmt.Sprintf(`{"session":{"id":"%s","status":"connected","keepalive_timeout_seconds":10}}`, sessionID))
+}
+
+func notification(t *testing.T, conn *websocket.Conn, id, name string) {
+	n := readNotification(t, name)
+	b, _ := json.Marshal(map[string]interface{}{"subscription": n.Subscription, "event": n.Event})
+	sendMessage(t, conn, id, notificationMessage, string(b))
+}
+
+// waitClosed blocks until the client closes the connection.
+func waitClosed(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func waitSubscriptions(t *testing.T, recorder *subscriptionRecorder) {
+	for range Subscriptions("1337") {
+		select {
+		case <-recorder.created:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for subscriptions")
+		}
+	}
+}
+
+func readMessage(t *testing.T, event *stream.Event) stream.Message {
+	select {
+	case msg := <-event.Message:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return stream.Message{}
+}
+
+func newTestWebSocket(t *testing.T, url string, recorder *subscriptionRecorder) (*WebSocket, *stream.Event) {
+	event := stream.New()
+	ws := NewWebSocket(url, newTestAPI(t, recorder), "1337", NewForwarder(event, cache.New()))
+	ws.minDelay = 10 * time.Millisecond
+	t.Cleanup(ws.Close)
+	return ws, event
+}
+
+func TestWebSocketReconnectMessage(t *testing.T) {
+	recorder := &subscriptionRecorder{created: make(chan bool, 20)}
+	oldClosed := make(chan bool)
+	var ts *wsServer
+
+	ts = newWSServer(t, map[string]func(conn *websocket.Conn){
+		"/ws": func(conn *websocket.Conn) {
+			welcome(t, conn, "session-1")
+			waitSubscriptions(t, recorder)
+
+			notification(t, conn, "1", "follow")
+			// Twitch can send the same message twice
+			notification(t, conn, "1", "follow")
+			sendMessage(t, conn, "2", keepaliveMessage, `{}`)
+			sendMessage(t, conn, "3", reconnectMessage, fmt.Sprintf(`{"session":{"id":"session-1","status":"reconnecting","reconnect_url":"%s"}}`, ts.url("/reconnect")))
+
+			waitClosed(conn)
+			close(oldClosed)
+		},
+		"/reconnect": func(conn *websocket.Conn) {
+			welcome(t, conn, "session-1")
+			notification(t, conn, "4", "raid")
+			waitClosed(conn)
+		},
+	})
+
+	ws, event := newTestWebSocket(t, ts.url("/ws"), recorder)
+	ws.Start()
+
+	if msg := readMessage(t, event); msg.Type != stream.NewFollower || msg.Text != "Cool_User" {
+		t.Errorf("expected follower, got: %v", msg)
+	}
+
+	if msg := readMessage(t, event); msg.Type != stream.NewRaid {
+		t.Errorf("duplicate should be skipped and raid forwarded, got: %v", msg)
+	}
+
+	select {
+	case <-oldClosed:
+	case <-time.After(time.Second):
+		t.Error("old connection should be closed after reconnecting")
+	}
+
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	if len(recorder.subs) != len(Subscriptions("1337")) {
+		t.Errorf("subscriptions should only be created once, got: %d", len(recorder.subs))
+	}
+
+	for _, sub := range recorder.subs {
+		if sub.Transport.Method != twitch.WebSocketMethod || sub.Transport.SessionID != "session-1" {
+			t.Errorf("transport doesn't match got: %+v", sub.Transport)
+		}
+	}
+}
+
+func TestWebSocketConnectionDropped(t *testing.T) {
+	recorder := &subscriptionRecorder{created: make(chan bool, 20)}
+	var sessions int32
+
+	ts := newWSServer(t, map[string]func(conn *websocket.Conn){
+		"/ws": func(conn *websocket.Conn) {
+			session := atomic.AddInt32(&sessions, 1)
+			welcome(t, conn, fmt.Sprintf("session-%d", session))
+			waitSubscriptions(t, recorder)
+
+			if session == 1 {
+				// drop the connection without a close frame
+				conn.UnderlyingConn().Close()
+				return
+			}
+
+			notification(t, conn, "1", "follow")
+			waitClosed(conn)
+		},
+	})
+
+	ws, event := newTestWebSocket(t, ts.url("/ws"), recorder)
+	ws.Start()
+
+	if msg := readMessage(t, event); msg.Type != stream.NewFollower {
+		t.Errorf("expected follower after reconnecting, got: %v", msg)
+	}
+
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	want := 2 * len(Subscriptions("1337"))
+	if len(recorder.subs) != want {
+		t.Fatalf("subscriptions should be created for every session want: %d, got: %d", want, len(recorder.subs))
+	}
+
+	if got := recorder.subs[want-1].Transport.SessionID; got != "session-2" {
+		t.Errorf("session id doesn't match want: session-2, got: %s", got)
+	}
+}
+
+func TestWebSocketWelcomeRequired(t *testing.T) {
+	recorder := &subscriptionRecorder{created: make(chan bool, 20)}
+
+	ts := newWSServer(t, map[string]func(conn *websocket.Conn){
+		"/ws": func(conn *websocket.Conn) {
+			sendMessage(t, conn, "1", keepaliveMessage, `{}`)
+			waitClosed(conn)
+		},
+	})
+
+	ws, _ := newTestWebSocket(t, ts.url("/ws"), recorder)
+
+	if _, _, err := ws.connect(ts.url("/ws")); err == nil {
+		t.Error("connecting should fail without a welcome")
+	}
+}
@@ -0,0 +1,64 @@
+package irc
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReconnectMinDelay = 1 * time.Second
+	defaultReconnectMaxDelay = 2 * time.Minute
+)
+
+// backoff calculates how long to wait between reconnect attempts. The delay
+// doubles on every attempt until it reaches max and half of it is randomized
+// so multiple clients don't hit the server at the same time.
+type backoff struct {
+	sync.Mutex
+	min  time.Duration
+	max  time.Duration
+	rand *rand.Rand
+}
+
+func (b *backoff) duration(attempt int) time.Duration {
+	delay := b.min
+	for i := 0; i < attempt && delay < b.max; i++ {
+		delay *= 2
+	}
+
+	if delay > b.max {
+		delay = b.max
+	}
+
+	half := int64(delay / 2)
+	if half == 0 {
+		return delay
+	}
+
+	b.Lock()
+	jitter := b.rand.Int63n(half + 1)
+	b.Unlock()
+
+	return time.Duration(half + jitter)
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	if min <= 0 {
+		min = defaultReconnectMinDelay
+	}
+
+	if max <= 0 {
+		max = defaultReconnectMaxDelay
+	}
+
+	if max < min {
+		max = min
+	}
+
+	return &backoff{
+		min:  min,
+		max:  max,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
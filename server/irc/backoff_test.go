@@ -0,0 +1,42 @@
+package irc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, time.Second)
+
+	for _, test := range []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{4, 500 * time.Millisecond, time.Second},
+		{50, 500 * time.Millisecond, time.Second},
+	} {
+		for i := 0; i < 20; i++ {
+			got := b.duration(test.attempt)
+
+			if got < test.min || got > test.max {
+				t.Errorf("delay for attempt %d out of range got: %s, want between %s and %s", test.attempt, got, test.min, test.max)
+			}
+		}
+	}
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	b := newBackoff(0, 0)
+
+	if b.min != defaultReconnectMinDelay {
+		t.Errorf("min delay doesn't match got: %s, want: %s", b.min, defaultReconnectMinDelay)
+	}
+
+	if b.max != defaultReconnectMaxDelay {
+		t.Errorf("max delay doesn't match got: %s, want: %s", b.max, defaultReconnectMaxDelay)
+	}
+}
@@ -0,0 +1,25 @@
+package irc
+
+import (
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/irc/parser"
+)
+
+func TestHandshakeAckSeveralCapabilities(t *testing.T) {
+	h := newHandshake("test_user", nil)
+	h.welcomed = true
+
+	msg, err := parser.ParseMsg(":tmi.twitch.tv CAP * ACK :twitch.tv/membership twitch.tv/tags twitch.tv/commands")
+	if err != nil {
+		t.Fatalf("failed to parse message with %s", err)
+	}
+
+	if err := h.handle(msg); err != nil {
+		t.Fatalf("failed to handle ACK with %s", err)
+	}
+
+	if !h.done() {
+		t.Errorf("every capability was acknowledged, still waiting for: %s", h.pending())
+	}
+}
@@ -1,21 +1,48 @@
 package irc
 
 import (
+	"crypto/tls"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/miguel250/streaming-setup/server/twitch"
 	"github.com/miguel250/streaming-setup/server/twitchemotes"
 )
 
+// Config for the chat client. URL selects how to connect to the server:
+// irc://host:6667 for plain TCP, ircs://host:6697 for TLS and
+// wss://irc-ws.chat.twitch.tv:443 for IRC over WebSocket.
 type Config struct {
-	Auth         string                          `json:"auth"`
-	URL          string                          `json:"url"`
-	Name         string                          `json:"name"`
-	Channel      string                          `json:"channel"`
-	TwitchAPI    *twitch.API                     `json:"-"`
-	TwitchEmotes *twitchemotes.API               `json:"-"`
-	Badges       map[string]*twitch.BadgeVersion `json:"-"`
+	Auth      string                          `json:"auth"`
+	URL       string                          `json:"url"`
+	Name      string                          `json:"name"`
+	Channel   string                          `json:"channel"`
+	Channels  []string                        `json:"channels"`
+	TwitchAPI *twitch.API                     `json:"-"`
+	Badges    map[string]*twitch.BadgeVersion `json:"-"`
+
+	// EmoteStore has the BTTV, FFZ and 7TV emotes, messages only use
+	// Twitch emotes when it is nil.
+	EmoteStore *twitchemotes.Store `json:"-"`
+
+	// ReconnectMinDelay and ReconnectMaxDelay bound the exponential backoff
+	// used when the connection to the chat server drops.
+	ReconnectMinDelay time.Duration `json:"-"`
+	ReconnectMaxDelay time.Duration `json:"-"`
+
+	// AuthTimeout is how long Auth waits for the server to accept the
+	// login.
+	AuthTimeout time.Duration `json:"-"`
+
+	// SendQueueSize is how many outgoing messages can wait for the rate
+	// limiter before sends start failing.
+	SendQueueSize int `json:"send_queue_size"`
+
+	// Transport replaces the transport picked from the URL scheme.
+	Transport Transport `json:"-"`
+	// TLSConfig is used by the ircs:// and wss:// transports.
+	TLSConfig *tls.Config `json:"-"`
 }
 
 func (c *Config) validate() error {
@@ -32,21 +59,35 @@ func (c *Config) validate() error {
 		return err
 	}
 
-	if err := formatStrErr("channel", c.Channel); err != nil {
-		return err
+	if len(c.channels()) == 0 {
+		return formatStrErr("channel", "")
 	}
 
 	if err := formatPtrErr("TwitchAPI", c.TwitchAPI); err != nil {
 		return err
 	}
 
-	if err := formatPtrErr("TwitchEmotes", c.TwitchEmotes); err != nil {
-		return err
-	}
-
 	return nil
 }
 
+// channels returns every channel from the configuration starting with the
+// default one, without duplicates.
+func (c *Config) channels() []string {
+	channels := make([]string, 0, len(c.Channels)+1)
+	seen := make(map[string]bool)
+
+	for _, channel := range append([]string{c.Channel}, c.Channels...) {
+		channel = normalizeChannel(channel)
+
+		if channel == "" || seen[channel] {
+			continue
+		}
+		seen[channel] = true
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
 func formatStrErr(fieldName string, value string) error {
 	if value == "" {
 		return fmt.Errorf("irc config: field %s can't be empty", fieldName)
@@ -3,8 +3,6 @@ package irc
 import (
 	"fmt"
 	"testing"
-
-	"github.com/miguel250/streaming-setup/server/twitch"
 )
 
 func TestConfValidation(t *testing.T) {
@@ -46,16 +44,6 @@ func TestConfValidation(t *testing.T) {
 				Channel: "test channel",
 			},
 		},
-		{
-			"TwitchEmotes",
-			Config{
-				Auth:      "test",
-				URL:       "http://example.com",
-				Name:      "account_name",
-				Channel:   "test channel",
-				TwitchAPI: &twitch.API{},
-			},
-		},
 	} {
 		t.Run(test.fieldName, func(t *testing.T) {
 			err := test.conf.validate()
@@ -71,3 +59,23 @@ func TestConfValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestConfChannels(t *testing.T) {
+	conf := Config{
+		Channel:  "#MiguelCodeTV",
+		Channels: []string{"miguelcodetv", "attackkopter", " #erikdotdev"},
+	}
+
+	got := conf.channels()
+	want := []string{"miguelcodetv", "attackkopter", "erikdotdev"}
+
+	if len(got) != len(want) {
+		t.Fatalf("Channels len don't match got: %v, want: %v", got, want)
+	}
+
+	for i, channel := range want {
+		if got[i] != channel {
+			t.Errorf("Channel doesn't match got: %s, want: %s", got[i], channel)
+		}
+	}
+}
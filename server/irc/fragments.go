@@ -0,0 +1,277 @@
+package irc
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitchemotes"
+)
+
+type FragmentType string
+
+const (
+	TextFragment      FragmentType = "text"
+	EmoteFragment     FragmentType = "emote"
+	MentionFragment   FragmentType = "mention"
+	LinkFragment      FragmentType = "link"
+	CheermoteFragment FragmentType = "cheermote"
+)
+
+const emoteURL = "https://static-cdn.jtvnw.net/emoticons/v1/%s/2.0"
+
+// Fragment is a piece of a chat message. Start and End are the positions
+// of the fragment in the message counted in runes, End is inclusive like in
+// the emotes tag.
+type Fragment struct {
+	Type  FragmentType `json:"type"`
+	Text  string       `json:"text"`
+	Start int          `json:"start"`
+	End   int          `json:"end"`
+	// ID of the emote
+	ID string `json:"id,omitempty"`
+	// Provider is set for BTTV, FFZ and 7TV emotes
+	Provider string `json:"provider,omitempty"`
+	// URL of the emote or cheermote image or the link
+	URL string `json:"url,omitempty"`
+	// Username without @ for mentions
+	Username string `json:"username,omitempty"`
+	// Bits and Color are only set for cheermotes
+	Bits  int    `json:"bits,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+// emoteLookup finds third-party emotes by their exact code.
+type emoteLookup func(code string) (*twitchemotes.Emote, bool)
+
+type emoteRange struct {
+	id         string
+	start, end int
+}
+
+// parseEmoteRanges parses the emotes tag, e.g. 25:0-4,12-16/1902:6-10.
+func parseEmoteRanges(tag string, length int) []emoteRange {
+	ranges := make([]emoteRange, 0)
+
+	if tag == "" {
+		return ranges
+	}
+
+	for _, emote := range strings.Split(tag, "/") {
+		parts := strings.SplitN(emote, ":", 2)
+
+		if len(parts) != 2 {
+			continue
+		}
+
+		for _, position := range strings.Split(parts[1], ",") {
+			bounds := strings.SplitN(position, "-", 2)
+			if len(bounds) != 2 {
+				continue
+			}
+
+			start, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				continue
+			}
+
+			end, err := strconv.Atoi(bounds[1])
+			if err != nil {
+				continue
+			}
+
+			if start < 0 || end < start || end >= length {
+				continue
+			}
+
+			ranges = append(ranges, emoteRange{id: parts[0], start: start, end: end})
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	return ranges
+}
+
+// buildFragments splits a message into text, emotes, mentions, links and
+// cheermotes. Emotes come from the emotes tag positions so only the exact
+// ranges Twitch reported become emotes, third-party emotes are matched
+// against whole words with lookup which can be nil. Cheermotes are only
+// matched when cheermotes is not empty, which is when the message carried
+// bits.
+func buildFragments(message, emotesTag string, cheermotes []*twitch.Cheermote, lookup emoteLookup) []*Fragment {
+	runes := []rune(message)
+	fragments := make([]*Fragment, 0)
+	position := 0
+
+	for _, emote := range parseEmoteRanges(emotesTag, len(runes)) {
+		// overlapping ranges are invalid, skip them
+		if emote.start < position {
+			continue
+		}
+
+		fragments = append(fragments, wordFragments(runes[position:emote.start], position, cheermotes, lookup)...)
+		fragments = append(fragments, &Fragment{
+			Type:  EmoteFragment,
+			Text:  string(runes[emote.start : emote.end+1]),
+			Start: emote.start,
+			End:   emote.end,
+			ID:    emote.id,
+			URL:   fmt.Sprintf(emoteURL, emote.id),
+		})
+		position = emote.end + 1
+	}
+
+	fragments = append(fragments, wordFragments(runes[position:], position, cheermotes, lookup)...)
+	return mergeText(fragments)
+}
+
+// wordFragments looks for mentions, links and cheermotes in text that
+// doesn't include emotes. offset is the position of text in the message.
+func wordFragments(text []rune, offset int, cheermotes []*twitch.Cheermote, lookup emoteLookup) []*Fragment {
+	fragments := make([]*Fragment, 0)
+	start := 0
+
+	for start < len(text) {
+		if text[start] == ' ' {
+			fragments = append(fragments, textFragment(" ", offset+start))
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(text) && text[end] != ' ' {
+			end++
+		}
+
+		word := string(text[start:end])
+		fragment := wordFragment(word, cheermotes, lookup)
+		fragment.Start = offset + start
+		fragment.End = offset + end - 1
+		fragments = append(fragments, fragment)
+		start = end
+	}
+	return fragments
+}
+
+func wordFragment(word string, cheermotes []*twitch.Cheermote, lookup emoteLookup) *Fragment {
+	if lookup != nil {
+		if emote, ok := lookup(word); ok {
+			return &Fragment{
+				Type:     EmoteFragment,
+				Text:     word,
+				ID:       emote.ID,
+				URL:      emote.URL,
+				Provider: emote.Provider,
+			}
+		}
+	}
+
+	if len(word) > 1 && word[0] == '@' {
+		return &Fragment{
+			Type:     MentionFragment,
+			Text:     word,
+			Username: strings.TrimRight(word[1:], ",.!?:;"),
+		}
+	}
+
+	lower := strings.ToLower(word)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return &Fragment{
+			Type: LinkFragment,
+			Text: word,
+			// punctuation after a link is part of the sentence
+			URL: strings.TrimRight(word, ".,!?:;)"),
+		}
+	}
+
+	if fragment := cheermoteFragment(word, cheermotes); fragment != nil {
+		return fragment
+	}
+
+	return &Fragment{Type: TextFragment, Text: word}
+}
+
+// cheermoteFragment matches words like Cheer100 with the tier for the
+// amount.
+func cheermoteFragment(word string, cheermotes []*twitch.Cheermote) *Fragment {
+	for _, cheermote := range cheermotes {
+		if len(word) <= len(cheermote.Prefix) || !strings.EqualFold(word[:len(cheermote.Prefix)], cheermote.Prefix) {
+			continue
+		}
+
+		amount, err := strconv.Atoi(word[len(cheermote.Prefix):])
+		if err != nil {
+			continue
+		}
+
+		tier := cheermote.Tier(amount)
+		if tier == nil {
+			continue
+		}
+
+		return &Fragment{
+			Type:  CheermoteFragment,
+			Text:  word,
+			URL:   tier.Images["dark"]["animated"]["2"],
+			Bits:  amount,
+			Color: tier.Color,
+		}
+	}
+	return nil
+}
+
+func textFragment(text string, start int) *Fragment {
+	return &Fragment{
+		Type:  TextFragment,
+		Text:  text,
+		Start: start,
+		End:   start + len([]rune(text)) - 1,
+	}
+}
+
+// mergeText joins consecutive text fragments.
+func mergeText(fragments []*Fragment) []*Fragment {
+	merged := make([]*Fragment, 0, len(fragments))
+
+	for _, fragment := range fragments {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			if last.Type == TextFragment && fragment.Type == TextFragment {
+				last.Text += fragment.Text
+				last.End = fragment.End
+				continue
+			}
+		}
+		merged = append(merged, fragment)
+	}
+	return merged
+}
+
+// renderHTML renders fragments for overlays that still expect HTML. Text
+// from chat is always escaped.
+func renderHTML(fragments []*Fragment) string {
+	var b strings.Builder
+
+	for _, fragment := range fragments {
+		text := html.EscapeString(fragment.Text)
+
+		switch fragment.Type {
+		case EmoteFragment:
+			fmt.Fprintf(&b, "<img src='%s' alt='%s'>", html.EscapeString(fragment.URL), text)
+		case CheermoteFragment:
+			fmt.Fprintf(&b, "<img src='%s'><span style='color: %s'>%d</span>", html.EscapeString(fragment.URL), html.EscapeString(fragment.Color), fragment.Bits)
+		case MentionFragment:
+			fmt.Fprintf(&b, "<span class='mention'>%s</span>", text)
+		case LinkFragment:
+			fmt.Fprintf(&b, "<span class='link'>%s</span>", text)
+		default:
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package irc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitchemotes"
+)
+
+func TestBuildFragments(t *testing.T) {
+	cheermotes := []*twitch.Cheermote{{
+		Prefix: "Cheer",
+		Tiers: []*twitch.CheermoteTier{{
+			MinBits: 1,
+			Color:   "#979797",
+			Images: map[string]map[string]map[string]string{
+				"dark": {"animated": {"2": "https://example.com/cheer/1.gif"}},
+			},
+		}},
+	}}
+
+	thirdParty := func(code string) (*twitchemotes.Emote, bool) {
+		if code != "catJAM" {
+			return nil, false
+		}
+
+		return &twitchemotes.Emote{
+			ID:       "5e76d338d6581c3724c0f0b2",
+			Code:     "catJAM",
+			URL:      "https://cdn.betterttv.net/emote/5e76d338d6581c3724c0f0b2/2x",
+			Provider: "bttv",
+		}, true
+	}
+
+	for _, test := range []struct {
+		name       string
+		message    string
+		emotes     string
+		cheermotes []*twitch.Cheermote
+		lookup     emoteLookup
+		want       []*Fragment
+		wantHTML   string
+	}{
+		{
+			"plain text",
+			"hello <b>chat</b>",
+			"",
+			nil,
+			nil,
+			[]*Fragment{
+				{Type: TextFragment, Text: "hello <b>chat</b>", Start: 0, End: 16},
+			},
+			"hello &lt;b&gt;chat&lt;/b&gt;",
+		},
+		{
+			"emote inside a longer word is not replaced",
+			"Kappa KappaPride",
+			"25:0-4",
+			nil,
+			nil,
+			[]*Fragment{
+				{Type: EmoteFragment, Text: "Kappa", Start: 0, End: 4, ID: "25", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0"},
+				{Type: TextFragment, Text: " KappaPride", Start: 5, End: 15},
+			},
+			"<img src='https://static-cdn.jtvnw.net/emoticons/v1/25/2.0' alt='Kappa'> KappaPride",
+		},
+		{
+			"multiple emotes with unicode",
+			"ñ Kappa ❤ Keepo Kappa",
+			"25:2-6,16-20/1902:10-14",
+			nil,
+			nil,
+			[]*Fragment{
+				{Type: TextFragment, Text: "ñ ", Start: 0, End: 1},
+				{Type: EmoteFragment, Text: "Kappa", Start: 2, End: 6, ID: "25", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0"},
+				{Type: TextFragment, Text: " ❤ ", Start: 7, End: 9},
+				{Type: EmoteFragment, Text: "Keepo", Start: 10, End: 14, ID: "1902", URL: "https://static-cdn.jtvnw.net/emoticons/v1/1902/2.0"},
+				{Type: TextFragment, Text: " ", Start: 15, End: 15},
+				{Type: EmoteFragment, Text: "Kappa", Start: 16, End: 20, ID: "25", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0"},
+			},
+			"ñ <img src='https://static-cdn.jtvnw.net/emoticons/v1/25/2.0' alt='Kappa'> ❤ <img src='https://static-cdn.jtvnw.net/emoticons/v1/1902/2.0' alt='Keepo'> <img src='https://static-cdn.jtvnw.net/emoticons/v1/25/2.0' alt='Kappa'>",
+		},
+		{
+			"mentions and links",
+			"hi @miguelcodetv, see https://github.com/miguel250",
+			"",
+			nil,
+			nil,
+			[]*Fragment{
+				{Type: TextFragment, Text: "hi ", Start: 0, End: 2},
+				{Type: MentionFragment, Text: "@miguelcodetv,", Start: 3, End: 16, Username: "miguelcodetv"},
+				{Type: TextFragment, Text: " see ", Start: 17, End: 21},
+				{Type: LinkFragment, Text: "https://github.com/miguel250", Start: 22, End: 49, URL: "https://github.com/miguel250"},
+			},
+			"hi <span class='mention'>@miguelcodetv,</span> see <span class='link'>https://github.com/miguel250</span>",
+		},
+		{
+			"cheermotes",
+			"cheer10 <script>",
+			"",
+			cheermotes,
+			nil,
+			[]*Fragment{
+				{Type: CheermoteFragment, Text: "cheer10", Start: 0, End: 6, URL: "https://example.com/cheer/1.gif", Bits: 10, Color: "#979797"},
+				{Type: TextFragment, Text: " <script>", Start: 7, End: 15},
+			},
+			"<img src='https://example.com/cheer/1.gif'><span style='color: #979797'>10</span> &lt;script&gt;",
+		},
+		{
+			"third-party emotes only match whole words",
+			"catJAM catJAMs Kappa",
+			"25:15-19",
+			nil,
+			thirdParty,
+			[]*Fragment{
+				{Type: EmoteFragment, Text: "catJAM", Start: 0, End: 5, ID: "5e76d338d6581c3724c0f0b2", Provider: "bttv", URL: "https://cdn.betterttv.net/emote/5e76d338d6581c3724c0f0b2/2x"},
+				{Type: TextFragment, Text: " catJAMs ", Start: 6, End: 14},
+				{Type: EmoteFragment, Text: "Kappa", Start: 15, End: 19, ID: "25", URL: "https://static-cdn.jtvnw.net/emoticons/v1/25/2.0"},
+			},
+			"<img src='https://cdn.betterttv.net/emote/5e76d338d6581c3724c0f0b2/2x' alt='catJAM'> catJAMs <img src='https://static-cdn.jtvnw.net/emoticons/v1/25/2.0' alt='Kappa'>",
+		},
+		{
+			"invalid emote ranges are ignored",
+			"Kappa",
+			"25:0-10/25:a-b/25",
+			nil,
+			nil,
+			[]*Fragment{
+				{Type: TextFragment, Text: "Kappa", Start: 0, End: 4},
+			},
+			"Kappa",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := buildFragments(test.message, test.emotes, test.cheermotes, test.lookup)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("fragments don't match")
+				for _, fragment := range got {
+					t.Logf("got: %+v", fragment)
+				}
+			}
+
+			if html := renderHTML(got); html != test.wantHTML {
+				t.Errorf("html doesn't match\nwant: %s\ngot:  %s", test.wantHTML, html)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miguel250/streaming-setup/server/irc/parser"
+	"github.com/miguel250/streaming-setup/server/irc/token"
+)
+
+var requestedCapabilities = []string{
+	"twitch.tv/membership",
+	"twitch.tv/tags",
+	"twitch.tv/commands",
+}
+
+// handshake tracks what the server still has to confirm before chat is
+// ready: every capability, the welcome message and every channel join.
+type handshake struct {
+	name         string
+	capabilities map[string]bool
+	channels     map[string]bool
+	welcomed     bool
+}
+
+func newHandshake(name string, channels []string) *handshake {
+	h := &handshake{
+		name:         strings.ToLower(name),
+		capabilities: make(map[string]bool),
+		channels:     make(map[string]bool),
+	}
+
+	for _, capability := range requestedCapabilities {
+		h.capabilities[capability] = true
+	}
+
+	for _, channel := range channels {
+		h.channels[channel] = true
+	}
+	return h
+}
+
+func (h *handshake) handle(msg *parser.Message) error {
+	switch msg.Command {
+	case token.CAP:
+		if msg.Subcommand == token.NAK {
+			return fmt.Errorf("irc: chat server rejected capability %s", msg.Message)
+		}
+
+		// the server can acknowledge several capabilities in one ACK
+		for _, capability := range strings.Fields(msg.Message) {
+			delete(h.capabilities, capability)
+		}
+	case token.WELCOME:
+		h.welcomed = true
+	case token.NOTICE:
+		notice := &Notice{MsgID: msg.Tags["msg-id"], Message: msg.Message}
+		if notice.isLoginFailure() {
+			return ErrLoginFailed
+		}
+	case token.JOIN:
+		if strings.ToLower(msg.Username) == h.name {
+			delete(h.channels, normalizeChannel(msg.Channel))
+		}
+	}
+	return nil
+}
+
+func (h *handshake) done() bool {
+	return h.welcomed && len(h.capabilities) == 0 && len(h.channels) == 0
+}
+
+// pending describes what the server didn't confirm yet.
+func (h *handshake) pending() string {
+	missing := make([]string, 0)
+
+	for capability := range h.capabilities {
+		missing = append(missing, fmt.Sprintf("capability %s", capability))
+	}
+
+	if !h.welcomed {
+		missing = append(missing, "welcome message")
+	}
+
+	for channel := range h.channels {
+		missing = append(missing, fmt.Sprintf("join #%s", channel))
+	}
+	sort.Strings(missing)
+	return strings.Join(missing, ", ")
+}
+
+// Auth logs in to the chat server and waits until the server acknowledges
+// every capability, welcomes the user and confirms every channel join. It
+// returns ErrLoginFailed when the oauth token is invalid. The configured
+// AuthTimeout is used when ctx doesn't have a deadline.
+func (c *Client) Auth(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := c.conf.AuthTimeout
+		if timeout <= 0 {
+			timeout = defaultAuthTimeout
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	events := make(chan *parser.Message, 100)
+	c.Lock()
+	c.authEvents = events
+	c.Unlock()
+
+	defer func() {
+		c.Lock()
+		c.authEvents = nil
+		c.Unlock()
+	}()
+
+	err := c.capabilities()
+	if err != nil {
+		return err
+	}
+
+	h := newHandshake(c.conf.Name, c.Channels())
+
+	for !h.done() {
+		select {
+		case msg := <-events:
+			if err := h.handle(msg); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("irc: chat server didn't confirm %s with %w", h.pending(), ctx.Err())
+		case <-c.shutdown:
+			return errors.New("client was closed")
+		}
+	}
+
+	c.Lock()
+	c.authenticated = true
+	c.Unlock()
+	return nil
+}
+
+// forwardAuthEvent passes handshake responses to Auth while it is waiting.
+func (c *Client) forwardAuthEvent(parse *parser.Message) {
+	switch parse.Command {
+	case token.CAP, token.WELCOME, token.NOTICE, token.JOIN:
+	default:
+		return
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.authEvents == nil {
+		return
+	}
+
+	select {
+	case c.authEvents <- parse:
+	default:
+	}
+}
@@ -0,0 +1,131 @@
+package irc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+)
+
+func TestAuthWaitsForJoin(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	err = client.Auth(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to auth with Twitch chat with %s", err)
+	}
+
+	// JOIN goes through the send queue so it is the last line of the
+	// handshake the server gets
+	found := false
+	for _, line := range chatServerMock.Received() {
+		if line == "JOIN #test_channel" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Auth returned before the channel was joined, got: %v", chatServerMock.Received())
+	}
+}
+
+func TestAuthInvalidToken(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+	chatServerMock.RejectLogin()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	err = client.Auth(context.Background())
+	if !errors.Is(err, irc.ErrLoginFailed) {
+		t.Fatalf("Auth error doesn't match want: %s, got: %v", irc.ErrLoginFailed, err)
+	}
+}
+
+func TestAuthCapabilityRejected(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+	chatServerMock.RejectCapability("twitch.tv/tags")
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	err = client.Auth(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "twitch.tv/tags") {
+		t.Fatalf("expected Auth to fail for rejected capability, got: %v", err)
+	}
+}
+
+func TestAuthTimeout(t *testing.T) {
+	chatServerMock := util.MockTwitchChatServer(t)
+	chatServerMock.Start()
+	defer chatServerMock.Shutdown()
+
+	var buf bytes.Buffer
+	buf.WriteString(":tmi.twitch.tv CAP * ACK :twitch.tv/membership")
+	chatServerMock.SetResponse(&buf)
+
+	client := util.CreateMockChatClientWithConfig(t, func(conf *irc.Config) {
+		conf.URL = chatServerMock.Addr()
+		conf.AuthTimeout = 50 * time.Millisecond
+	})
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	err = client.Auth(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Auth to time out, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "welcome message") || !strings.Contains(err.Error(), "join #test_channel") {
+		t.Errorf("timeout error should describe what is missing, got: %s", err)
+	}
+}
+
+func TestAuthContextDeadline(t *testing.T) {
+	chatServerMock := util.MockTwitchChatServer(t)
+	chatServerMock.Start()
+	defer chatServerMock.Shutdown()
+
+	var buf bytes.Buffer
+	buf.WriteString(":tmi.twitch.tv CAP * ACK :twitch.tv/membership")
+	chatServerMock.SetResponse(&buf)
+
+	client := util.CreateMockChatClientWithConfig(t, func(conf *irc.Config) {
+		conf.URL = chatServerMock.Addr()
+	})
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = client.Auth(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Auth to time out, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Auth didn't use the context deadline, took %s", elapsed)
+	}
+}
@@ -2,14 +2,16 @@ package irc
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/textproto"
-	"strconv"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/miguel250/streaming-setup/server/irc/parser"
 	"github.com/miguel250/streaming-setup/server/irc/token"
@@ -24,42 +26,88 @@ const (
 	Pass
 	Nick
 	Join
+	Part
 	Pong
 	PrivMsg
 )
 
+const defaultAuthTimeout = 10 * time.Second
+
+type ConnectionState int
+
+const (
+	Disconnected ConnectionState = iota
+	Connecting
+	Connected
+)
+
+var connectionStateToString = map[ConnectionState]string{
+	Disconnected: "disconnected",
+	Connecting:   "connecting",
+	Connected:    "connected",
+}
+
+func (s ConnectionState) String() string {
+	return connectionStateToString[s]
+}
+
 var commandToString = map[chatCommand]string{
 	Cap:     "CAP REQ :",
 	Pass:    "PASS oauth:",
 	Nick:    "NICK ",
 	Join:    "JOIN #",
+	Part:    "PART #",
 	Pong:    "PONG :",
 	PrivMsg: "PRIVMSG #",
 }
 
 type Client struct {
 	sync.RWMutex
-	connMutex      sync.RWMutex
-	conf           *Config
-	conn           net.Conn
-	OnCap          chan *parser.Message
-	onMessages     []chan *Message
-	onClearMessage []chan *ClearMessage
-	OnReconnect    chan bool
-	twitchEmotes   *twitchemotes.API
-	twitchClient   *twitch.API
-	badges         map[string]*twitch.BadgeVersion
-	currentUsers   map[string]*user
-	emotesCache    map[string]*emote
-	reader         *textproto.Reader
+	connMutex        sync.RWMutex
+	conf             *Config
+	conn             io.ReadWriteCloser
+	transport        Transport
+	OnCap            chan *parser.Message
+	onMessages       []chan *Message
+	onChannelMsgs    map[string][]chan *Message
+	onClearMessage   []chan *ClearMessage
+	onUserNotice     []chan UserNoticeEvent
+	onClearChat      []chan *ClearChat
+	onNotice         []chan *Notice
+	onRoomState      []chan *RoomState
+	onHostTarget     []chan *HostTarget
+	roomStates       map[string]*RoomState
+	authEvents       chan *parser.Message
+	OnReconnect      chan bool
+	OnStateChange    chan ConnectionState
+	state            ConnectionState
+	authenticated    bool
+	backoff          *backoff
+	shutdown         chan struct{}
+	closeOnce        sync.Once
+	channels         map[string]struct{}
+	defaultChannel   string
+	sendQueue        *sendQueue
+	startWriter      sync.Once
+	emoteStore       *twitchemotes.Store
+	twitchClient     *twitch.API
+	badges           map[string]*twitch.BadgeVersion
+	currentUsers     map[string]*user
+	recentMessageIDs map[string]map[string][]string
+	cheermotesCache  map[string][]*twitch.Cheermote
+	reader           *textproto.Reader
 }
 
 type Message struct {
+	ID           string          `json:"id"`
 	Badges       []*twitch.Badge `json:"badges"`
 	DisplayName  string          `json:"display-name"`
 	Message      string          `json:"message"`
+	HTML         string          `json:"html"`
+	Fragments    []*Fragment     `json:"fragments"`
 	ProfileImage string          `json:"profile_image"`
 	Channel      string          `json:"channel"`
+	Bits         int             `json:"bits,omitempty"`
 }
 
 type ClearMessage struct {
@@ -70,141 +118,307 @@ type ClearMessage struct {
 	Timestamp int64
 }
 
-type user struct {
-	profileImage string
+// MessageSender sends chat messages and waits for them to go out, Client
+// implements it.
+type MessageSender interface {
+	SendMessageToWait(ctx context.Context, channel, msg string) error
 }
 
-type emote struct {
-	id       string
-	code     string
-	imageURL string
+type user struct {
+	profileImage string
 }
 
 func (c *Client) Start() error {
+	c.setState(Connecting)
 	err := c.connect()
 	if err != nil {
+		c.setState(Disconnected)
 		return err
 	}
+	c.setState(Connected)
+
+	c.startWriter.Do(func() {
+		go c.writeLoop()
+	})
+	go c.readLoop()
+	return nil
+}
 
-	go func() {
-		for {
-			line, err := c.reader.ReadLine()
+func (c *Client) readLoop() {
+	for {
+		line, err := c.readLine()
 
-			if err != nil && err == io.EOF {
-				log.Println("Connection was closed")
+		if err != nil {
+			if c.isClosed() {
 				return
 			}
 
-			if err != nil {
+			if err == io.EOF {
+				log.Println("Connection was closed")
+			} else {
+				log.Printf("failed to read from connection with %s\n", err)
+			}
+
+			if !c.reconnect() {
 				return
 			}
+			continue
+		}
 
-			parse, err := parser.ParseMsg(line)
+		parse, err := parser.ParseMsg(line)
 
-			if err != nil {
-				log.Printf("Failed to parse message with %s\n", err)
-			}
+		if err != nil {
+			log.Printf("Failed to parse message with %s\n", err)
+			continue
+		}
 
-			switch parse.Command {
-			case token.CAP:
-				c.OnCap <- parse
-			case token.RECONNECT:
-				err := c.connect()
-				if err != nil {
-					log.Printf("failed to reconnect to server with: %s", err)
-					return
-				}
-				c.OnReconnect <- true
-			case token.PING:
-				err := c.Send(Pong, parse.Message)
-				if err != nil {
-					log.Printf("failed to send pong command to server")
-				}
-			case token.CLEARMSG:
-				msg := &ClearMessage{
-					Message:   parse.Message,
-					UserLogin: parse.Tags["login"],
-					Channel:   parse.Channel,
-					MessageID: parse.Tags["target-msg-id"],
-				}
+		c.handleMessage(parse)
+	}
+}
 
-				if i, err := strconv.ParseInt(parse.Tags["tmi-sent-ts"], 10, 64); err == nil {
-					msg.Timestamp = i
-				}
+func (c *Client) handleMessage(parse *parser.Message) {
+	c.forwardAuthEvent(parse)
 
-				c.RLock()
-				for _, channel := range c.onClearMessage {
-					select {
-					case channel <- msg:
-					default:
-					}
-				}
-				c.RUnlock()
-			case token.PRIVMSG:
-				c.handleEmotes(parse)
+	switch parse.Command {
+	case token.CAP:
+		select {
+		case c.OnCap <- parse:
+		default:
+		}
+	case token.RECONNECT:
+		c.setState(Connecting)
+		err := c.connect()
+		if err != nil {
+			log.Printf("failed to reconnect to server with: %s", err)
+			c.closeConn()
+			return
+		}
 
-				displayName, ok := parse.Tags["display-name"]
+		if err := c.reauth(); err != nil {
+			log.Printf("failed to authenticate after reconnect with %s", err)
+			c.closeConn()
+			return
+		}
 
-				if !ok || displayName == "" {
-					displayName = parse.Username
-				}
+		c.setState(Connected)
+		c.notifyReconnect()
+	case token.USERSTATE:
+		channel := normalizeChannel(parse.Channel)
+		isModerator := parse.Tags["mod"] == "1" || strings.Contains(parse.Tags["badges"], "broadcaster/")
+		c.sendQueue.setModerator(channel, isModerator)
+	case token.USERNOTICE:
+		c.handleUserNotice(parse)
+	case token.PING:
+		err := c.Send(Pong, parse.Message)
+		if err != nil {
+			log.Printf("failed to send pong command to server")
+		}
+	case token.CLEARMSG:
+		c.handleClearMessage(parse)
+	case token.CLEARCHAT:
+		c.handleClearChat(parse)
+	case token.NOTICE:
+		c.handleNotice(parse)
+	case token.ROOMSTATE:
+		c.handleRoomState(parse)
+	case token.HOSTTARGET:
+		c.handleHostTarget(parse)
+	case token.PRIVMSG:
+		c.trackMessageID(parse.Channel, parse.Username, parse.Tags["id"])
+		bits, cheermotes := c.channelCheermotes(parse)
+		fragments := buildFragments(parse.Message, parse.Tags["emotes"], cheermotes, c.thirdPartyEmotes(parse.Tags["room-id"]))
+
+		displayName, ok := parse.Tags["display-name"]
+
+		if !ok || displayName == "" {
+			displayName = parse.Username
+		}
 
-				badges, err := c.handleBadges(parse)
+		badges, err := c.handleBadges(parse)
 
-				if err != nil {
-					log.Printf("failed to get twitch badges with %s", err)
-				}
+		if err != nil {
+			log.Printf("failed to get twitch badges with %s", err)
+		}
 
-				userID := parse.Tags["user-id"]
-
-				c.RLock()
-				cachedUser, ok := c.currentUsers[parse.Username]
-				c.RUnlock()
-
-				if !ok && userID != "" {
-					twitchUser, err := c.twitchClient.GetUser(userID)
-					if err != nil {
-						log.Printf("failed to get user information with %s\n", err)
-					} else {
-						cachedUser = &user{
-							profileImage: twitchUser.Logo,
-						}
-						c.Lock()
-						c.currentUsers[parse.Username] = cachedUser
-						c.Unlock()
-					}
-				}
+		userID := parse.Tags["user-id"]
 
-				profileImage := ""
+		c.RLock()
+		cachedUser, ok := c.currentUsers[parse.Username]
+		c.RUnlock()
 
-				if cachedUser != nil {
-					profileImage = cachedUser.profileImage
+		if !ok && userID != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), twitchRequestTimeout)
+			twitchUser, err := c.twitchClient.GetUser(ctx, userID)
+			cancel()
+			if err != nil {
+				log.Printf("failed to get user information with %s\n", err)
+			} else {
+				cachedUser = &user{
+					profileImage: twitchUser.ProfileImageURL,
 				}
+				c.Lock()
+				c.currentUsers[parse.Username] = cachedUser
+				c.Unlock()
+			}
+		}
 
-				msg := &Message{
-					Message:      parse.Message,
-					DisplayName:  displayName,
-					Badges:       badges,
-					ProfileImage: profileImage,
-					Channel:      parse.Channel,
-				}
+		profileImage := ""
 
-				c.RLock()
-				for _, channel := range c.onMessages {
-					channel <- msg
-				}
-				c.RUnlock()
+		if cachedUser != nil {
+			profileImage = cachedUser.profileImage
+		}
+
+		msg := &Message{
+			ID:           parse.Tags["id"],
+			Message:      parse.Message,
+			HTML:         renderHTML(fragments),
+			Fragments:    fragments,
+			DisplayName:  displayName,
+			Badges:       badges,
+			ProfileImage: profileImage,
+			Channel:      parse.Channel,
+			Bits:         bits,
+		}
+
+		c.RLock()
+		defer c.RUnlock()
+
+		// listeners are closed once the client is shutting down
+		if c.isClosed() {
+			return
+		}
+
+		for _, channel := range c.onMessages {
+			select {
+			case channel <- msg:
+			case <-c.shutdown:
+				return
 			}
 		}
-	}()
-	return nil
+
+		for _, channel := range c.onChannelMsgs[normalizeChannel(msg.Channel)] {
+			select {
+			case channel <- msg:
+			case <-c.shutdown:
+				return
+			}
+		}
+	}
+}
+
+// reconnect keeps dialing the server until it succeeds or the client is
+// closed. It returns false when the client was closed while waiting.
+func (c *Client) reconnect() bool {
+	c.setState(Disconnected)
+
+	for attempt := 0; ; attempt++ {
+		delay := c.backoff.duration(attempt)
+		log.Printf("Reconnecting to chat server in %s\n", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-c.shutdown:
+			return false
+		}
+
+		c.setState(Connecting)
+		err := c.connect()
+		if err != nil {
+			log.Printf("failed to reconnect to server with: %s", err)
+			c.setState(Disconnected)
+			continue
+		}
+
+		if err := c.reauth(); err != nil {
+			log.Printf("failed to authenticate after reconnect with %s", err)
+			c.closeConn()
+			c.setState(Disconnected)
+			continue
+		}
+
+		c.setState(Connected)
+		c.notifyReconnect()
+		return true
+	}
+}
+
+// reauth runs the capabilities handshake again when the client was
+// authenticated before losing the connection.
+func (c *Client) reauth() error {
+	c.RLock()
+	authenticated := c.authenticated
+	c.RUnlock()
+
+	if !authenticated {
+		return nil
+	}
+	return c.capabilities()
+}
+
+func (c *Client) readLine() (string, error) {
+	c.connMutex.RLock()
+	reader := c.reader
+	c.connMutex.RUnlock()
+
+	if reader == nil {
+		return "", errors.New("client is not started yet")
+	}
+	return reader.ReadLine()
+}
+
+func (c *Client) setState(state ConnectionState) {
+	c.Lock()
+	changed := c.state != state
+	c.state = state
+	c.Unlock()
+
+	if !changed {
+		return
+	}
+
+	select {
+	case c.OnStateChange <- state:
+	default:
+	}
+}
+
+// State returns the current state of the connection with the chat server.
+func (c *Client) State() ConnectionState {
+	c.RLock()
+	defer c.RUnlock()
+	return c.state
+}
+
+func (c *Client) notifyReconnect() {
+	select {
+	case c.OnReconnect <- true:
+	default:
+	}
+}
+
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) closeConn() {
+	c.connMutex.Lock()
+	defer c.connMutex.Unlock()
+
+	if c.conn != nil {
+		c.conn.Close()
+	}
 }
 
 func (c *Client) connect() error {
 	c.connMutex.Lock()
 	defer c.connMutex.Unlock()
 
-	conn, err := net.Dial("tcp", c.conf.URL)
+	conn, err := c.transport.Dial()
 	if err != nil {
 		return fmt.Errorf("failed to create connection with %w", err)
 	}
@@ -229,6 +443,10 @@ func (c *Client) Close() error {
 		return errors.New("client is not started yet")
 	}
 
+	c.closeOnce.Do(func() {
+		close(c.shutdown)
+	})
+
 	c.Lock()
 	defer c.Unlock()
 
@@ -236,55 +454,177 @@ func (c *Client) Close() error {
 	for _, channel := range c.onMessages {
 		close(channel)
 	}
-	return c.conn.Close()
-}
 
-func (c *Client) Auth() error {
-	err := c.capabilities()
-	return err
+	for _, channels := range c.onChannelMsgs {
+		for _, channel := range channels {
+			close(channel)
+		}
+	}
+
+	for _, channel := range c.onUserNotice {
+		close(channel)
+	}
+
+	for _, channel := range c.onClearChat {
+		close(channel)
+	}
+
+	for _, channel := range c.onNotice {
+		close(channel)
+	}
+
+	for _, channel := range c.onRoomState {
+		close(channel)
+	}
+
+	for _, channel := range c.onHostTarget {
+		close(channel)
+	}
+	return c.conn.Close()
 }
 
 func (c *Client) capabilities() error {
-	err := c.Send(Cap, "twitch.tv/membership")
+	for _, capability := range requestedCapabilities {
+		err := c.Send(Cap, capability)
 
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
 	}
-	err = c.Send(Cap, "twitch.tv/tags")
+
+	err := c.Send(Pass, c.conf.Auth)
 
 	if err != nil {
 		return err
 	}
 
-	err = c.Send(Cap, "twitch.tv/commands")
+	err = c.Send(Nick, c.conf.Name)
 
 	if err != nil {
 		return err
 	}
 
-	err = c.Send(Pass, c.conf.Auth)
+	for _, channel := range c.Channels() {
+		err = c.Send(Join, channel)
 
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
 	}
 
-	err = c.Send(Nick, c.conf.Name)
+	return nil
+}
+
+// JoinChannel joins a new channel at runtime. The channel is joined again
+// automatically when the client reconnects.
+func (c *Client) JoinChannel(channel string) error {
+	channel = normalizeChannel(channel)
+
+	if channel == "" {
+		return errors.New("channel can't be empty")
+	}
 
+	err := c.Send(Join, channel)
 	if err != nil {
 		return err
 	}
 
-	err = c.Send(Join, c.conf.Channel)
+	c.Lock()
+	c.channels[channel] = struct{}{}
+	c.Unlock()
+	return nil
+}
+
+// PartChannel leaves a channel previously joined.
+func (c *Client) PartChannel(channel string) error {
+	channel = normalizeChannel(channel)
+
+	c.RLock()
+	_, ok := c.channels[channel]
+	c.RUnlock()
 
+	if !ok {
+		return fmt.Errorf("channel %s was never joined", channel)
+	}
+
+	err := c.Send(Part, channel)
 	if err != nil {
 		return err
 	}
 
+	c.Lock()
+	delete(c.channels, channel)
+	c.Unlock()
 	return nil
 }
 
+// Channels returns all channels the client is joined to.
+func (c *Client) Channels() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	channels := make([]string, 0, len(c.channels))
+	for channel := range c.channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// DefaultChannel returns the channel from the configuration field channel,
+// or the first one from channels.
+func (c *Client) DefaultChannel() string {
+	return c.defaultChannel
+}
+
+// SendMessage sends a message to the default channel, which is the one in
+// the configuration field channel or the first one from channels.
 func (c *Client) SendMessage(msg string) error {
-	return c.Send(PrivMsg, fmt.Sprintf("%s :%s", c.conf.Channel, msg))
+	return c.SendMessageTo(c.defaultChannel, msg)
+}
+
+// SendMessageTo sends a message to a specific channel. An empty channel
+// sends the message to the default channel.
+func (c *Client) SendMessageTo(channel, msg string) error {
+	return c.SendMessageToContext(context.Background(), channel, msg)
+}
+
+// SendMessageToContext is like SendMessageTo but the message is dropped if
+// ctx is done while it waits in the send queue.
+func (c *Client) SendMessageToContext(ctx context.Context, channel, msg string) error {
+	channel = normalizeChannel(channel)
+
+	if channel == "" {
+		channel = c.defaultChannel
+	}
+	return c.SendContext(ctx, PrivMsg, fmt.Sprintf("%s :%s", channel, msg))
+}
+
+// SendMessageToWait is like SendMessageToContext but it blocks until the
+// message is written to the connection. It returns an error when the
+// message is dropped, a message still in the queue when ctx is done is
+// dropped by the queue.
+func (c *Client) SendMessageToWait(ctx context.Context, channel, msg string) error {
+	channel = normalizeChannel(channel)
+
+	if channel == "" {
+		channel = c.defaultChannel
+	}
+
+	sent := make(chan error, 1)
+	err := c.enqueue(ctx, PrivMsg, fmt.Sprintf("%s :%s", channel, msg), sent)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-sent:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.shutdown:
+		return ErrMessageDropped
+	}
 }
 
 func (c *Client) MessageListener() chan *Message {
@@ -295,6 +635,16 @@ func (c *Client) MessageListener() chan *Message {
 	return channel
 }
 
+// ChannelMessageListener only receives messages sent to channel.
+func (c *Client) ChannelMessageListener(channel string) chan *Message {
+	channel = normalizeChannel(channel)
+	msgChannel := make(chan *Message)
+	c.Lock()
+	defer c.Unlock()
+	c.onChannelMsgs[channel] = append(c.onChannelMsgs[channel], msgChannel)
+	return msgChannel
+}
+
 func (c *Client) ClearMessageListener() chan *ClearMessage {
 	channel := make(chan *ClearMessage)
 	c.Lock()
@@ -304,12 +654,28 @@ func (c *Client) ClearMessageListener() chan *ClearMessage {
 }
 
 func (c *Client) Send(command chatCommand, message string) error {
+	return c.SendContext(context.Background(), command, message)
+}
+
+// SendContext sends a command to the chat server. Messages, joins and parts
+// are queued and rate limited so it doesn't block, the message is dropped if
+// ctx is done before it goes out.
+func (c *Client) SendContext(ctx context.Context, command chatCommand, message string) error {
 	commandString, ok := commandToString[command]
 
 	if !ok {
 		return fmt.Errorf("unknown command %v", command)
 	}
 
+	switch command {
+	case PrivMsg, Join, Part:
+		return c.enqueue(ctx, command, message, nil)
+	}
+
+	return c.write(commandString, message)
+}
+
+func (c *Client) write(commandString, message string) error {
 	c.connMutex.Lock()
 	defer c.connMutex.Unlock()
 
@@ -317,8 +683,8 @@ func (c *Client) Send(command chatCommand, message string) error {
 		return errors.New("client is not started yet")
 	}
 
-	fmt.Fprintf(c.conn, "%s%s\r\n", commandString, message)
-	return nil
+	_, err := fmt.Fprintf(c.conn, "%s%s\r\n", commandString, message)
+	return err
 }
 
 func New(conf *Config) (*Client, error) {
@@ -331,16 +697,49 @@ func New(conf *Config) (*Client, error) {
 		return nil, err
 	}
 
+	transport := conf.Transport
+	if transport == nil {
+		var err error
+		transport, err = newTransport(conf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	channels := make(map[string]struct{})
+	for _, channel := range conf.channels() {
+		channels[channel] = struct{}{}
+	}
+
 	return &Client{
-		conf:           conf,
-		OnCap:          make(chan *parser.Message, 100),
-		onMessages:     make([]chan *Message, 0, 10),
-		onClearMessage: make([]chan *ClearMessage, 0, 10),
-		OnReconnect:    make(chan bool, 10),
-		twitchEmotes:   conf.TwitchEmotes,
-		twitchClient:   conf.TwitchAPI,
-		badges:         conf.Badges,
-		currentUsers:   make(map[string]*user),
-		emotesCache:    make(map[string]*emote),
+		conf:             conf,
+		transport:        transport,
+		OnCap:            make(chan *parser.Message, 100),
+		onMessages:       make([]chan *Message, 0, 10),
+		onChannelMsgs:    make(map[string][]chan *Message),
+		onClearMessage:   make([]chan *ClearMessage, 0, 10),
+		onUserNotice:     make([]chan UserNoticeEvent, 0, 10),
+		onClearChat:      make([]chan *ClearChat, 0, 10),
+		onNotice:         make([]chan *Notice, 0, 10),
+		onRoomState:      make([]chan *RoomState, 0, 10),
+		onHostTarget:     make([]chan *HostTarget, 0, 10),
+		roomStates:       make(map[string]*RoomState),
+		OnReconnect:      make(chan bool, 10),
+		OnStateChange:    make(chan ConnectionState, 10),
+		backoff:          newBackoff(conf.ReconnectMinDelay, conf.ReconnectMaxDelay),
+		shutdown:         make(chan struct{}),
+		emoteStore:       conf.EmoteStore,
+		twitchClient:     conf.TwitchAPI,
+		badges:           conf.Badges,
+		currentUsers:     make(map[string]*user),
+		recentMessageIDs: make(map[string]map[string][]string),
+		cheermotesCache:  make(map[string][]*twitch.Cheermote),
+		channels:         channels,
+		defaultChannel:   conf.channels()[0],
+		sendQueue:        newSendQueue(conf.SendQueueSize),
 	}, nil
 }
+
+func normalizeChannel(channel string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(channel), "#"))
+}
@@ -2,10 +2,14 @@ package irc_test
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
+	"github.com/miguel250/streaming-setup/server/irc"
 	"github.com/miguel250/streaming-setup/server/irc/util"
 	"github.com/miguel250/streaming-setup/server/twitch"
+	twitch_util "github.com/miguel250/streaming-setup/server/twitch/util"
 )
 
 func TestPrivMsgWithBadges(t *testing.T) {
@@ -15,6 +19,7 @@ func TestPrivMsgWithBadges(t *testing.T) {
 		displayName  string
 		channel      string
 		message      string
+		html         string
 		badges       []*twitch.Badge
 		profileImage string
 	}{
@@ -24,6 +29,7 @@ func TestPrivMsgWithBadges(t *testing.T) {
 			"sanjayshr",
 			"miguelcodetv",
 			"jwt ?",
+			"jwt ?",
 			[]*twitch.Badge{},
 			"https://static-cdn.jtvnw.net/jtv_user_pictures/cf98ab68-af25-441b-989e-f203cd46522e-profile_image-300x300.png",
 		},
@@ -33,6 +39,7 @@ func TestPrivMsgWithBadges(t *testing.T) {
 			"AttackKopter",
 			"miguelcodetv",
 			"wow",
+			"wow",
 			[]*twitch.Badge{{
 				Title:   "Subscriber",
 				Image1X: "https://static-cdn.jtvnw.net/badges/v1/bea6cc27-c419-48e3-a121-110320d3482e/1",
@@ -43,10 +50,11 @@ func TestPrivMsgWithBadges(t *testing.T) {
 		},
 		{
 			"testing emotes",
-			"@badge-info=founder/1;badges=moderator/1,founder/0,bits-leader/1,subscriber/0;client-nonce=2519a0bb9411a510293c39fac51323ad;color=;display-name=AttackKopter;emotes=303365132;flags=;id=f12c675b-32b0-4ef3-8d20-e6c073ca6693;mod=1;room-id=558843277;subscriber=0;tmi-sent-ts=1599245324478;turbo=0;user-id=558843277;user-type=mod :attackkopter!attackkopter@attackkopter.tmi.twitch.tv PRIVMSG #miguelcodetv :wow miguel156Hero",
+			"@badge-info=founder/1;badges=moderator/1,founder/0,bits-leader/1,subscriber/0;client-nonce=2519a0bb9411a510293c39fac51323ad;color=;display-name=AttackKopter;emotes=303365132:4-16;flags=;id=f12c675b-32b0-4ef3-8d20-e6c073ca6693;mod=1;room-id=558843277;subscriber=0;tmi-sent-ts=1599245324478;turbo=0;user-id=558843277;user-type=mod :attackkopter!attackkopter@attackkopter.tmi.twitch.tv PRIVMSG #miguelcodetv :wow miguel156Hero",
 			"AttackKopter",
 			"miguelcodetv",
-			"wow <img src='https://static-cdn.jtvnw.net/emoticons/v1/303365132/2.0'>",
+			"wow miguel156Hero",
+			"wow <img src='https://static-cdn.jtvnw.net/emoticons/v1/303365132/2.0' alt='miguel156Hero'>",
 			[]*twitch.Badge{{
 				Title:   "Subscriber",
 				Image1X: "https://static-cdn.jtvnw.net/badges/v1/bea6cc27-c419-48e3-a121-110320d3482e/1",
@@ -82,6 +90,10 @@ func TestPrivMsgWithBadges(t *testing.T) {
 				t.Errorf("Message doesn't match want: %s, got: %s", test.message, data.Message)
 			}
 
+			if data.HTML != test.html {
+				t.Errorf("HTML doesn't match want: %s, got: %s", test.html, data.HTML)
+			}
+
 			if data.ProfileImage != test.profileImage {
 				t.Errorf("Profile image url doesn't match want: %s, got: %s", test.profileImage, data.ProfileImage)
 			}
@@ -143,22 +155,16 @@ func TestPrivMsg(t *testing.T) {
 
 func TestSimpleCap(t *testing.T) {
 
-	client, chatServerMock := util.CreateMockChatClient(t)
-	serverResponse := ":tmi.twitch.tv CAP * ACK :twitch.tv/membership\n:tmi.twitch.tv CAP * ACK :twitch.tv/tags\n:tmi.twitch.tv CAP * ACK :twitch.tv/commands"
-
-	var buf bytes.Buffer
-	buf.WriteString(serverResponse)
-
-	chatServerMock.SetResponse(&buf)
+	client, _ := util.CreateMockChatClient(t)
 
 	err := client.Start()
 	if err != nil {
 		t.Fatalf("failed to start irc client with %s", err)
 	}
 
-	err = client.Auth()
+	err = client.Auth(context.Background())
 	if err != nil {
-		t.Fatalf("Failed to auth with Twitch chat")
+		t.Fatalf("Failed to auth with Twitch chat with %s", err)
 	}
 
 	wants := []string{
@@ -196,6 +202,194 @@ func TestReconnect(t *testing.T) {
 	}
 }
 
+func TestReconnectAfterConnectionDrop(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	err = client.Auth(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to auth with Twitch chat")
+	}
+
+	waitForLines(t, chatServerMock, "JOIN #test_channel", 1)
+	drainStates(client)
+	chatServerMock.CloseConnections()
+
+	for _, want := range []irc.ConnectionState{irc.Disconnected, irc.Connecting, irc.Connected} {
+		select {
+		case got := <-client.OnStateChange:
+			if got != want {
+				t.Fatalf("Connection state doesn't match got: %s, want: %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for connection state %s", want)
+		}
+	}
+
+	select {
+	case <-client.OnReconnect:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting connection to reconnect")
+	}
+
+	waitForLines(t, chatServerMock, "JOIN #test_channel", 2)
+	waitForLines(t, chatServerMock, "PASS oauth:test_auth_token", 2)
+
+	messages := client.MessageListener()
+	err = client.SendMessage("still here")
+	if err != nil {
+		t.Fatalf("failed to send message after reconnect with %s", err)
+	}
+
+	data := <-messages
+
+	wantMessage := "still here"
+	if data.Message != wantMessage {
+		t.Errorf("Message doesn't match want: %s, got: %s", wantMessage, data.Message)
+	}
+}
+
+func TestMultipleChannels(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	err = client.JoinChannel("#AttackKopter")
+	if err != nil {
+		t.Fatalf("failed to join channel with %s", err)
+	}
+
+	wantChannels := []string{"attackkopter", "test_channel"}
+	gotChannels := client.Channels()
+
+	if len(gotChannels) != len(wantChannels) {
+		t.Fatalf("Channels don't match got: %v, want: %v", gotChannels, wantChannels)
+	}
+
+	for i, channel := range wantChannels {
+		if gotChannels[i] != channel {
+			t.Errorf("Channel doesn't match got: %s, want: %s", gotChannels[i], channel)
+		}
+	}
+
+	all := client.MessageListener()
+	attackkopter := client.ChannelMessageListener("attackkopter")
+
+	err = client.SendMessageTo("attackkopter", "hello co-stream")
+	if err != nil {
+		t.Fatalf("failed to send message with %s", err)
+	}
+
+	data := <-all
+	if data.Channel != "attackkopter" {
+		t.Errorf("Channel doesn't match want: attackkopter, got: %s", data.Channel)
+	}
+
+	data = <-attackkopter
+	if data.Message != "hello co-stream" {
+		t.Errorf("Message doesn't match want: hello co-stream, got: %s", data.Message)
+	}
+
+	err = client.PartChannel("attackkopter")
+	if err != nil {
+		t.Fatalf("failed to part channel with %s", err)
+	}
+
+	waitForLines(t, chatServerMock, "JOIN #attackkopter", 1)
+	waitForLines(t, chatServerMock, "PART #attackkopter", 1)
+
+	if got := client.Channels(); len(got) != 1 || got[0] != "test_channel" {
+		t.Errorf("Channels don't match got: %v, want: [test_channel]", got)
+	}
+
+	err = client.PartChannel("attackkopter")
+	if err == nil {
+		t.Error("expected an error when leaving a channel that wasn't joined")
+	}
+}
+
+func TestSendQueue(t *testing.T) {
+	client, _ := util.CreateMockChatClient(t)
+
+	err := client.SendMessage("not started")
+	if err == nil {
+		t.Error("expected an error when client is not started")
+	}
+
+	err = client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	messages := client.MessageListener()
+
+	err = client.SendMessage("hello chat")
+	if err != nil {
+		t.Fatalf("failed to send message with %s", err)
+	}
+
+	err = client.SendMessage("hello chat")
+	if err != irc.ErrDuplicateMessage {
+		t.Errorf("error doesn't match got: %v, want: %s", err, irc.ErrDuplicateMessage)
+	}
+
+	data := <-messages
+	if data.Message != "hello chat" {
+		t.Errorf("Message doesn't match want: hello chat, got: %s", data.Message)
+	}
+
+	if depth := client.QueueDepth(); depth != 0 {
+		t.Errorf("queue depth doesn't match got: %d, want: 0", depth)
+	}
+
+	err = client.SendMessageToWait(context.Background(), "", "waited for")
+	if err != nil {
+		t.Fatalf("failed to send message with %s", err)
+	}
+
+	data = <-messages
+	if data.Message != "waited for" {
+		t.Errorf("Message doesn't match want: waited for, got: %s", data.Message)
+	}
+}
+
+func drainStates(client *irc.Client) {
+	for {
+		select {
+		case <-client.OnStateChange:
+		default:
+			return
+		}
+	}
+}
+
+func waitForLines(t *testing.T, server *util.EchoServer, want string, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		got := 0
+		for _, line := range server.Received() {
+			if line == want {
+				got++
+			}
+		}
+
+		if got >= count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d lines of %q, got: %v", count, want, server.Received())
+}
+
 func TestClearMsg(t *testing.T) {
 	client, chatServerMock := util.CreateMockChatClient(t)
 	msg := "@login=zpapa2112017;room-id=;target-msg-id=eec7a15c-ad91-45ac-a0ce-c52a2e8c9b65;tmi-sent-ts=1600803187681 :tmi.twitch.tv CLEARMSG #miguelcodetv :In search of followers, primes and views?"
@@ -229,3 +423,55 @@ func TestClearMsg(t *testing.T) {
 	}
 
 }
+
+func TestCheer(t *testing.T) {
+	channeID := "558843277"
+	api, twitchMockServer := twitch_util.TestCreateClient(t, "cheermotes_response", "/helix/bits/cheermotes", channeID)
+	defer twitchMockServer.Close()
+
+	chatServerMock := util.MockTwitchChatServer(t)
+	chatServerMock.Start()
+	defer chatServerMock.Shutdown()
+
+	client := util.CreateMockChatClientWithConfig(t, func(conf *irc.Config) {
+		conf.URL = chatServerMock.Addr()
+		conf.TwitchAPI = api
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("@bits=150;display-name=cheerer;room-id=558843277 :cheerer!cheerer@cheerer.tmi.twitch.tv PRIVMSG #miguelcodetv :cheer100 great stream Kappa50\n")
+	buf.WriteString("@display-name=chatter;room-id=558843277 :chatter!chatter@chatter.tmi.twitch.tv PRIVMSG #miguelcodetv :Cheer100 without bits")
+	chatServerMock.SetResponse(&buf)
+
+	messages := client.MessageListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	msg := <-messages
+
+	if msg.Bits != 150 {
+		t.Errorf("Bits doesn't match want: 150, got: %d", msg.Bits)
+	}
+
+	want := "<img src='https://d3aqoihi2n8ty8.cloudfront.net/actions/cheer/dark/animated/100/2.gif'><span style='color: #9c3ee8'>100</span> great stream <img src='https://d3aqoihi2n8ty8.cloudfront.net/actions/kappa/dark/animated/1/2.gif'><span style='color: #979797'>50</span>"
+	if msg.HTML != want {
+		t.Errorf("HTML doesn't match\nwant: %s\ngot:  %s", want, msg.HTML)
+	}
+
+	if msg.Message != "cheer100 great stream Kappa50" {
+		t.Errorf("Message should stay as it was sent, got: %s", msg.Message)
+	}
+
+	msg = <-messages
+
+	if msg.Bits != 0 {
+		t.Errorf("Bits doesn't match want: 0, got: %d", msg.Bits)
+	}
+
+	if msg.HTML != "Cheer100 without bits" {
+		t.Errorf("cheermotes should only be rendered for messages with bits, got: %s", msg.HTML)
+	}
+}
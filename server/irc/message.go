@@ -1,64 +1,64 @@
 package irc
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/miguel250/streaming-setup/server/irc/parser"
 	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitchemotes"
 )
 
-func (c *Client) handleEmotes(parse *parser.Message) {
-	emoteTag := parse.Tags["emotes"]
+// twitchRequestTimeout bounds the Twitch API calls made while handling a
+// message so retries can't hold up reading chat.
+const twitchRequestTimeout = 5 * time.Second
 
-	if emoteTag == "" {
-		return
+// channelCheermotes returns the amount of bits in the message and the
+// cheermotes of the channel when the message is a cheer.
+func (c *Client) channelCheermotes(parse *parser.Message) (int, []*twitch.Cheermote) {
+	bits, err := strconv.Atoi(parse.Tags["bits"])
+
+	if err != nil || bits <= 0 {
+		return 0, nil
 	}
 
-	emotes := strings.Split(emoteTag, "/")
-	chatEmotes := make([]string, 0)
-	cacheMiss := make([]string, 0)
+	channelID := parse.Tags["room-id"]
 
-	for _, val := range emotes {
-		emoteParts := strings.Split(val, ":")
-		emoteID := emoteParts[0]
-		chatEmotes = append(chatEmotes, emoteParts[0])
+	c.RLock()
+	cheermotes, ok := c.cheermotesCache[channelID]
+	c.RUnlock()
 
-		c.RLock()
-		if _, ok := c.emotesCache[emoteID]; !ok {
-			cacheMiss = append(cacheMiss, emoteID)
-		}
-		c.RUnlock()
+	if ok {
+		return bits, cheermotes
 	}
 
-	if len(cacheMiss) > 0 {
-		data, err := c.twitchEmotes.Emotes.GetByID(cacheMiss)
+	ctx, cancel := context.WithTimeout(context.Background(), twitchRequestTimeout)
+	defer cancel()
 
-		if err != nil {
-			fmt.Printf("Failed to get emote with %s\n", err)
-			return
-		}
+	cheermotes, err = c.twitchClient.GetCheermotes(ctx, channelID)
 
-		for _, val := range data {
-			emoteURL := fmt.Sprintf("https://static-cdn.jtvnw.net/emoticons/v1/%d/2.0", val.ID)
-			e := &emote{
-				id:       strconv.Itoa(val.ID),
-				code:     val.Code,
-				imageURL: emoteURL,
-			}
-			c.Lock()
-			c.emotesCache[e.id] = e
-			c.Unlock()
-		}
+	if err != nil {
+		log.Printf("Failed to get cheermotes with %s\n", err)
+		return bits, nil
 	}
 
-	for _, val := range chatEmotes {
-		if emote, ok := c.emotesCache[val]; ok {
-			imgElem := fmt.Sprintf("<img src='%s'>", emote.imageURL)
-			parse.Message = strings.ReplaceAll(parse.Message, emote.code, imgElem)
-		}
+	c.Lock()
+	c.cheermotesCache[channelID] = cheermotes
+	c.Unlock()
+	return bits, cheermotes
+}
+
+// thirdPartyEmotes finds BTTV, FFZ and 7TV emotes for the channel.
+func (c *Client) thirdPartyEmotes(channelID string) emoteLookup {
+	if c.emoteStore == nil {
+		return nil
+	}
+
+	return func(code string) (*twitchemotes.Emote, bool) {
+		return c.emoteStore.Lookup(channelID, code)
 	}
 }
 
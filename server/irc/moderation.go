@@ -0,0 +1,120 @@
+package irc
+
+import "github.com/miguel250/streaming-setup/server/irc/parser"
+
+// maxRecentMessageIDs is how many message IDs are kept per user so they can
+// be removed from the overlays when the user is banned or timed out.
+const maxRecentMessageIDs = 50
+
+// ClearChat is sent when a moderator purges the whole chat, bans a user or
+// times out a user.
+type ClearChat struct {
+	Channel string `json:"channel"`
+	// UserLogin is empty when the whole chat was cleared
+	UserLogin string `json:"login,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	// Duration of the timeout in seconds, zero is a permanent ban
+	Duration   int      `json:"duration,omitempty"`
+	MessageIDs []string `json:"message_ids,omitempty"`
+	Timestamp  int64    `json:"timestamp"`
+}
+
+// IsChatCleared reports if every message in the channel was removed.
+func (c *ClearChat) IsChatCleared() bool {
+	return c.UserLogin == ""
+}
+
+// IsTimeout reports if the user was only timed out instead of banned.
+func (c *ClearChat) IsTimeout() bool {
+	return c.UserLogin != "" && c.Duration > 0
+}
+
+// ClearChatListener receives chat purges, bans and timeouts.
+func (c *Client) ClearChatListener() chan *ClearChat {
+	channel := make(chan *ClearChat)
+	c.Lock()
+	defer c.Unlock()
+	c.onClearChat = append(c.onClearChat, channel)
+	return channel
+}
+
+func (c *Client) handleClearChat(parse *parser.Message) {
+	msg := &ClearChat{
+		Channel:   parse.Channel,
+		UserLogin: parse.Message,
+		UserID:    parse.Tags["target-user-id"],
+		Duration:  tagInt(parse.Tags, "ban-duration"),
+		Timestamp: tagInt64(parse.Tags, "tmi-sent-ts"),
+	}
+
+	c.Lock()
+	if msg.IsChatCleared() {
+		delete(c.recentMessageIDs, msg.Channel)
+	} else {
+		msg.MessageIDs = c.recentMessageIDs[msg.Channel][msg.UserLogin]
+		delete(c.recentMessageIDs[msg.Channel], msg.UserLogin)
+	}
+	c.Unlock()
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	for _, channel := range c.onClearChat {
+		select {
+		case channel <- msg:
+		case <-c.shutdown:
+			return
+		}
+	}
+}
+
+func (c *Client) handleClearMessage(parse *parser.Message) {
+	msg := &ClearMessage{
+		Message:   parse.Message,
+		UserLogin: parse.Tags["login"],
+		Channel:   parse.Channel,
+		MessageID: parse.Tags["target-msg-id"],
+		Timestamp: tagInt64(parse.Tags, "tmi-sent-ts"),
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	for _, channel := range c.onClearMessage {
+		select {
+		case channel <- msg:
+		case <-c.shutdown:
+			return
+		}
+	}
+}
+
+// trackMessageID remembers the last messages sent by a user in channel.
+func (c *Client) trackMessageID(channel, login, id string) {
+	if login == "" || id == "" {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	users, ok := c.recentMessageIDs[channel]
+	if !ok {
+		users = make(map[string][]string)
+		c.recentMessageIDs[channel] = users
+	}
+
+	ids := append(users[login], id)
+	if len(ids) > maxRecentMessageIDs {
+		ids = ids[len(ids)-maxRecentMessageIDs:]
+	}
+	users[login] = ids
+}
@@ -0,0 +1,139 @@
+package irc_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+)
+
+func TestClearChat(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	var buf bytes.Buffer
+	buf.WriteString("@id=msg-1 :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #miguelcodetv :first\n")
+	buf.WriteString("@id=msg-2 :spammer!spammer@spammer.tmi.twitch.tv PRIVMSG #miguelcodetv :buy followers\n")
+	buf.WriteString("@id=msg-3 :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #miguelcodetv :second\n")
+	buf.WriteString("@ban-duration=600;room-id=558843277;target-user-id=1337;tmi-sent-ts=1601065308944 :tmi.twitch.tv CLEARCHAT #miguelcodetv :ronni\n")
+	buf.WriteString("@room-id=558843277;target-user-id=42;tmi-sent-ts=1601065308945 :tmi.twitch.tv CLEARCHAT #miguelcodetv :spammer\n")
+	buf.WriteString("@room-id=558843277;tmi-sent-ts=1601065308946 :tmi.twitch.tv CLEARCHAT #miguelcodetv")
+	chatServerMock.SetResponse(&buf)
+
+	messages := client.MessageListener()
+	clearChat := client.ClearChatListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	for _, wantID := range []string{"msg-1", "msg-2", "msg-3"} {
+		msg := <-messages
+		if msg.ID != wantID {
+			t.Errorf("message id doesn't match want: %s, got: %s", wantID, msg.ID)
+		}
+	}
+
+	for _, test := range []struct {
+		want        *irc.ClearChat
+		timeout     bool
+		chatCleared bool
+	}{
+		{
+			&irc.ClearChat{
+				Channel:    "miguelcodetv",
+				UserLogin:  "ronni",
+				UserID:     "1337",
+				Duration:   600,
+				MessageIDs: []string{"msg-1", "msg-3"},
+				Timestamp:  1601065308944,
+			},
+			true,
+			false,
+		},
+		{
+			&irc.ClearChat{
+				Channel:    "miguelcodetv",
+				UserLogin:  "spammer",
+				UserID:     "42",
+				MessageIDs: []string{"msg-2"},
+				Timestamp:  1601065308945,
+			},
+			false,
+			false,
+		},
+		{
+			&irc.ClearChat{
+				Channel:   "miguelcodetv",
+				Timestamp: 1601065308946,
+			},
+			false,
+			true,
+		},
+	} {
+		select {
+		case got := <-clearChat:
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("clear chat doesn't match\nwant: %+v\ngot:  %+v", test.want, got)
+			}
+
+			if got.IsTimeout() != test.timeout {
+				t.Errorf("IsTimeout doesn't match want: %t, got: %t", test.timeout, got.IsTimeout())
+			}
+
+			if got.IsChatCleared() != test.chatCleared {
+				t.Errorf("IsChatCleared doesn't match want: %t, got: %t", test.chatCleared, got.IsChatCleared())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for clear chat")
+		}
+	}
+}
+
+func TestClearChatPerChannel(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	var buf bytes.Buffer
+	buf.WriteString("@id=msg-1 :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #miguelcodetv :first\n")
+	buf.WriteString("@id=msg-2 :ronni!ronni@ronni.tmi.twitch.tv PRIVMSG #other_channel :second\n")
+	buf.WriteString("@id=msg-3 :spammer!spammer@spammer.tmi.twitch.tv PRIVMSG #miguelcodetv :third\n")
+	buf.WriteString("@room-id=42;target-user-id=1337;tmi-sent-ts=1601065308944 :tmi.twitch.tv CLEARCHAT #other_channel :ronni\n")
+	buf.WriteString("@room-id=42;tmi-sent-ts=1601065308945 :tmi.twitch.tv CLEARCHAT #other_channel\n")
+	buf.WriteString("@room-id=558843277;target-user-id=1337;tmi-sent-ts=1601065308946 :tmi.twitch.tv CLEARCHAT #miguelcodetv :ronni\n")
+	buf.WriteString("@room-id=558843277;target-user-id=7;tmi-sent-ts=1601065308947 :tmi.twitch.tv CLEARCHAT #miguelcodetv :spammer")
+	chatServerMock.SetResponse(&buf)
+
+	messages := client.MessageListener()
+	clearChat := client.ClearChatListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		<-messages
+	}
+
+	for _, want := range []struct {
+		channel string
+		ids     []string
+	}{
+		{"other_channel", []string{"msg-2"}},
+		{"other_channel", nil},
+		{"miguelcodetv", []string{"msg-1"}},
+		{"miguelcodetv", []string{"msg-3"}},
+	} {
+		select {
+		case got := <-clearChat:
+			if got.Channel != want.channel || !reflect.DeepEqual(got.MessageIDs, want.ids) {
+				t.Errorf("clear chat doesn't match want: %s %v, got: %s %v", want.channel, want.ids, got.Channel, got.MessageIDs)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for clear chat")
+		}
+	}
+}
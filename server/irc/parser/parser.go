@@ -17,10 +17,12 @@ type Message struct {
 	raw          string
 	scan         *scanner.Scanner
 	Command      token.Token       `json:"-"`
+	Subcommand   token.Token       `json:"-"` // ACK or NAK for CAP responses
 	Username     string            `json:"username"`
 	Channel      string            `json:"channel"`
 	Message      string            `json:"message"`
 	Tags         map[string]string `json:"tags"`
+	Params       []string          `json:"params"` // values after the message like the viewers in HOSTTARGET
 	currentToken token.Token
 }
 
@@ -35,6 +37,10 @@ func (msg *Message) parseCap() error {
 				return err
 			}
 
+			if msg.currentToken == token.ACK || msg.currentToken == token.NAK {
+				msg.Subcommand = msg.currentToken
+			}
+
 			if msg.currentToken == token.COLON {
 				msg.Message = val
 				return nil
@@ -180,6 +186,65 @@ func (msg *Message) parseMessage(t token.Token) error {
 	return nil
 }
 
+// parseOptionalMessage parses commands where the trailing message can be
+// missing, CLEARCHAT only includes the user when it is a ban or timeout.
+func (msg *Message) parseOptionalMessage(t token.Token) error {
+	if msg.currentToken == t {
+		msg.Command = t
+
+		for {
+			val, err := msg.next()
+			if err == ErrEOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if msg.currentToken == token.HASH {
+				msg.Channel = val
+			}
+
+			if msg.currentToken == token.COLON {
+				msg.Message = val
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (msg *Message) parseHostTarget() error {
+	if msg.currentToken == token.HOSTTARGET {
+		msg.Command = token.HOSTTARGET
+
+		for {
+			val, err := msg.next()
+			if err == ErrEOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			switch msg.currentToken {
+			case token.HASH:
+				msg.Channel = val
+			case token.COLON:
+				// the target is followed by the viewers when there are any
+				fields := strings.Fields(val)
+				if len(fields) > 0 {
+					msg.Message = fields[0]
+					msg.Params = append(msg.Params, fields[1:]...)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (msg *Message) parseSimpleCommandWithChannel(t token.Token) error {
 	err := msg.parseSimpleCommands(t)
 	if err != nil {
@@ -228,7 +293,6 @@ func (msg *Message) next() (string, error) {
 }
 
 // ParseMsg parses a chat message sent from the Twitch chat server
-// TODO: Add support for NOTICE, ROOMSTATE, HOSTTARGET, and CLEARCHAT
 func ParseMsg(msg string) (*Message, error) {
 	resultMsg := &Message{
 		currentToken: -1,
@@ -304,6 +368,32 @@ func ParseMsg(msg string) (*Message, error) {
 			return nil, err
 		}
 
+		err = resultMsg.parseOptionalMessage(token.CLEARCHAT)
+		if err != nil {
+			return nil, err
+		}
+
+		// NOTICE is sent with * instead of a channel when login fails
+		err = resultMsg.parseOptionalMessage(token.NOTICE)
+		if err != nil {
+			return nil, err
+		}
+
+		err = resultMsg.parseSimpleCommandWithChannel(token.ROOMSTATE)
+		if err != nil {
+			return nil, err
+		}
+
+		err = resultMsg.parseHostTarget()
+		if err != nil {
+			return nil, err
+		}
+
+		err = resultMsg.parseSimpleCommands(token.WELCOME)
+		if err != nil {
+			return nil, err
+		}
+
 		val, err = resultMsg.next()
 
 		if err != nil && err == ErrEOF {
@@ -41,6 +41,75 @@ func TestParseMsg(t *testing.T) {
 				"tmi-sent-ts":   "1600803187681",
 			},
 		},
+		{
+			":tmi.twitch.tv CLEARCHAT #dallas",
+			"dallas",
+			"",
+			"",
+			token.CLEARCHAT,
+			map[string]string{},
+		},
+		{
+			"@ban-duration=600;room-id=558843277;target-user-id=239246205;tmi-sent-ts=1601065308944 :tmi.twitch.tv CLEARCHAT #miguelcodetv :ronni",
+			"miguelcodetv",
+			"ronni",
+			"",
+			token.CLEARCHAT,
+			map[string]string{
+				"ban-duration":   "600",
+				"room-id":        "558843277",
+				"target-user-id": "239246205",
+				"tmi-sent-ts":    "1601065308944",
+			},
+		},
+		{
+			"@msg-id=slow_off :tmi.twitch.tv NOTICE #dallas :This room is no longer in slow mode.",
+			"dallas",
+			"This room is no longer in slow mode.",
+			"",
+			token.NOTICE,
+			map[string]string{
+				"msg-id": "slow_off",
+			},
+		},
+		{
+			":tmi.twitch.tv NOTICE * :Login authentication failed",
+			"",
+			"Login authentication failed",
+			"",
+			token.NOTICE,
+			map[string]string{},
+		},
+		{
+			"@emote-only=0;followers-only=-1;r9k=0;rituals=0;room-id=558843277;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #miguelcodetv",
+			"miguelcodetv",
+			"",
+			"",
+			token.ROOMSTATE,
+			map[string]string{
+				"emote-only":     "0",
+				"followers-only": "-1",
+				"r9k":            "0",
+				"slow":           "0",
+				"subs-only":      "0",
+			},
+		},
+		{
+			":tmi.twitch.tv HOSTTARGET #hosting_channel :target_channel 12",
+			"hosting_channel",
+			"target_channel",
+			"",
+			token.HOSTTARGET,
+			map[string]string{},
+		},
+		{
+			":tmi.twitch.tv 001 miguelcodetv_bot :Welcome, GLHF!",
+			"",
+			"",
+			"",
+			token.WELCOME,
+			map[string]string{},
+		},
 		{
 			":tmi.twitch.tv RECONNECT",
 			"",
@@ -203,3 +272,58 @@ func TestParseMsg(t *testing.T) {
 		}
 	}
 }
+
+func TestParseHostTarget(t *testing.T) {
+	for _, test := range []struct {
+		input      string
+		wantTarget string
+		wantParams []string
+	}{
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :target_channel 12", "target_channel", []string{"12"}},
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :target_channel", "target_channel", []string{}},
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :- 0", "-", []string{"0"}},
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :-", "-", []string{}},
+	} {
+		msg, err := parser.ParseMsg(test.input)
+		if err != nil {
+			t.Fatalf("Failed to parse message with %s", err)
+		}
+
+		if msg.Message != test.wantTarget {
+			t.Errorf("Target doesn't match got: '%s', want: %s", msg.Message, test.wantTarget)
+		}
+
+		if fmt.Sprint(msg.Params) != fmt.Sprint(test.wantParams) {
+			t.Errorf("Params don't match got: %v, want: %v", msg.Params, test.wantParams)
+		}
+	}
+}
+
+func TestParseCap(t *testing.T) {
+	for _, test := range []struct {
+		input          string
+		wantSubcommand token.Token
+		wantMessage    string
+	}{
+		{":tmi.twitch.tv CAP * ACK :twitch.tv/membership", token.ACK, "twitch.tv/membership"},
+		{":tmi.twitch.tv CAP * NAK :twitch.tv/unknown", token.NAK, "twitch.tv/unknown"},
+		{":tmi.twitch.tv CAP * ACK :twitch.tv/membership twitch.tv/tags", token.ACK, "twitch.tv/membership twitch.tv/tags"},
+	} {
+		msg, err := parser.ParseMsg(test.input)
+		if err != nil {
+			t.Fatalf("Failed to parse message with %s", err)
+		}
+
+		if msg.Command != token.CAP {
+			t.Errorf("Command doesn't match got: '%s', want: %s", msg.Command, token.CAP)
+		}
+
+		if msg.Subcommand != test.wantSubcommand {
+			t.Errorf("Subcommand doesn't match got: '%s', want: %s", msg.Subcommand, test.wantSubcommand)
+		}
+
+		if msg.Message != test.wantMessage {
+			t.Errorf("Message doesn't match got: '%s', want: %s", msg.Message, test.wantMessage)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Twitch chat limits, see https://dev.twitch.tv/docs/irc/guide#rate-limits
+const (
+	userMessageLimit      = 20
+	moderatorMessageLimit = 100
+	messageLimitWindow    = 30 * time.Second
+	joinLimit             = 20
+	joinLimitWindow       = 10 * time.Second
+	duplicateWindow       = 30 * time.Second
+	defaultSendQueueSize  = 256
+)
+
+var (
+	ErrQueueFull        = errors.New("irc: send queue is full")
+	ErrDuplicateMessage = errors.New("irc: same message was sent in the last 30 seconds")
+	ErrMessageDropped   = errors.New("irc: client closed before the message was sent")
+)
+
+// tokenBucket allows capacity actions per window. Tokens are refilled
+// continuously so the bucket never allows more than capacity in any window.
+type tokenBucket struct {
+	sync.Mutex
+	capacity float64
+	tokens   float64
+	perToken time.Duration
+	last     time.Time
+	now      func() time.Time
+}
+
+// take consumes a token when one is available and returns zero, otherwise it
+// returns how long to wait before trying again.
+func (b *tokenBucket) take() time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	now := b.now()
+	elapsed := now.Sub(b.last)
+	b.last = now
+
+	b.tokens += float64(elapsed) / float64(b.perToken)
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) * float64(b.perToken))
+}
+
+func newTokenBucket(capacity int, window time.Duration, now func() time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		perToken: window / time.Duration(capacity),
+		last:     now(),
+		now:      now,
+	}
+}
+
+type outgoing struct {
+	ctx     context.Context
+	command chatCommand
+	channel string
+	message string
+	// sent receives the result once the message is written or dropped, it
+	// is nil when nobody waits for it
+	sent chan error
+}
+
+func (o *outgoing) done(err error) {
+	if o.sent != nil {
+		o.sent <- err
+	}
+}
+
+type sendQueue struct {
+	sync.Mutex
+	queue          chan *outgoing
+	pending        int64
+	userBucket     *tokenBucket
+	modBucket      *tokenBucket
+	joinBucket     *tokenBucket
+	moderatorIn    map[string]bool
+	recentMessages map[string]time.Time
+	now            func() time.Time
+}
+
+// isDuplicate reports if the same message was sent to the channel in the
+// last 30 seconds and records it otherwise.
+func (q *sendQueue) isDuplicate(message string) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	now := q.now()
+	for key, sentAt := range q.recentMessages {
+		if now.Sub(sentAt) >= duplicateWindow {
+			delete(q.recentMessages, key)
+		}
+	}
+
+	if _, ok := q.recentMessages[message]; ok {
+		return true
+	}
+
+	q.recentMessages[message] = now
+	return false
+}
+
+// forget removes a message recorded by isDuplicate that was never queued.
+func (q *sendQueue) forget(message string) {
+	q.Lock()
+	defer q.Unlock()
+	delete(q.recentMessages, message)
+}
+
+func (q *sendQueue) setModerator(channel string, isModerator bool) {
+	q.Lock()
+	defer q.Unlock()
+	q.moderatorIn[channel] = isModerator
+}
+
+func (q *sendQueue) bucket(item *outgoing) *tokenBucket {
+	switch item.command {
+	case Join:
+		return q.joinBucket
+	case PrivMsg:
+		q.Lock()
+		defer q.Unlock()
+
+		if q.moderatorIn[item.channel] {
+			return q.modBucket
+		}
+		return q.userBucket
+	}
+	return nil
+}
+
+func newSendQueue(size int) *sendQueue {
+	if size <= 0 {
+		size = defaultSendQueueSize
+	}
+
+	return &sendQueue{
+		queue:          make(chan *outgoing, size),
+		userBucket:     newTokenBucket(userMessageLimit, messageLimitWindow, time.Now),
+		modBucket:      newTokenBucket(moderatorMessageLimit, messageLimitWindow, time.Now),
+		joinBucket:     newTokenBucket(joinLimit, joinLimitWindow, time.Now),
+		moderatorIn:    make(map[string]bool),
+		recentMessages: make(map[string]time.Time),
+		now:            time.Now,
+	}
+}
+
+// QueueDepth returns how many messages are waiting to be sent to the chat
+// server.
+func (c *Client) QueueDepth() int {
+	return int(atomic.LoadInt64(&c.sendQueue.pending))
+}
+
+func (c *Client) enqueue(ctx context.Context, command chatCommand, message string, sent chan error) error {
+	c.connMutex.RLock()
+	started := c.conn != nil
+	c.connMutex.RUnlock()
+
+	if !started {
+		return errors.New("client is not started yet")
+	}
+
+	item := &outgoing{
+		ctx:     ctx,
+		command: command,
+		channel: strings.SplitN(message, " ", 2)[0],
+		message: message,
+		sent:    sent,
+	}
+
+	if command == PrivMsg && c.sendQueue.isDuplicate(message) {
+		return ErrDuplicateMessage
+	}
+
+	atomic.AddInt64(&c.sendQueue.pending, 1)
+	select {
+	case c.sendQueue.queue <- item:
+		return nil
+	default:
+		atomic.AddInt64(&c.sendQueue.pending, -1)
+
+		// nothing was sent, the same message can be retried
+		if command == PrivMsg {
+			c.sendQueue.forget(message)
+		}
+		return ErrQueueFull
+	}
+}
+
+func (c *Client) writeLoop() {
+	for {
+		select {
+		case item := <-c.sendQueue.queue:
+			c.sendQueued(item)
+		case <-c.shutdown:
+			return
+		}
+	}
+}
+
+func (c *Client) sendQueued(item *outgoing) {
+	err := c.waitForToken(item)
+	atomic.AddInt64(&c.sendQueue.pending, -1)
+
+	if err == nil {
+		err = c.write(commandToString[item.command], item.message)
+		if err != nil {
+			log.Printf("failed to send message to %s with %s\n", item.channel, err)
+		}
+	}
+
+	// nothing was sent, the same message can be retried
+	if err != nil && item.command == PrivMsg {
+		c.sendQueue.forget(item.message)
+	}
+	item.done(err)
+}
+
+// waitForToken blocks until the rate limiter allows item to be sent. It
+// returns why the item should be dropped instead.
+func (c *Client) waitForToken(item *outgoing) error {
+	bucket := c.sendQueue.bucket(item)
+
+	for bucket != nil {
+		wait := bucket.take()
+		if wait <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-item.ctx.Done():
+			timer.Stop()
+			log.Printf("dropping message to %s with %s\n", item.channel, item.ctx.Err())
+			return item.ctx.Err()
+		case <-c.shutdown:
+			timer.Stop()
+			return ErrMessageDropped
+		}
+	}
+
+	if err := item.ctx.Err(); err != nil {
+		log.Printf("dropping message to %s with %s\n", item.channel, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package irc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	current time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.current
+}
+
+func (f *fakeClock) add(d time.Duration) {
+	f.current = f.current.Add(d)
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1600000000, 0)}
+	bucket := newTokenBucket(20, 30*time.Second, clock.now)
+
+	for i := 0; i < 20; i++ {
+		if wait := bucket.take(); wait != 0 {
+			t.Fatalf("token %d should be available, got wait: %s", i, wait)
+		}
+	}
+
+	wait := bucket.take()
+	want := 1500 * time.Millisecond
+	if wait != want {
+		t.Fatalf("wait time doesn't match got: %s, want: %s", wait, want)
+	}
+
+	clock.add(want)
+	if wait := bucket.take(); wait != 0 {
+		t.Fatalf("token should be available after waiting, got wait: %s", wait)
+	}
+
+	clock.add(time.Hour)
+	for i := 0; i < 20; i++ {
+		if wait := bucket.take(); wait != 0 {
+			t.Fatalf("token %d should be available, got wait: %s", i, wait)
+		}
+	}
+
+	if wait := bucket.take(); wait == 0 {
+		t.Fatal("bucket shouldn't refill over its capacity")
+	}
+}
+
+func TestSendQueueDuplicates(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(1600000000, 0)}
+	queue := newSendQueue(10)
+	queue.now = clock.now
+
+	if queue.isDuplicate("test_channel :hello") {
+		t.Fatal("first message can't be a duplicate")
+	}
+
+	if !queue.isDuplicate("test_channel :hello") {
+		t.Fatal("same message within 30 seconds should be a duplicate")
+	}
+
+	if queue.isDuplicate("other_channel :hello") {
+		t.Fatal("same message to another channel isn't a duplicate")
+	}
+
+	clock.add(duplicateWindow)
+	if queue.isDuplicate("test_channel :hello") {
+		t.Fatal("same message after 30 seconds isn't a duplicate")
+	}
+}
+
+func TestSendQueueFullIsNotDuplicate(t *testing.T) {
+	conn, other := net.Pipe()
+	defer conn.Close()
+	defer other.Close()
+
+	c := &Client{
+		conf:      &Config{Channel: "test_channel"},
+		conn:      conn,
+		sendQueue: newSendQueue(1),
+		shutdown:  make(chan struct{}),
+	}
+
+	if err := c.SendMessageTo("test_channel", "first"); err != nil {
+		t.Fatalf("failed to queue message with %s", err)
+	}
+
+	// nothing drains the queue
+	for i := 0; i < 2; i++ {
+		if err := c.SendMessageTo("test_channel", "second"); err != ErrQueueFull {
+			t.Fatalf("error doesn't match got: %v, want: %s", err, ErrQueueFull)
+		}
+	}
+}
+
+func TestSendQueueBuckets(t *testing.T) {
+	queue := newSendQueue(10)
+	queue.setModerator("miguelcodetv", true)
+
+	for _, test := range []struct {
+		name string
+		item *outgoing
+		want *tokenBucket
+	}{
+		{"join", &outgoing{command: Join, channel: "miguelcodetv"}, queue.joinBucket},
+		{"moderator", &outgoing{command: PrivMsg, channel: "miguelcodetv"}, queue.modBucket},
+		{"user", &outgoing{command: PrivMsg, channel: "attackkopter"}, queue.userBucket},
+		{"part", &outgoing{command: Part, channel: "attackkopter"}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := queue.bucket(test.item); got != test.want {
+				t.Errorf("bucket doesn't match for %s", test.name)
+			}
+		})
+	}
+}
+
+func TestSendQueueCancel(t *testing.T) {
+	conf := &Config{Channel: "test_channel"}
+	c := &Client{
+		conf:      conf,
+		sendQueue: newSendQueue(1),
+		shutdown:  make(chan struct{}),
+	}
+	c.sendQueue.userBucket = newTokenBucket(1, time.Hour, time.Now)
+	c.sendQueue.userBucket.take()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := make(chan error, 1)
+	item := &outgoing{ctx: ctx, command: PrivMsg, channel: "test_channel", message: "test_channel :hi", sent: sent}
+	c.sendQueue.isDuplicate(item.message)
+
+	done := make(chan struct{})
+	go func() {
+		c.sendQueued(item)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("canceled message should stop waiting for the rate limiter")
+	}
+
+	if err := <-sent; err != context.Canceled {
+		t.Errorf("dropped message error doesn't match got: %v, want: %s", err, context.Canceled)
+	}
+
+	if c.sendQueue.isDuplicate(item.message) {
+		t.Error("dropped message should be sendable again")
+	}
+}
@@ -0,0 +1,251 @@
+package irc
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/miguel250/streaming-setup/server/irc/parser"
+)
+
+// Errors for NOTICE messages sent when the server refuses a login or a
+// message, see https://dev.twitch.tv/docs/irc/msg-id
+var (
+	ErrLoginFailed       = errors.New("invalid oauth token")
+	ErrBanned            = errors.New("irc: account is banned from the channel")
+	ErrChannelSuspended  = errors.New("irc: channel is suspended")
+	ErrMessageRateLimit  = errors.New("irc: message was sent too quickly")
+	ErrSlowMode          = errors.New("irc: channel is in slow mode")
+	ErrFollowersOnly     = errors.New("irc: channel is in followers-only mode")
+	ErrSubsOnly          = errors.New("irc: channel is in subscribers-only mode")
+	ErrEmoteOnly         = errors.New("irc: channel is in emote-only mode")
+	ErrR9K               = errors.New("irc: message is not unique")
+	ErrTimedOut          = errors.New("irc: account is timed out in the channel")
+	ErrVerifiedEmailOnly = errors.New("irc: channel requires a verified email")
+)
+
+var noticeErrors = map[string]error{
+	"msg_banned":                  ErrBanned,
+	"msg_channel_suspended":       ErrChannelSuspended,
+	"msg_ratelimit":               ErrMessageRateLimit,
+	"msg_duplicate":               ErrDuplicateMessage,
+	"msg_slowmode":                ErrSlowMode,
+	"msg_followersonly":           ErrFollowersOnly,
+	"msg_followersonly_followed":  ErrFollowersOnly,
+	"msg_followersonly_zero":      ErrFollowersOnly,
+	"msg_subsonly":                ErrSubsOnly,
+	"msg_emoteonly":               ErrEmoteOnly,
+	"msg_r9k":                     ErrR9K,
+	"msg_timedout":                ErrTimedOut,
+	"msg_verified_email":          ErrVerifiedEmailOnly,
+	"msg_requires_verified_phone": ErrVerifiedEmailOnly,
+}
+
+// Notice is a general message from the server like mode changes or why a
+// message wasn't delivered.
+type Notice struct {
+	Channel string `json:"channel"`
+	MsgID   string `json:"msg_id"`
+	Message string `json:"message"`
+}
+
+// Err returns the error the notice stands for, nil when it is only
+// informative.
+func (n *Notice) Err() error {
+	if n.isLoginFailure() {
+		return ErrLoginFailed
+	}
+	return noticeErrors[n.MsgID]
+}
+
+// isLoginFailure reports a wrong oauth token, Twitch sends these without
+// msg-id and without a channel.
+func (n *Notice) isLoginFailure() bool {
+	return n.MsgID == "" && (strings.Contains(n.Message, "Login authentication failed") ||
+		strings.Contains(n.Message, "Improperly formatted auth"))
+}
+
+// RoomState has the chat modes of a channel.
+type RoomState struct {
+	Channel   string `json:"channel"`
+	EmoteOnly bool   `json:"emote_only"`
+	// FollowersOnly is how many minutes users must follow before chatting,
+	// -1 means followers-only mode is off
+	FollowersOnly int  `json:"followers_only"`
+	R9K           bool `json:"r9k"`
+	// Slow is how many seconds users must wait between messages
+	Slow     int  `json:"slow"`
+	SubsOnly bool `json:"subs_only"`
+}
+
+// HostTarget is sent when a channel starts or stops hosting another channel.
+type HostTarget struct {
+	Channel string `json:"channel"`
+	// Target is empty when the channel stopped hosting
+	Target  string `json:"target"`
+	Viewers int    `json:"viewers"`
+}
+
+// RoomState returns the last known chat modes of a channel.
+func (c *Client) RoomState(channel string) (RoomState, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	state, ok := c.roomStates[normalizeChannel(channel)]
+	if !ok {
+		return RoomState{}, false
+	}
+	return *state, true
+}
+
+// NoticeListener receives every NOTICE from the server.
+func (c *Client) NoticeListener() chan *Notice {
+	channel := make(chan *Notice)
+	c.Lock()
+	defer c.Unlock()
+	c.onNotice = append(c.onNotice, channel)
+	return channel
+}
+
+// RoomStateListener receives the chat modes of a channel every time they
+// change.
+func (c *Client) RoomStateListener() chan *RoomState {
+	channel := make(chan *RoomState)
+	c.Lock()
+	defer c.Unlock()
+	c.onRoomState = append(c.onRoomState, channel)
+	return channel
+}
+
+// HostTargetListener receives host mode changes.
+func (c *Client) HostTargetListener() chan *HostTarget {
+	channel := make(chan *HostTarget)
+	c.Lock()
+	defer c.Unlock()
+	c.onHostTarget = append(c.onHostTarget, channel)
+	return channel
+}
+
+func (c *Client) handleNotice(parse *parser.Message) {
+	notice := &Notice{
+		Channel: parse.Channel,
+		MsgID:   parse.Tags["msg-id"],
+		Message: parse.Message,
+	}
+
+	// the send queue doesn't learn about refused messages, log why chat
+	// didn't show them
+	if err := notice.Err(); err != nil {
+		log.Printf("chat server refused message in %s with %s\n", notice.Channel, err)
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	for _, channel := range c.onNotice {
+		select {
+		case channel <- notice:
+		case <-c.shutdown:
+			return
+		}
+	}
+}
+
+// handleRoomState merges the modes into the channel snapshot, Twitch only
+// sends the tags that changed after the first ROOMSTATE.
+func (c *Client) handleRoomState(parse *parser.Message) {
+	channel := normalizeChannel(parse.Channel)
+	tags := parse.Tags
+
+	c.Lock()
+	state, ok := c.roomStates[channel]
+	if !ok {
+		state = &RoomState{
+			Channel:       channel,
+			FollowersOnly: -1,
+		}
+		c.roomStates[channel] = state
+
+		// the first ROOMSTATE after joining is the only place with the
+		// channel ID for channels other than the configured one
+		if c.emoteStore != nil {
+			c.emoteStore.AddChannel(tags["room-id"])
+		}
+	}
+
+	if val, ok := tags["emote-only"]; ok {
+		state.EmoteOnly = val == "1"
+	}
+
+	if _, ok := tags["followers-only"]; ok {
+		state.FollowersOnly = tagInt(tags, "followers-only")
+	}
+
+	if val, ok := tags["r9k"]; ok {
+		state.R9K = val == "1"
+	}
+
+	if _, ok := tags["slow"]; ok {
+		state.Slow = tagInt(tags, "slow")
+	}
+
+	if val, ok := tags["subs-only"]; ok {
+		state.SubsOnly = val == "1"
+	}
+
+	snapshot := *state
+	c.Unlock()
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	for _, listener := range c.onRoomState {
+		msg := snapshot
+		select {
+		case listener <- &msg:
+		case <-c.shutdown:
+			return
+		}
+	}
+}
+
+func (c *Client) handleHostTarget(parse *parser.Message) {
+	host := &HostTarget{
+		Channel: parse.Channel,
+		Target:  parse.Message,
+	}
+
+	if host.Target == "-" {
+		host.Target = ""
+	}
+
+	if len(parse.Params) > 0 {
+		if viewers, err := strconv.Atoi(parse.Params[0]); err == nil {
+			host.Viewers = viewers
+		}
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	for _, channel := range c.onHostTarget {
+		select {
+		case channel <- host:
+		case <-c.shutdown:
+			return
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package irc_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+)
+
+func TestRoomState(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	var buf bytes.Buffer
+	buf.WriteString("@emote-only=0;followers-only=-1;r9k=0;rituals=0;room-id=558843277;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #miguelcodetv\n")
+	buf.WriteString("@room-id=558843277;slow=30 :tmi.twitch.tv ROOMSTATE #miguelcodetv\n")
+	buf.WriteString("@followers-only=10;room-id=558843277 :tmi.twitch.tv ROOMSTATE #miguelcodetv")
+	chatServerMock.SetResponse(&buf)
+
+	states := client.RoomStateListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	for _, want := range []*irc.RoomState{
+		{Channel: "miguelcodetv", FollowersOnly: -1},
+		{Channel: "miguelcodetv", FollowersOnly: -1, Slow: 30},
+		{Channel: "miguelcodetv", FollowersOnly: 10, Slow: 30},
+	} {
+		select {
+		case got := <-states:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("room state doesn't match\nwant: %+v\ngot:  %+v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for room state")
+		}
+	}
+
+	got, ok := client.RoomState("#MiguelCodeTV")
+	if !ok {
+		t.Fatal("expected room state for channel")
+	}
+
+	if got.Slow != 30 || got.FollowersOnly != 10 {
+		t.Errorf("room state snapshot doesn't match latest state, got: %+v", got)
+	}
+
+	if _, ok := client.RoomState("unknown"); ok {
+		t.Error("expected no room state for a channel without ROOMSTATE")
+	}
+}
+
+func TestNotice(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	var buf bytes.Buffer
+	buf.WriteString("@msg-id=slow_on :tmi.twitch.tv NOTICE #miguelcodetv :This room is now in slow mode.\n")
+	buf.WriteString("@msg-id=msg_banned :tmi.twitch.tv NOTICE #miguelcodetv :You are permanently banned from talking in miguelcodetv.")
+	chatServerMock.SetResponse(&buf)
+
+	notices := client.NoticeListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	for _, test := range []struct {
+		msgID   string
+		message string
+		err     error
+	}{
+		{"slow_on", "This room is now in slow mode.", nil},
+		{"msg_banned", "You are permanently banned from talking in miguelcodetv.", irc.ErrBanned},
+	} {
+		select {
+		case got := <-notices:
+			if got.MsgID != test.msgID {
+				t.Errorf("msg-id doesn't match want: %s, got: %s", test.msgID, got.MsgID)
+			}
+
+			if got.Message != test.message {
+				t.Errorf("message doesn't match want: %s, got: %s", test.message, got.Message)
+			}
+
+			if got.Err() != test.err {
+				t.Errorf("error doesn't match want: %v, got: %v", test.err, got.Err())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for notice")
+		}
+	}
+}
+
+func TestHostTarget(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	var buf bytes.Buffer
+	buf.WriteString(":tmi.twitch.tv HOSTTARGET #miguelcodetv :erikdotdev 44\n")
+	buf.WriteString(":tmi.twitch.tv HOSTTARGET #miguelcodetv :- 0\n")
+	buf.WriteString(":tmi.twitch.tv HOSTTARGET #miguelcodetv :-")
+	chatServerMock.SetResponse(&buf)
+
+	hosts := client.HostTargetListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	for _, want := range []*irc.HostTarget{
+		{Channel: "miguelcodetv", Target: "erikdotdev", Viewers: 44},
+		{Channel: "miguelcodetv"},
+		{Channel: "miguelcodetv"},
+	} {
+		select {
+		case got := <-hosts:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("host target doesn't match\nwant: %+v\ngot:  %+v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for host target")
+		}
+	}
+}
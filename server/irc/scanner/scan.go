@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"bytes"
 	"unicode/utf8"
 
 	"github.com/miguel250/streaming-setup/server/irc/token"
@@ -99,17 +100,28 @@ func (sc *Scanner) NextToken() (*value, token.Token) {
 		sc.endToken(val)
 		return val, token.ASTERISK
 	case 'A':
+		// the capabilities are separated by spaces
 		sc.moveForward(3)
 		sc.startToken(val)
 		sc.endToken(val)
+		sc.ignoreSpace = true
 		return val, token.ACK
 	case 'H':
-		// HOSTTARGET
+		// HOSTTARGET, the message has the target and the viewers
 		sc.moveForward(11)
 		sc.startToken(val)
 		sc.endToken(val)
+		sc.ignoreSpace = true
 		return val, token.HOSTTARGET
 	case 'N':
+		if bytes.HasPrefix(sc.rest, []byte("NAK")) {
+			sc.moveForward(3)
+			sc.startToken(val)
+			sc.endToken(val)
+			sc.ignoreSpace = true
+			return val, token.NAK
+		}
+
 		// NOTICE
 		sc.moveForward(6)
 		sc.startToken(val)
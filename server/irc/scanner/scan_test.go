@@ -20,6 +20,7 @@ func TestScanner(t *testing.T) {
 		{":tmi.twitch.tv RECONNECT", "colon tmi.twitch.tv whitespace reconnect"},
 		{":tmi.twitch.tv CAP * ACK :twitch.tv/membership", "colon tmi.twitch.tv whitespace cap whitespace asterisk whitespace ack whitespace colon twitch.tv/membership"},
 		{":tmi.twitch.tv CAP * ACK :twitch.tv/tags", "colon tmi.twitch.tv whitespace cap whitespace asterisk whitespace ack whitespace colon twitch.tv/tags"},
+		{":tmi.twitch.tv CAP * NAK :twitch.tv/unknown", "colon tmi.twitch.tv whitespace cap whitespace asterisk whitespace nak whitespace colon twitch.tv/unknown"},
 		{":tmi.twitch.tv 001 <user> :Welcome, GLHF!", "colon tmi.twitch.tv whitespace welcome <user> whitespace colon Welcome, GLHF!"},
 		{":tmi.twitch.tv 002 <user> :Your host is tmi.twitch.tv", "colon tmi.twitch.tv whitespace your host <user> whitespace colon Your host is tmi.twitch.tv"},
 		{":tmi.twitch.tv 003 <user> :This server is rather new", "colon tmi.twitch.tv whitespace server created <user> whitespace colon This server is rather new"},
@@ -36,8 +37,9 @@ func TestScanner(t *testing.T) {
 		{":tmi.twitch.tv CLEARCHAT #dallas", "colon tmi.twitch.tv whitespace clear chat hash dallas"},
 		{":tmi.twitch.tv CLEARCHAT #dallas :ronni", "colon tmi.twitch.tv whitespace clear chat hash dallas whitespace colon ronni"},
 		{":tmi.twitch.tv CLEARMSG #dallas :HeyGuys", "colon tmi.twitch.tv whitespace clear msg hash dallas whitespace colon HeyGuys"},
-		{":tmi.twitch.tv HOSTTARGET #hosting_channel :<channel> [1]", "colon tmi.twitch.tv whitespace host target hash hosting_channel whitespace colon <channel> whitespace [ 1 ]"},
-		{":tmi.twitch.tv HOSTTARGET #hosting_channel :- [1]", "colon tmi.twitch.tv whitespace host target hash hosting_channel whitespace colon - whitespace [ 1 ]"},
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :<channel> 1", "colon tmi.twitch.tv whitespace host target hash hosting_channel whitespace colon <channel> 1"},
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :- 1", "colon tmi.twitch.tv whitespace host target hash hosting_channel whitespace colon - 1"},
+		{":tmi.twitch.tv HOSTTARGET #hosting_channel :<channel>", "colon tmi.twitch.tv whitespace host target hash hosting_channel whitespace colon <channel>"},
 		{":tmi.twitch.tv NOTICE #dallas :This room is no longer in slow mode.", "colon tmi.twitch.tv whitespace notice whitespace hash dallas whitespace colon This room is no longer in slow mode."},
 		{":tmi.twitch.tv ROOMSTATE #<channel>", "colon tmi.twitch.tv whitespace room state whitespace hash <channel>"},
 		{":tmi.twitch.tv USERNOTICE #<channel> :message", "colon tmi.twitch.tv whitespace user notice hash <channel> whitespace colon message"},
@@ -31,6 +31,7 @@ const (
 	// Commands
 	CAP
 	ACK
+	NAK
 	PING
 	JOIN
 	PART
@@ -79,6 +80,7 @@ var tokenName = [...]string{
 
 	CAP:             "cap",
 	ACK:             "ack",
+	NAK:             "nak",
 	PING:            "ping",
 	JOIN:            "join",
 	PART:            "part",
@@ -0,0 +1,140 @@
+package irc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+const defaultTLSPort = "6697"
+
+// Transport opens the connection to the chat server. Whatever it returns is
+// read line by line and fed to the parser.
+type Transport interface {
+	Dial() (io.ReadWriteCloser, error)
+}
+
+type tcpTransport struct {
+	addr string
+}
+
+func (t *tcpTransport) Dial() (io.ReadWriteCloser, error) {
+	return net.Dial("tcp", t.addr)
+}
+
+type tlsTransport struct {
+	addr   string
+	config *tls.Config
+}
+
+func (t *tlsTransport) Dial() (io.ReadWriteCloser, error) {
+	return tls.Dial("tcp", t.addr, t.config)
+}
+
+type websocketTransport struct {
+	url    string
+	dialer *websocket.Dialer
+}
+
+func (t *websocketTransport) Dial() (io.ReadWriteCloser, error) {
+	conn, _, err := t.dialer.Dial(t.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &websocketConn{conn: conn}, nil
+}
+
+// websocketConn exposes a WebSocket connection as a stream. Every write is
+// sent as its own text frame and frames are read back to back.
+type websocketConn struct {
+	readMutex  sync.Mutex
+	writeMutex sync.Mutex
+	conn       *websocket.Conn
+	reader     io.Reader
+}
+
+func (w *websocketConn) Read(p []byte) (int, error) {
+	w.readMutex.Lock()
+	defer w.readMutex.Unlock()
+
+	for {
+		if w.reader == nil {
+			_, reader, err := w.conn.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			w.reader = reader
+		}
+
+		n, err := w.reader.Read(p)
+		if err == io.EOF {
+			w.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (w *websocketConn) Write(p []byte) (int, error) {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	err := w.conn.WriteMessage(websocket.TextMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *websocketConn) Close() error {
+	return w.conn.Close()
+}
+
+// newTransport picks the transport based on the scheme of the configured
+// URL. irc:// is plain TCP, ircs:// is TLS and ws:// or wss:// is IRC over
+// WebSocket. An address without scheme uses plain TCP.
+func newTransport(conf *Config) (Transport, error) {
+	if !strings.Contains(conf.URL, "://") {
+		return &tcpTransport{addr: conf.URL}, nil
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("irc config: invalid url %w", err)
+	}
+
+	switch u.Scheme {
+	case "irc":
+		return &tcpTransport{addr: u.Host}, nil
+	case "ircs":
+		addr := u.Host
+		if u.Port() == "" {
+			addr = net.JoinHostPort(u.Hostname(), defaultTLSPort)
+		}
+
+		config := conf.TLSConfig
+		if config == nil {
+			config = &tls.Config{}
+		}
+
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName = u.Hostname()
+		}
+		return &tlsTransport{addr: addr, config: config}, nil
+	case "ws", "wss":
+		dialer := *websocket.DefaultDialer
+		dialer.TLSClientConfig = conf.TLSConfig
+		return &websocketTransport{url: u.String(), dialer: &dialer}, nil
+	}
+	return nil, fmt.Errorf("irc config: unsupported url scheme %s", u.Scheme)
+}
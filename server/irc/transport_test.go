@@ -0,0 +1,88 @@
+package irc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+)
+
+func TestTransports(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		configure func(t *testing.T, conf *irc.Config)
+	}{
+		{
+			"irc scheme",
+			func(t *testing.T, conf *irc.Config) {
+				ts := util.MockTwitchChatServer(t)
+				ts.Start()
+				t.Cleanup(ts.Shutdown)
+				conf.URL = "irc://" + ts.Addr()
+			},
+		},
+		{
+			"ircs scheme",
+			func(t *testing.T, conf *irc.Config) {
+				ts, tlsConfig := util.MockTwitchChatTLSServer(t)
+				ts.Start()
+				t.Cleanup(ts.Shutdown)
+				conf.URL = "ircs://" + ts.Addr()
+				conf.TLSConfig = tlsConfig
+			},
+		},
+		{
+			"ws scheme",
+			func(t *testing.T, conf *irc.Config) {
+				conf.URL = util.MockTwitchChatWebSocketServer(t)
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client := util.CreateMockChatClientWithConfig(t, func(conf *irc.Config) {
+				test.configure(t, conf)
+			})
+
+			err := client.Start()
+			if err != nil {
+				t.Fatalf("failed to start irc client with %s", err)
+			}
+
+			messages := client.MessageListener()
+
+			for _, want := range []string{"first message", "second message"} {
+				err = client.SendMessage(want)
+				if err != nil {
+					t.Fatalf("failed to send message with %s", err)
+				}
+
+				select {
+				case data := <-messages:
+					if data.Message != want {
+						t.Errorf("Message doesn't match want: %s, got: %s", want, data.Message)
+					}
+
+					if data.Channel != "test_channel" {
+						t.Errorf("Channel doesn't match want: test_channel, got: %s", data.Channel)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for message %s", want)
+				}
+			}
+		})
+	}
+}
+
+func TestTransportInvalidScheme(t *testing.T) {
+	_, err := irc.New(&irc.Config{
+		Auth:    "test",
+		URL:     "http://irc.chat.twitch.tv",
+		Name:    "account_name",
+		Channel: "test_channel",
+	})
+
+	if err == nil {
+		t.Fatal("expected an error for an unsupported url scheme")
+	}
+}
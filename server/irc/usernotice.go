@@ -0,0 +1,212 @@
+package irc
+
+import (
+	"strconv"
+
+	"github.com/miguel250/streaming-setup/server/irc/parser"
+)
+
+// UserNotice has the fields shared by every USERNOTICE sent by Twitch.
+type UserNotice struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Channel       string `json:"channel"`
+	UserID        string `json:"user_id"`
+	Login         string `json:"login"`
+	DisplayName   string `json:"display_name"`
+	SystemMessage string `json:"system_message"`
+	Message       string `json:"message"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// Notice returns the shared fields of the event.
+func (u *UserNotice) Notice() *UserNotice {
+	return u
+}
+
+// UserNoticeEvent is implemented by all typed USERNOTICE events.
+type UserNoticeEvent interface {
+	Notice() *UserNotice
+}
+
+// SubEvent is sent when a user subscribes for the first time.
+type SubEvent struct {
+	UserNotice
+	Plan     string `json:"plan"`
+	PlanName string `json:"plan_name"`
+}
+
+// ResubEvent is sent when a user shares a subscription anniversary.
+type ResubEvent struct {
+	UserNotice
+	Plan             string `json:"plan"`
+	PlanName         string `json:"plan_name"`
+	CumulativeMonths int    `json:"cumulative_months"`
+	StreakMonths     int    `json:"streak_months"`
+	ShareStreak      bool   `json:"share_streak"`
+}
+
+// GiftSubEvent is sent when a user gifts a subscription to another user.
+type GiftSubEvent struct {
+	UserNotice
+	Plan                 string `json:"plan"`
+	PlanName             string `json:"plan_name"`
+	Months               int    `json:"months"`
+	RecipientID          string `json:"recipient_id"`
+	RecipientLogin       string `json:"recipient_login"`
+	RecipientDisplayName string `json:"recipient_display_name"`
+	Anonymous            bool   `json:"anonymous"`
+}
+
+// MysteryGiftEvent is sent when a user gifts subscriptions to random users
+// in the channel. A GiftSubEvent follows for every recipient.
+type MysteryGiftEvent struct {
+	UserNotice
+	Plan        string `json:"plan"`
+	Count       int    `json:"count"`
+	SenderTotal int    `json:"sender_total"`
+	Anonymous   bool   `json:"anonymous"`
+}
+
+// RaidEvent is sent when another channel raids the channel.
+type RaidEvent struct {
+	UserNotice
+	ViewerCount     int    `json:"viewer_count"`
+	ProfileImageURL string `json:"profile_image_url"`
+}
+
+// RitualEvent is sent for chat rituals like a new chatter saying hi.
+type RitualEvent struct {
+	UserNotice
+	Ritual string `json:"ritual"`
+}
+
+// BitsBadgeTierEvent is sent when a user earns a new bits badge tier.
+type BitsBadgeTierEvent struct {
+	UserNotice
+	Threshold int `json:"threshold"`
+}
+
+// parseUserNotice decodes a USERNOTICE using its msg-id tag. It returns nil
+// for notices we don't handle yet.
+func parseUserNotice(parse *parser.Message) UserNoticeEvent {
+	tags := parse.Tags
+
+	notice := UserNotice{
+		ID:            tags["id"],
+		Type:          tags["msg-id"],
+		Channel:       parse.Channel,
+		UserID:        tags["user-id"],
+		Login:         tags["login"],
+		DisplayName:   tags["display-name"],
+		SystemMessage: tags["system-msg"],
+		Message:       parse.Message,
+		Timestamp:     tagInt64(tags, "tmi-sent-ts"),
+	}
+
+	if notice.DisplayName == "" {
+		notice.DisplayName = notice.Login
+	}
+
+	switch notice.Type {
+	case "sub":
+		return &SubEvent{
+			UserNotice: notice,
+			Plan:       tags["msg-param-sub-plan"],
+			PlanName:   tags["msg-param-sub-plan-name"],
+		}
+	case "resub":
+		return &ResubEvent{
+			UserNotice:       notice,
+			Plan:             tags["msg-param-sub-plan"],
+			PlanName:         tags["msg-param-sub-plan-name"],
+			CumulativeMonths: tagInt(tags, "msg-param-cumulative-months"),
+			StreakMonths:     tagInt(tags, "msg-param-streak-months"),
+			ShareStreak:      tags["msg-param-should-share-streak"] == "1",
+		}
+	case "subgift", "anonsubgift":
+		return &GiftSubEvent{
+			UserNotice:           notice,
+			Plan:                 tags["msg-param-sub-plan"],
+			PlanName:             tags["msg-param-sub-plan-name"],
+			Months:               tagInt(tags, "msg-param-months"),
+			RecipientID:          tags["msg-param-recipient-id"],
+			RecipientLogin:       tags["msg-param-recipient-user-name"],
+			RecipientDisplayName: tags["msg-param-recipient-display-name"],
+			Anonymous:            notice.Type == "anonsubgift",
+		}
+	case "submysterygift", "anonsubmysterygift":
+		return &MysteryGiftEvent{
+			UserNotice:  notice,
+			Plan:        tags["msg-param-sub-plan"],
+			Count:       tagInt(tags, "msg-param-mass-gift-count"),
+			SenderTotal: tagInt(tags, "msg-param-sender-count"),
+			Anonymous:   notice.Type == "anonsubmysterygift",
+		}
+	case "raid":
+		return &RaidEvent{
+			UserNotice:      notice,
+			ViewerCount:     tagInt(tags, "msg-param-viewerCount"),
+			ProfileImageURL: tags["msg-param-profileImageURL"],
+		}
+	case "ritual":
+		return &RitualEvent{
+			UserNotice: notice,
+			Ritual:     tags["msg-param-ritual-name"],
+		}
+	case "bitsbadgetier":
+		return &BitsBadgeTierEvent{
+			UserNotice: notice,
+			Threshold:  tagInt(tags, "msg-param-threshold"),
+		}
+	}
+	return nil
+}
+
+func tagInt(tags map[string]string, key string) int {
+	i, err := strconv.Atoi(tags[key])
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+func tagInt64(tags map[string]string, key string) int64 {
+	i, err := strconv.ParseInt(tags[key], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// UserNoticeListener receives subscriptions, gift subs, raids, rituals and
+// bits badge tiers as typed events.
+func (c *Client) UserNoticeListener() chan UserNoticeEvent {
+	channel := make(chan UserNoticeEvent)
+	c.Lock()
+	defer c.Unlock()
+	c.onUserNotice = append(c.onUserNotice, channel)
+	return channel
+}
+
+func (c *Client) handleUserNotice(parse *parser.Message) {
+	event := parseUserNotice(parse)
+	if event == nil {
+		return
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.isClosed() {
+		return
+	}
+
+	for _, channel := range c.onUserNotice {
+		select {
+		case channel <- event:
+		case <-c.shutdown:
+			return
+		}
+	}
+}
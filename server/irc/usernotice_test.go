@@ -0,0 +1,204 @@
+package irc_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/irc/util"
+)
+
+func TestUserNotice(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input string
+		want  irc.UserNoticeEvent
+	}{
+		{
+			"sub",
+			`@badge-info=subscriber/0;badges=subscriber/0;display-name=ronni;id=db25007f-7a18-43eb-9379-80131e44d633;login=ronni;msg-id=sub;msg-param-cumulative-months=1;msg-param-sub-plan-name=Channel\sSubscription;msg-param-sub-plan=1000;room-id=1337;system-msg=ronni\shas\ssubscribed!;tmi-sent-ts=1507246572675;user-id=1337 :tmi.twitch.tv USERNOTICE #miguelcodetv :Great stream`,
+			&irc.SubEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "db25007f-7a18-43eb-9379-80131e44d633",
+					Type:          "sub",
+					Channel:       "miguelcodetv",
+					UserID:        "1337",
+					Login:         "ronni",
+					DisplayName:   "ronni",
+					SystemMessage: "ronni has subscribed!",
+					Message:       "Great stream",
+					Timestamp:     1507246572675,
+				},
+				Plan:     "1000",
+				PlanName: "Channel Subscription",
+			},
+		},
+		{
+			"resub",
+			`@badge-info=founder/2;badges=moderator/1,founder/0,bits/100;color=;display-name=AttackKopter;emotes=;flags=;id=a1d91e60-ae2b-4730-a2b1-38c23145887d;login=attackkopter;mod=1;msg-id=resub;msg-param-cumulative-months=2;msg-param-months=0;msg-param-should-share-streak=1;msg-param-streak-months=2;msg-param-sub-plan-name=Channel\sSubscription\s(miguelcodetv);msg-param-sub-plan=Prime;msg-param-was-gifted=false;room-id=558843277;subscriber=1;system-msg=AttackKopter\ssubscribed\swith\sTwitch\sPrime.;tmi-sent-ts=1601065308944;user-id=239246205;user-type=mod :tmi.twitch.tv USERNOTICE #miguelcodetv :guess what`,
+			&irc.ResubEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "a1d91e60-ae2b-4730-a2b1-38c23145887d",
+					Type:          "resub",
+					Channel:       "miguelcodetv",
+					UserID:        "239246205",
+					Login:         "attackkopter",
+					DisplayName:   "AttackKopter",
+					SystemMessage: "AttackKopter subscribed with Twitch Prime.",
+					Message:       "guess what",
+					Timestamp:     1601065308944,
+				},
+				Plan:             "Prime",
+				PlanName:         "Channel Subscription (miguelcodetv)",
+				CumulativeMonths: 2,
+				StreakMonths:     2,
+				ShareStreak:      true,
+			},
+		},
+		{
+			"gift sub",
+			`@badges=staff/1,premium/1;display-name=TWW2;id=e9176cd8-5e22-4684-ad40-ce53c2561c5e;login=tww2;msg-id=subgift;msg-param-months=1;msg-param-recipient-display-name=Mr_Woodchuck;msg-param-recipient-id=89614178;msg-param-recipient-user-name=mr_woodchuck;msg-param-sub-plan-name=House\sof\sNyoro~n;msg-param-sub-plan=1000;room-id=19571752;system-msg=TWW2\sgifted\sa\sTier\s1\ssub\sto\sMr_Woodchuck!;tmi-sent-ts=1521159445153;user-id=13405587 :tmi.twitch.tv USERNOTICE #miguelcodetv`,
+			&irc.GiftSubEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "e9176cd8-5e22-4684-ad40-ce53c2561c5e",
+					Type:          "subgift",
+					Channel:       "miguelcodetv",
+					UserID:        "13405587",
+					Login:         "tww2",
+					DisplayName:   "TWW2",
+					SystemMessage: "TWW2 gifted a Tier 1 sub to Mr_Woodchuck!",
+					Timestamp:     1521159445153,
+				},
+				Plan:                 "1000",
+				PlanName:             "House of Nyoro~n",
+				Months:               1,
+				RecipientID:          "89614178",
+				RecipientLogin:       "mr_woodchuck",
+				RecipientDisplayName: "Mr_Woodchuck",
+			},
+		},
+		{
+			"anonymous mystery gift",
+			`@badges=;display-name=AnAnonymousGifter;id=1234;login=ananonymousgifter;msg-id=anonsubmysterygift;msg-param-mass-gift-count=5;msg-param-sub-plan=1000;room-id=558843277;system-msg=An\sanonymous\suser\sis\sgifting\s5\sTier\s1\sSubs!;tmi-sent-ts=1601065308944;user-id=274598607 :tmi.twitch.tv USERNOTICE #miguelcodetv`,
+			&irc.MysteryGiftEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "1234",
+					Type:          "anonsubmysterygift",
+					Channel:       "miguelcodetv",
+					UserID:        "274598607",
+					Login:         "ananonymousgifter",
+					DisplayName:   "AnAnonymousGifter",
+					SystemMessage: "An anonymous user is gifting 5 Tier 1 Subs!",
+					Timestamp:     1601065308944,
+				},
+				Plan:      "1000",
+				Count:     5,
+				Anonymous: true,
+			},
+		},
+		{
+			"raid",
+			`@badge-info=;badges=premium/1;color=#008000;display-name=erikdotdev;emotes=;flags=;id=f1013215-e7e9-4441-830d-95bf7d12459f;login=erikdotdev;mod=0;msg-id=raid;msg-param-displayName=erikdotdev;msg-param-login=erikdotdev;msg-param-profileImageURL=https://static-cdn.jtvnw.net/jtv_user_pictures/2537a5a5-f45d-4cfb-80e2-f6b6b887ee23-profile_image-70x70.png;msg-param-viewerCount=44;room-id=558843277;subscriber=0;system-msg=44\sraiders\sfrom\serikdotdev\shave\sjoined!;tmi-sent-ts=1598300953914;user-id=192497221;user-type= :tmi.twitch.tv USERNOTICE #miguelcodetv`,
+			&irc.RaidEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "f1013215-e7e9-4441-830d-95bf7d12459f",
+					Type:          "raid",
+					Channel:       "miguelcodetv",
+					UserID:        "192497221",
+					Login:         "erikdotdev",
+					DisplayName:   "erikdotdev",
+					SystemMessage: "44 raiders from erikdotdev have joined!",
+					Timestamp:     1598300953914,
+				},
+				ViewerCount:     44,
+				ProfileImageURL: "https://static-cdn.jtvnw.net/jtv_user_pictures/2537a5a5-f45d-4cfb-80e2-f6b6b887ee23-profile_image-70x70.png",
+			},
+		},
+		{
+			"ritual",
+			`@badges=;display-name=SevenTest1;id=37feed0f-b9c7-4c3a-b475-21c6c6d21c3d;login=seventest1;msg-id=ritual;msg-param-ritual-name=new_chatter;room-id=6316121;system-msg=Seventoes\sis\snew\shere!;tmi-sent-ts=1508363903826;user-id=131260580 :tmi.twitch.tv USERNOTICE #miguelcodetv :HeyGuys`,
+			&irc.RitualEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "37feed0f-b9c7-4c3a-b475-21c6c6d21c3d",
+					Type:          "ritual",
+					Channel:       "miguelcodetv",
+					UserID:        "131260580",
+					Login:         "seventest1",
+					DisplayName:   "SevenTest1",
+					SystemMessage: "Seventoes is new here!",
+					Message:       "HeyGuys",
+					Timestamp:     1508363903826,
+				},
+				Ritual: "new_chatter",
+			},
+		},
+		{
+			"bits badge tier",
+			`@badges=;display-name=Cheerer;id=5678;login=cheerer;msg-id=bitsbadgetier;msg-param-threshold=1000;room-id=558843277;system-msg=bits\sbadge\stier\snotification;tmi-sent-ts=1601065308944;user-id=42 :tmi.twitch.tv USERNOTICE #miguelcodetv`,
+			&irc.BitsBadgeTierEvent{
+				UserNotice: irc.UserNotice{
+					ID:            "5678",
+					Type:          "bitsbadgetier",
+					Channel:       "miguelcodetv",
+					UserID:        "42",
+					Login:         "cheerer",
+					DisplayName:   "Cheerer",
+					SystemMessage: "bits badge tier notification",
+					Timestamp:     1601065308944,
+				},
+				Threshold: 1000,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client, chatServerMock := util.CreateMockChatClient(t)
+
+			var buf bytes.Buffer
+			buf.WriteString(test.input)
+			chatServerMock.SetResponse(&buf)
+
+			notices := client.UserNoticeListener()
+
+			err := client.Start()
+			if err != nil {
+				t.Fatalf("failed to start irc client with %s", err)
+			}
+
+			select {
+			case got := <-notices:
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("user notice doesn't match\nwant: %+v\ngot:  %+v", test.want, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for user notice")
+			}
+		})
+	}
+}
+
+func TestUnknownUserNotice(t *testing.T) {
+	client, chatServerMock := util.CreateMockChatClient(t)
+
+	var buf bytes.Buffer
+	buf.WriteString("@display-name=someone;login=someone;msg-id=unknown-notice;user-id=1 :tmi.twitch.tv USERNOTICE #miguelcodetv\n")
+	buf.WriteString("@display-name=ronni;login=ronni;msg-id=sub;msg-param-sub-plan=1000;user-id=1337 :tmi.twitch.tv USERNOTICE #miguelcodetv")
+	chatServerMock.SetResponse(&buf)
+
+	notices := client.UserNoticeListener()
+
+	err := client.Start()
+	if err != nil {
+		t.Fatalf("failed to start irc client with %s", err)
+	}
+
+	select {
+	case got := <-notices:
+		if _, ok := got.(*irc.SubEvent); !ok {
+			t.Errorf("expected unknown notices to be skipped, got %T", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for user notice")
+	}
+}
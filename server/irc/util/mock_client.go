@@ -2,23 +2,31 @@ package util
 
 import (
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/miguel250/streaming-setup/server/irc"
 	"github.com/miguel250/streaming-setup/server/twitch"
 	twitch_util "github.com/miguel250/streaming-setup/server/twitch/util"
-	emote_util "github.com/miguel250/streaming-setup/server/twitchemotes/util"
 )
 
 func CreateMockChatClient(t *testing.T) (*irc.Client, *EchoServer) {
 	ts := MockTwitchChatServer(t)
 	ts.Start()
+	t.Cleanup(ts.Shutdown)
 
+	client := CreateMockChatClientWithConfig(t, func(conf *irc.Config) {
+		conf.URL = ts.addr
+	})
+	return client, ts
+}
+
+// CreateMockChatClientWithConfig creates a client backed by mock Twitch APIs,
+// configure can change the configuration before the client is created.
+func CreateMockChatClientWithConfig(t *testing.T, configure func(conf *irc.Config)) *irc.Client {
 	channeID := "558843277"
-	testEndpoint := fmt.Sprintf("/kraken/users/%s", channeID)
-	api, twitchMockServer := twitch_util.TestCreateClient(t, "user_response", testEndpoint, channeID)
+	api, twitchMockServer := twitch_util.TestCreateClient(t, "user_response", "/helix/users", channeID)
 
 	b, err := ioutil.ReadFile("testdata/channel_badges_response.json")
 
@@ -34,16 +42,19 @@ func CreateMockChatClient(t *testing.T) (*irc.Client, *EchoServer) {
 		t.Fatalf("Failed to parse json file with %s", err)
 	}
 
-	twitchEmotesMockAPI := emote_util.TestCreateClient(t, "emote_response", []string{"303365132"})
-
 	conf := &irc.Config{
-		Auth:         "test_auth_token",
-		URL:          ts.addr,
-		Name:         "test_account",
-		Channel:      "test_channel",
-		TwitchAPI:    api,
-		TwitchEmotes: twitchEmotesMockAPI,
-		Badges:       resp.BadgeSet,
+		Auth:      "test_auth_token",
+		Name:      "test_account",
+		Channel:   "test_channel",
+		TwitchAPI: api,
+		Badges:    resp.BadgeSets(),
+
+		ReconnectMinDelay: 10 * time.Millisecond,
+		ReconnectMaxDelay: 50 * time.Millisecond,
+	}
+
+	if configure != nil {
+		configure(conf)
 	}
 
 	client, err := irc.New(conf)
@@ -54,9 +65,8 @@ func CreateMockChatClient(t *testing.T) (*irc.Client, *EchoServer) {
 
 	t.Cleanup(func() {
 		client.Close()
-		ts.Shutdown()
 		twitchMockServer.Close()
 	})
 
-	return client, ts
+	return client
 }
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// mockSendDelay is how long MockSender takes to send a message, like the
+// send queue it drops the message when ctx is done before then.
+const mockSendDelay = 10 * time.Millisecond
+
+// MockSender records the chat messages sent through it, it implements
+// irc.MessageSender. Err is returned instead of sending when it is set.
+type MockSender struct {
+	sync.Mutex
+	Err      error
+	messages []string
+}
+
+func (s *MockSender) SendMessageToWait(ctx context.Context, channel, msg string) error {
+	if s.Err != nil {
+		return s.Err
+	}
+
+	timer := time.NewTimer(mockSendDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.messages = append(s.messages, channel+": "+msg)
+	return nil
+}
+
+// Messages returns the messages sent so far as "channel: message".
+func (s *MockSender) Messages() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string{}, s.messages...)
+}
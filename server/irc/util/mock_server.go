@@ -1,9 +1,12 @@
 package util
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"net"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -15,6 +18,11 @@ type EchoServer struct {
 	listener       net.Listener
 	t              *testing.T
 	addr           string
+	connMutex      sync.Mutex
+	conns          []net.Conn
+	received       []string
+	rejected       map[string]bool
+	rejectLogin    bool
 }
 
 func (e *EchoServer) Start() {
@@ -35,16 +43,26 @@ func (e *EchoServer) Start() {
 					continue
 				}
 
+				e.connMutex.Lock()
+				e.conns = append(e.conns, conn)
+				e.connMutex.Unlock()
+
 				go func(c net.Conn) {
 					e.Lock()
 					defer e.Unlock()
+					recorder := e.recorder()
 					if e.serverResponse == nil {
-						io.Copy(c, c)
+						e.echo(c, recorder)
+						recorder.Close()
 						c.Close()
 						return
 					}
 
 					io.Copy(c, e.serverResponse)
+					go func() {
+						io.Copy(recorder, c)
+						recorder.Close()
+					}()
 				}(conn)
 			}
 		}
@@ -59,6 +77,116 @@ func (e *EchoServer) SetResponse(msg *bytes.Buffer) {
 	e.serverResponse = msg
 }
 
+// CloseConnections drops every connection accepted so far, simulating the
+// chat server going away in the middle of a stream.
+func (e *EchoServer) CloseConnections() {
+	e.connMutex.Lock()
+	defer e.connMutex.Unlock()
+
+	for _, conn := range e.conns {
+		conn.Close()
+	}
+	e.conns = nil
+}
+
+// Received returns every line the server got from its clients.
+func (e *EchoServer) Received() []string {
+	e.connMutex.Lock()
+	defer e.connMutex.Unlock()
+
+	lines := make([]string, len(e.received))
+	copy(lines, e.received)
+	return lines
+}
+
+func (e *EchoServer) recorder() io.WriteCloser {
+	r, w := io.Pipe()
+
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			e.connMutex.Lock()
+			e.received = append(e.received, strings.TrimRight(scanner.Text(), "\r"))
+			e.connMutex.Unlock()
+		}
+	}()
+	return w
+}
+
+// echo sends every line back to the client except the login handshake,
+// which gets the same replies the Twitch chat server sends.
+func (e *EchoServer) echo(conn io.ReadWriter, recorder io.Writer) {
+	reader := bufio.NewReader(io.TeeReader(conn, recorder))
+	name := ""
+
+	for {
+		line, err := reader.ReadString('\n')
+		command := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(command, "CAP REQ :"):
+			capability := strings.TrimPrefix(command, "CAP REQ :")
+			reply := "ACK"
+			if e.isRejected(capability) {
+				reply = "NAK"
+			}
+			fmt.Fprintf(conn, ":tmi.twitch.tv CAP * %s :%s\r\n", reply, capability)
+		case strings.HasPrefix(command, "PASS "):
+		case strings.HasPrefix(command, "NICK "):
+			name = strings.TrimPrefix(command, "NICK ")
+			if e.isLoginRejected() {
+				fmt.Fprint(conn, ":tmi.twitch.tv NOTICE * :Login authentication failed\r\n")
+				continue
+			}
+			fmt.Fprintf(conn, ":tmi.twitch.tv 001 %s :Welcome, GLHF!\r\n", name)
+		case strings.HasPrefix(command, "JOIN "):
+			fmt.Fprintf(conn, ":%s!%s@%s.tmi.twitch.tv %s\r\n", name, name, name, command)
+		case len(line) > 0:
+			conn.Write([]byte(line))
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// RejectLogin makes the server answer NICK with the NOTICE Twitch sends for
+// an invalid oauth token.
+func (e *EchoServer) RejectLogin() {
+	e.connMutex.Lock()
+	defer e.connMutex.Unlock()
+	e.rejectLogin = true
+}
+
+func (e *EchoServer) isLoginRejected() bool {
+	e.connMutex.Lock()
+	defer e.connMutex.Unlock()
+	return e.rejectLogin
+}
+
+// RejectCapability makes the server answer CAP REQ for capability with NAK.
+func (e *EchoServer) RejectCapability(capability string) {
+	e.connMutex.Lock()
+	defer e.connMutex.Unlock()
+
+	if e.rejected == nil {
+		e.rejected = make(map[string]bool)
+	}
+	e.rejected[capability] = true
+}
+
+func (e *EchoServer) isRejected(capability string) bool {
+	e.connMutex.Lock()
+	defer e.connMutex.Unlock()
+	return e.rejected[capability]
+}
+
+// Addr returns the address the server is listening on.
+func (e *EchoServer) Addr() string {
+	return e.addr
+}
+
 func (e *EchoServer) Shutdown() {
 	close(e.shutdown)
 }
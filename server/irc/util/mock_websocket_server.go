@@ -0,0 +1,40 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// MockTwitchChatWebSocketServer echoes every frame back like the Twitch IRC
+// over WebSocket endpoint would for our own messages. It returns the ws://
+// URL for the server.
+func MockTwitchChatWebSocketServer(t *testing.T) string {
+	upgrader := websocket.Upgrader{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade connection with %s", err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if err := conn.WriteMessage(messageType, msg); err != nil {
+				return
+			}
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+	return "ws://" + strings.TrimPrefix(ts.URL, "http://")
+}
@@ -0,0 +1,157 @@
+package live
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// State is what the overlays and chat know about the stream. StartedAt is
+// nil while the stream is offline.
+type State struct {
+	Online      bool       `json:"online"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	ViewerCount int        `json:"viewer_count"`
+	GameID      string     `json:"game_id"`
+	GameName    string     `json:"game_name"`
+	Title       string     `json:"title"`
+}
+
+// pollGrace is how long Helix can disagree with EventSub after the stream
+// goes online or offline, /streams takes a while to catch up.
+const pollGrace = 3 * time.Minute
+
+// Tracker keeps the state of the stream and tells the overlays when it
+// goes online, offline or the viewer count changes.
+type Tracker struct {
+	sync.RWMutex
+	event *stream.Event
+	state State
+	now   func() time.Time
+	// notifiedAt is when EventSub last changed the state
+	notifiedAt time.Time
+}
+
+// Update sets the state from a Helix stream, s is nil when the channel is
+// offline. Polls that disagree with EventSub within pollGrace only update
+// the state of an online stream.
+func (t *Tracker) Update(s *twitch.Stream) {
+	t.Lock()
+	wasOnline := t.state.Online
+	stale := t.now().Sub(t.notifiedAt) < pollGrace
+
+	if stale && (s == nil) == wasOnline {
+		t.Unlock()
+		return
+	}
+
+	if s == nil {
+		t.Unlock()
+		t.offline()
+		return
+	}
+
+	viewersChanged := t.state.ViewerCount != s.ViewerCount
+
+	startedAt := s.StartedAt
+	t.state = State{
+		Online:      true,
+		StartedAt:   &startedAt,
+		ViewerCount: s.ViewerCount,
+		GameID:      s.GameID,
+		GameName:    s.GameName,
+		Title:       s.Title,
+	}
+	state := t.state
+	t.Unlock()
+
+	switch {
+	case !wasOnline:
+		t.send(stream.StreamOnline, state)
+	case viewersChanged:
+		t.send(stream.ViewerCount, state)
+	}
+}
+
+// Online marks the stream as live. EventSub only sends the start time, the
+// rest of the state is filled by the next Update.
+func (t *Tracker) Online(startedAt time.Time) {
+	t.Lock()
+	if t.state.Online {
+		t.Unlock()
+		return
+	}
+
+	t.state.Online = true
+	t.state.StartedAt = &startedAt
+	t.state.ViewerCount = 0
+	t.notifiedAt = t.now()
+	state := t.state
+	t.Unlock()
+
+	t.send(stream.StreamOnline, state)
+}
+
+// Offline marks the stream as ended, the title and category are kept.
+func (t *Tracker) Offline() {
+	t.Lock()
+	t.notifiedAt = t.now()
+	t.Unlock()
+
+	t.offline()
+}
+
+func (t *Tracker) offline() {
+	t.Lock()
+	if !t.state.Online {
+		t.Unlock()
+		return
+	}
+
+	t.state.Online = false
+	t.state.StartedAt = nil
+	t.state.ViewerCount = 0
+	state := t.state
+	t.Unlock()
+
+	t.send(stream.StreamOffline, state)
+}
+
+// State returns a copy of the current state.
+func (t *Tracker) State() State {
+	t.RLock()
+	defer t.RUnlock()
+	return t.state
+}
+
+// Uptime returns how long the stream has been live, false when it is
+// offline.
+func (t *Tracker) Uptime() (time.Duration, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	if !t.state.Online || t.state.StartedAt == nil {
+		return 0, false
+	}
+	return t.now().Sub(*t.state.StartedAt), true
+}
+
+func (t *Tracker) send(eventType stream.EventType, state State) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("failed to encode %s with %s", stream.EventTypeToString[eventType], err)
+		return
+	}
+	t.event.Send(eventType, string(b))
+}
+
+func New(event *stream.Event) *Tracker {
+	return &Tracker{
+		event: event,
+		now:   time.Now,
+	}
+}
@@ -0,0 +1,206 @@
+package live
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+var startedAt = time.Date(2021, 3, 10, 15, 4, 21, 0, time.UTC)
+
+func liveStream(viewers int) *twitch.Stream {
+	return &twitch.Stream{
+		ID:          "40952121085",
+		UserID:      "141981764",
+		GameID:      "509670",
+		GameName:    "Science & Technology",
+		Title:       "Building a chat bot in Go",
+		ViewerCount: viewers,
+		StartedAt:   startedAt,
+	}
+}
+
+// collect returns the events sent while running f.
+func collect(t *testing.T, event *stream.Event, f func()) []stream.Message {
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+
+	messages := make([]stream.Message, 0)
+	for {
+		select {
+		case msg := <-event.Message:
+			messages = append(messages, msg)
+		case <-done:
+			return messages
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for tracker")
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		updates []*twitch.Stream
+		want    []stream.EventType
+		online  bool
+	}{
+		{
+			name:    "offline",
+			updates: []*twitch.Stream{nil, nil},
+			want:    []stream.EventType{},
+		},
+		{
+			name:    "goes online",
+			updates: []*twitch.Stream{nil, liveStream(10)},
+			want:    []stream.EventType{stream.StreamOnline},
+			online:  true,
+		},
+		{
+			name:    "viewer count",
+			updates: []*twitch.Stream{liveStream(10), liveStream(10), liveStream(12)},
+			want:    []stream.EventType{stream.StreamOnline, stream.ViewerCount},
+			online:  true,
+		},
+		{
+			name:    "goes offline",
+			updates: []*twitch.Stream{liveStream(10), nil, nil},
+			want:    []stream.EventType{stream.StreamOnline, stream.StreamOffline},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			event := stream.New()
+			tracker := New(event)
+
+			messages := collect(t, event, func() {
+				for _, s := range test.updates {
+					tracker.Update(s)
+				}
+			})
+
+			if len(messages) != len(test.want) {
+				t.Fatalf("events don't match want: %d, got: %+v", len(test.want), messages)
+			}
+
+			for i, msg := range messages {
+				if msg.Type != test.want[i] {
+					t.Errorf("event doesn't match want: %s, got: %s", stream.EventTypeToString[test.want[i]], stream.EventTypeToString[msg.Type])
+				}
+			}
+
+			if tracker.State().Online != test.online {
+				t.Errorf("online doesn't match want: %t, got: %+v", test.online, tracker.State())
+			}
+		})
+	}
+}
+
+func TestUpdatePayload(t *testing.T) {
+	event := stream.New()
+	tracker := New(event)
+
+	messages := collect(t, event, func() {
+		tracker.Update(liveStream(42))
+	})
+
+	if len(messages) != 1 {
+		t.Fatalf("expected one event got: %+v", messages)
+	}
+
+	got := &State{}
+	if err := json.Unmarshal([]byte(messages[0].Text), got); err != nil {
+		t.Fatalf("failed to parse event with %s", err)
+	}
+
+	if !got.Online || got.ViewerCount != 42 || got.GameName != "Science & Technology" || got.StartedAt == nil || !got.StartedAt.Equal(startedAt) {
+		t.Errorf("state doesn't match got: %s", messages[0].Text)
+	}
+}
+
+func TestOnlineOffline(t *testing.T) {
+	event := stream.New()
+	tracker := New(event)
+	tracker.now = func() time.Time {
+		return startedAt.Add(90 * time.Minute)
+	}
+
+	if _, ok := tracker.Uptime(); ok {
+		t.Error("expected no uptime while offline")
+	}
+
+	messages := collect(t, event, func() {
+		tracker.Online(startedAt)
+		// the poll after EventSub shouldn't announce the stream again
+		tracker.Update(liveStream(0))
+	})
+
+	if len(messages) != 1 || messages[0].Type != stream.StreamOnline {
+		t.Fatalf("expected stream_online got: %+v", messages)
+	}
+
+	uptime, ok := tracker.Uptime()
+	if !ok || uptime != 90*time.Minute {
+		t.Errorf("uptime doesn't match got: %s, %t", uptime, ok)
+	}
+
+	messages = collect(t, event, func() {
+		tracker.Offline()
+		tracker.Offline()
+	})
+
+	if len(messages) != 1 || messages[0].Type != stream.StreamOffline {
+		t.Fatalf("expected stream_offline got: %+v", messages)
+	}
+
+	state := tracker.State()
+	if state.StartedAt != nil || state.Title != "Building a chat bot in Go" {
+		t.Errorf("offline state doesn't match got: %+v", state)
+	}
+}
+
+func TestUpdateLagsBehindEventSub(t *testing.T) {
+	event := stream.New()
+	tracker := New(event)
+
+	now := startedAt
+	tracker.now = func() time.Time {
+		return now
+	}
+
+	messages := collect(t, event, func() {
+		tracker.Online(startedAt)
+		// Helix doesn't list the stream yet
+		tracker.Update(nil)
+		tracker.Update(liveStream(5))
+	})
+
+	if len(messages) != 2 || messages[0].Type != stream.StreamOnline || messages[1].Type != stream.ViewerCount {
+		t.Fatalf("expected stream_online and viewer_count got: %+v", messages)
+	}
+
+	messages = collect(t, event, func() {
+		tracker.Offline()
+		// Helix still lists the stream
+		tracker.Update(liveStream(5))
+	})
+
+	if len(messages) != 1 || messages[0].Type != stream.StreamOffline {
+		t.Fatalf("expected stream_offline got: %+v", messages)
+	}
+
+	// a poll after the grace period is trusted again
+	now = now.Add(pollGrace)
+	messages = collect(t, event, func() {
+		tracker.Update(liveStream(5))
+	})
+
+	if len(messages) != 1 || messages[0].Type != stream.StreamOnline {
+		t.Fatalf("expected stream_online got: %+v", messages)
+	}
+}
@@ -0,0 +1,72 @@
+package protection
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxFollows = 20
+	DefaultWindow     = time.Minute
+)
+
+// follows is a group of follows that arrived together, the refresher can
+// find many in the same refresh.
+type follows struct {
+	at    time.Time
+	count int
+}
+
+// Detector finds follow rates above what the channel normally gets, it
+// counts the follows inside a sliding window.
+type Detector struct {
+	sync.Mutex
+	max     int
+	window  time.Duration
+	follows []follows
+	total   int
+	now     func() time.Time
+}
+
+// NewDetector creates a detector that fires when more than max follows
+// arrive within window.
+func NewDetector(max int, window time.Duration) *Detector {
+	if max <= 0 {
+		max = DefaultMaxFollows
+	}
+
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	return &Detector{
+		max:    max,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// Add records count follows and reports if the rate is abnormal, with how
+// many follows are in the window.
+func (d *Detector) Add(count int) (bool, int) {
+	d.Lock()
+	defer d.Unlock()
+
+	now := d.now()
+	d.expire(now)
+
+	if count > 0 {
+		d.follows = append(d.follows, follows{at: now, count: count})
+		d.total += count
+	}
+	return d.total > d.max, d.total
+}
+
+// expire drops the follows that left the window.
+func (d *Detector) expire(now time.Time) {
+	i := 0
+	for ; i < len(d.follows) && now.Sub(d.follows[i].at) > d.window; i++ {
+		d.total -= d.follows[i].count
+	}
+	d.follows = d.follows[i:]
+}
@@ -0,0 +1,75 @@
+package protection
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetector(t *testing.T) {
+	now := time.Unix(1601065308, 0)
+	detector := NewDetector(5, 10*time.Second)
+	detector.now = func() time.Time {
+		return now
+	}
+
+	for _, step := range []struct {
+		name         string
+		follows      int
+		after        time.Duration
+		wantAbnormal bool
+		wantTotal    int
+	}{
+		{"first follow", 1, 0, false, 1},
+		{"normal rate", 1, 4 * time.Second, false, 2},
+		{"follows leave the window", 1, 9 * time.Second, false, 2},
+		{"refresh with a few follows", 3, time.Second, false, 5},
+		{"one more than max", 1, 0, true, 6},
+		{"wave keeps going", 10, time.Second, true, 15},
+		{"wave leaves the window", 1, 11 * time.Second, false, 1},
+	} {
+		now = now.Add(step.after)
+		abnormal, total := detector.Add(step.follows)
+
+		if abnormal != step.wantAbnormal {
+			t.Fatalf("%s: abnormal doesn't match want: %t, got: %t", step.name, step.wantAbnormal, abnormal)
+		}
+
+		if total != step.wantTotal {
+			t.Errorf("%s: total doesn't match want: %d, got: %d", step.name, step.wantTotal, total)
+		}
+	}
+}
+
+func TestDetectorSyntheticStreams(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// interval between follows
+		interval     time.Duration
+		follows      int
+		wantAbnormal int
+	}{
+		{"a follow every 5 seconds", 5 * time.Second, 500, 0},
+		{"a follow every 4 seconds", 4 * time.Second, 500, 0},
+		{"follow bot every 100ms", 100 * time.Millisecond, 500, 500 - DefaultMaxFollows},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Unix(1601065308, 0)
+			detector := NewDetector(0, 0)
+			detector.now = func() time.Time {
+				return now
+			}
+
+			abnormal := 0
+			for i := 0; i < test.follows; i++ {
+				now = now.Add(test.interval)
+				if ok, _ := detector.Add(1); ok {
+					abnormal++
+				}
+			}
+
+			if abnormal != test.wantAbnormal {
+				t.Errorf("abnormal follows don't match want: %d, got: %d", test.wantAbnormal, abnormal)
+			}
+		})
+	}
+}
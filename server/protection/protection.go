@@ -0,0 +1,265 @@
+package protection
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+const (
+	DefaultCooldown             = 2 * time.Minute
+	DefaultFollowersOnlyMinutes = 10
+)
+
+// Config has the thresholds for follow bot waves, the protection is off
+// when MaxFollows is zero.
+type Config struct {
+	// MaxFollows within WindowSeconds starts a wave
+	MaxFollows    int `json:"max_follows"`
+	WindowSeconds int `json:"window_seconds"`
+	// CooldownSeconds without an abnormal rate ends the wave
+	CooldownSeconds int `json:"cooldown_seconds"`
+	// BatchAlerts sends one alert with how many followers came during the
+	// wave once it ends, otherwise their alerts are dropped
+	BatchAlerts bool `json:"batch_alerts"`
+	// FollowersOnly only lets accounts that followed FollowersOnlyMinutes
+	// ago chat during the wave
+	FollowersOnly        bool `json:"followers_only"`
+	FollowersOnlyMinutes int  `json:"followers_only_minutes"`
+	EmoteOnly            bool `json:"emote_only"`
+	// NotifyModerators tells chat when a wave starts and ends
+	NotifyModerators bool `json:"notify_moderators"`
+}
+
+// Enabled reports if follows should go through the guard.
+func (c *Config) Enabled() bool {
+	return c.MaxFollows > 0
+}
+
+// FollowHandler decides whether to show the alert for a number of new
+// followers, Guard implements it.
+type FollowHandler interface {
+	Follow(alert string, follows int)
+}
+
+// ChatSettings changes the chat modes, *twitch.Channel implements it.
+// Twitch chat doesn't run /followers or /emoteonly anymore.
+type ChatSettings interface {
+	UpdateChatSettings(ctx context.Context, channelID, moderatorID string, update *twitch.ChatSettingsUpdate) error
+}
+
+// Guard sits between the follow sources and the overlays. Alerts are sent
+// as they come until the detector finds a wave, then they are held until
+// the rate is normal again for the cooldown.
+type Guard struct {
+	sync.Mutex
+	conf     *Config
+	detector *Detector
+	cooldown time.Duration
+	event    *stream.Event
+	chat     irc.MessageSender
+	channel  string
+	settings ChatSettings
+	// channelID is the broadcaster and the moderator changing the
+	// settings, the user token is the broadcaster's
+	channelID string
+	now       func() time.Time
+	// ctx is cancelled by Close, it drops the messages still waiting in
+	// the chat send queue
+	ctx    context.Context
+	cancel context.CancelFunc
+	// chatMutex keeps the chat modes and messages of the start and end of
+	// a wave in order
+	chatMutex sync.Mutex
+
+	active       bool
+	suppressed   int
+	lastAbnormal time.Time
+	timer        *time.Timer
+}
+
+// Follow sends the alert for follows followers, the refresher summarizes
+// bursts in one alert.
+func (g *Guard) Follow(alert string, follows int) {
+	abnormal, total := g.detector.Add(follows)
+
+	g.Lock()
+	if abnormal {
+		g.lastAbnormal = g.now()
+	}
+
+	starting := abnormal && !g.active
+	if starting {
+		g.active = true
+		g.timer = time.AfterFunc(g.cooldown, g.check)
+	}
+
+	if g.active {
+		g.suppressed += follows
+		g.Unlock()
+
+		if starting {
+			log.Printf("Follow bot wave detected with %d follows, holding follow alerts", total)
+			go g.start(total)
+		}
+		return
+	}
+	g.Unlock()
+
+	g.event.Send(stream.NewFollower, alert)
+}
+
+// Close stops waiting for the end of a wave and drops the chat messages
+// that weren't sent yet.
+func (g *Guard) Close() {
+	g.cancel()
+
+	g.Lock()
+	defer g.Unlock()
+
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+}
+
+// check ends the wave once there wasn't an abnormal rate for the cooldown.
+func (g *Guard) check() {
+	g.Lock()
+	if !g.active {
+		g.Unlock()
+		return
+	}
+
+	if wait := g.lastAbnormal.Add(g.cooldown).Sub(g.now()); wait > 0 {
+		g.timer = time.AfterFunc(wait, g.check)
+		g.Unlock()
+		return
+	}
+
+	g.active = false
+	suppressed := g.suppressed
+	g.suppressed = 0
+	g.Unlock()
+
+	log.Printf("Follow bot wave ended after %d more follows", suppressed)
+
+	if g.conf.BatchAlerts && suppressed > 0 {
+		g.event.Send(stream.NewFollower, fmt.Sprintf("%d new followers", suppressed))
+	}
+	g.end(suppressed)
+}
+
+// start locks chat and tells the moderators about the wave.
+func (g *Guard) start(total int) {
+	g.chatMutex.Lock()
+	defer g.chatMutex.Unlock()
+
+	modes := g.setChatModes(true)
+
+	if g.conf.NotifyModerators {
+		notice := fmt.Sprintf("Moderators: %d follows in %s looks like a follow bot, follow alerts are paused", total, g.detector.window)
+		if len(modes) > 0 {
+			notice += fmt.Sprintf(" and chat is %s", strings.Join(modes, " and "))
+		}
+		g.say(notice)
+	}
+}
+
+// end unlocks chat and tells the moderators the wave is over.
+func (g *Guard) end(suppressed int) {
+	g.chatMutex.Lock()
+	defer g.chatMutex.Unlock()
+
+	g.setChatModes(false)
+
+	if g.conf.NotifyModerators {
+		g.say(fmt.Sprintf("Moderators: the follow bot wave is over after %d more follows", suppressed))
+	}
+}
+
+// setChatModes turns the configured chat modes on or off, it returns the
+// modes that changed.
+func (g *Guard) setChatModes(on bool) []string {
+	update := &twitch.ChatSettingsUpdate{}
+	modes := make([]string, 0)
+
+	if g.conf.FollowersOnly {
+		update.FollowerMode = &on
+		if on {
+			minutes := g.followersOnlyMinutes()
+			update.FollowerModeDuration = &minutes
+		}
+		modes = append(modes, "followers-only")
+	}
+
+	if g.conf.EmoteOnly {
+		update.EmoteMode = &on
+		modes = append(modes, "emote-only")
+	}
+
+	if len(modes) == 0 || g.settings == nil {
+		return nil
+	}
+
+	if err := g.settings.UpdateChatSettings(g.ctx, g.channelID, g.channelID, update); err != nil {
+		log.Printf("Failed to change %s chat with %s", strings.Join(modes, " and "), err)
+		return nil
+	}
+	return modes
+}
+
+func (g *Guard) followersOnlyMinutes() int {
+	if g.conf.FollowersOnlyMinutes <= 0 {
+		return DefaultFollowersOnlyMinutes
+	}
+	return g.conf.FollowersOnlyMinutes
+}
+
+func (g *Guard) say(msg string) {
+	if g.chat == nil {
+		return
+	}
+
+	if err := g.chat.SendMessageToWait(g.ctx, g.channel, msg); err != nil {
+		log.Printf("Failed to send %q to chat with %s", msg, err)
+	}
+}
+
+// New creates the guard, chat messages are sent to channel and the chat
+// modes of channelID are changed through settings. chat and settings can be
+// nil to only hold the alerts.
+func New(conf *Config, event *stream.Event, chat irc.MessageSender, channel string, settings ChatSettings, channelID string) *Guard {
+	detector := NewDetector(conf.MaxFollows, time.Duration(conf.WindowSeconds)*time.Second)
+
+	cooldown := time.Duration(conf.CooldownSeconds) * time.Second
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+
+	// the wave would start again with its follows still in the window
+	if cooldown < detector.window {
+		cooldown = detector.window
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Guard{
+		conf:      conf,
+		detector:  detector,
+		cooldown:  cooldown,
+		event:     event,
+		chat:      chat,
+		channel:   channel,
+		settings:  settings,
+		channelID: channelID,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
@@ -0,0 +1,197 @@
+package protection
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc/util"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// alerts collects the follower alerts sent to the overlays.
+func alerts(event *stream.Event) func() []string {
+	var (
+		mu   sync.Mutex
+		list []string
+	)
+
+	go func() {
+		for msg := range event.Message {
+			mu.Lock()
+			list = append(list, msg.Text)
+			mu.Unlock()
+		}
+	}()
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, list...)
+	}
+}
+
+// settingsRecorder records the chat settings updates as JSON.
+type settingsRecorder struct {
+	sync.Mutex
+	updates []string
+}
+
+func (r *settingsRecorder) UpdateChatSettings(ctx context.Context, channelID, moderatorID string, update *twitch.ChatSettingsUpdate) error {
+	b, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.updates = append(r.updates, channelID+" "+moderatorID+": "+string(b))
+	return nil
+}
+
+func (r *settingsRecorder) Updates() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string{}, r.updates...)
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the guard")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		conf         *Config
+		wantAlerts   []string
+		wantChat     []string
+		wantSettings []string
+	}{
+		{
+			name: "batch alerts and lock chat",
+			conf: &Config{
+				MaxFollows:       3,
+				BatchAlerts:      true,
+				FollowersOnly:    true,
+				EmoteOnly:        true,
+				NotifyModerators: true,
+			},
+			wantAlerts: []string{"a", "b", "c", "11 new followers"},
+			wantChat: []string{
+				"miguelcodetv: Moderators: 13 follows in 40ms looks like a follow bot, follow alerts are paused and chat is followers-only and emote-only",
+				"miguelcodetv: Moderators: the follow bot wave is over after 11 more follows",
+			},
+			wantSettings: []string{
+				`558843277 558843277: {"emote_mode":true,"follower_mode":true,"follower_mode_duration":10}`,
+				`558843277 558843277: {"emote_mode":false,"follower_mode":false}`,
+			},
+		},
+		{
+			name:       "drop alerts",
+			conf:       &Config{MaxFollows: 3, NotifyModerators: true},
+			wantAlerts: []string{"a", "b", "c"},
+			wantChat: []string{
+				"miguelcodetv: Moderators: 13 follows in 40ms looks like a follow bot, follow alerts are paused",
+				"miguelcodetv: Moderators: the follow bot wave is over after 11 more follows",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			event := stream.New()
+			got := alerts(event)
+			chat := &util.MockSender{}
+			settings := &settingsRecorder{}
+
+			guard := New(test.conf, event, chat, "miguelcodetv", settings, "558843277")
+			guard.detector.window = 40 * time.Millisecond
+			guard.cooldown = 50 * time.Millisecond
+			defer guard.Close()
+
+			guard.Follow("a", 1)
+			guard.Follow("b", 1)
+			guard.Follow("c", 1)
+			guard.Follow("d and 9 others", 10)
+			guard.Follow("e", 1)
+
+			waitFor(t, func() bool {
+				return len(chat.Messages()) == len(test.wantChat)
+			})
+
+			// the batched alert is sent before the chat messages
+			if strings.Join(got(), "|") != strings.Join(test.wantAlerts, "|") {
+				t.Errorf("alerts don't match\nwant: %v\n got: %v", test.wantAlerts, got())
+			}
+
+			if strings.Join(chat.Messages(), "|") != strings.Join(test.wantChat, "|") {
+				t.Errorf("chat doesn't match\nwant: %v\n got: %v", test.wantChat, chat.Messages())
+			}
+
+			if strings.Join(settings.Updates(), "|") != strings.Join(test.wantSettings, "|") {
+				t.Errorf("chat settings don't match\nwant: %v\n got: %v", test.wantSettings, settings.Updates())
+			}
+
+			// alerts flow again after the wave
+			guard.Follow("f", 1)
+			waitFor(t, func() bool {
+				list := got()
+				return len(list) > 0 && list[len(list)-1] == "f"
+			})
+		})
+	}
+}
+
+func TestGuardWaveWaitsForCooldown(t *testing.T) {
+	event := stream.New()
+	got := alerts(event)
+
+	guard := New(&Config{MaxFollows: 1, BatchAlerts: true}, event, nil, "", nil, "")
+	guard.detector.window = 100 * time.Millisecond
+	guard.cooldown = 100 * time.Millisecond
+	defer guard.Close()
+
+	guard.Follow("a", 1)
+	guard.Follow("b", 1)
+
+	// the wave keeps going, so it doesn't end 100ms after it started
+	time.Sleep(60 * time.Millisecond)
+	guard.Follow("c", 1)
+	time.Sleep(60 * time.Millisecond)
+
+	if list := got(); len(list) != 1 {
+		t.Fatalf("the wave shouldn't be over, got: %v", list)
+	}
+
+	waitFor(t, func() bool {
+		return len(got()) == 2
+	})
+
+	if list := got(); list[1] != "2 new followers" {
+		t.Errorf("batched alert doesn't match want: 2 new followers, got: %s", list[1])
+	}
+}
+
+func TestGuardCloseDropsChat(t *testing.T) {
+	event := stream.New()
+	alerts(event)
+	chat := &util.MockSender{}
+
+	guard := New(&Config{MaxFollows: 1, NotifyModerators: true}, event, chat, "miguelcodetv", nil, "")
+	guard.Follow("a", 1)
+	guard.Follow("b", 1)
+	guard.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if messages := chat.Messages(); len(messages) != 0 {
+		t.Errorf("messages should be dropped after close, got: %v", messages)
+	}
+}
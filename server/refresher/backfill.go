@@ -0,0 +1,256 @@
+package refresher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+const (
+	// MaxBackfill is how many of the newest followers and subscribers are
+	// compared with the ones already announced on every refresh.
+	MaxBackfill = 100
+	// MaxAlerts is how many alerts a refresh sends, the last one
+	// summarizes the rest when there are more.
+	MaxAlerts = 5
+)
+
+// backfillState is what the last refresh saw. LastSeen is the newest
+// follow date, subscriptions don't have one, and IDs are the users in the
+// refresh. Announced are users chat or EventSub already alerted for that
+// Twitch didn't list yet.
+type backfillState struct {
+	LastSeen  time.Time `json:"last_seen,omitempty"`
+	IDs       []string  `json:"ids"`
+	Announced []string  `json:"announced,omitempty"`
+}
+
+// announce records users that were alerted for outside the refresher.
+func (s *backfillState) announce(ids []string) {
+	known := s.known()
+	for _, id := range ids {
+		if !known[id] {
+			known[id] = true
+			s.Announced = append(s.Announced, id)
+		}
+	}
+}
+
+func (s *backfillState) known() map[string]bool {
+	known := make(map[string]bool, len(s.IDs)+len(s.Announced))
+	for _, id := range s.IDs {
+		known[id] = true
+	}
+
+	for _, id := range s.Announced {
+		known[id] = true
+	}
+	return known
+}
+
+// backfillEntry is a follower or subscriber, at is zero for subscribers.
+type backfillEntry struct {
+	user *twitch.User
+	at   time.Time
+}
+
+// unseen returns the users from entries that the state doesn't have,
+// oldest first. Followers are newest first, so the walk stops at the first
+// one older than LastSeen or, without LastSeen, at the first known user.
+// Subscriptions aren't sorted, every known subscriber is skipped instead.
+func (s *backfillState) unseen(entries []*backfillEntry) []*twitch.User {
+	known := s.known()
+
+	users := make([]*twitch.User, 0)
+	for _, e := range entries {
+		if !e.at.IsZero() {
+			if !s.LastSeen.IsZero() && e.at.Before(s.LastSeen) {
+				break
+			}
+
+			if s.LastSeen.IsZero() && known[e.user.ID] {
+				break
+			}
+		}
+
+		// a follow in the same second as LastSeen, a returning follower or
+		// a subscriber listed before
+		if known[e.user.ID] {
+			continue
+		}
+		users = append(users, e.user)
+	}
+
+	for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+		users[i], users[j] = users[j], users[i]
+	}
+	return users
+}
+
+// update replaces the state with entries, announced users are kept until
+// Twitch lists them.
+func (s *backfillState) update(entries []*backfillEntry) {
+	listed := make(map[string]bool, len(entries))
+	s.IDs = make([]string, 0, len(entries))
+	for _, e := range entries {
+		listed[e.user.ID] = true
+		s.IDs = append(s.IDs, e.user.ID)
+	}
+
+	announced := make([]string, 0, len(s.Announced))
+	for _, id := range s.Announced {
+		if !listed[id] {
+			announced = append(announced, id)
+		}
+	}
+
+	// users that never show up, like a subscription that ended right away
+	if len(announced) > MaxBackfill {
+		announced = announced[len(announced)-MaxBackfill:]
+	}
+	s.Announced = announced
+
+	if len(entries) > 0 && !entries[0].at.IsZero() {
+		s.LastSeen = entries[0].at
+	}
+}
+
+// alert is what the overlay shows for follows users.
+type alert struct {
+	text    string
+	follows int
+}
+
+// summarize returns the alerts for users, oldest first. Bursts bigger than
+// MaxAlerts end with the newest user and how many others there were.
+func summarize(users []*twitch.User) []*alert {
+	alerts := make([]*alert, 0, MaxAlerts)
+
+	if len(users) <= MaxAlerts {
+		for _, user := range users {
+			alerts = append(alerts, &alert{user.DisplayName, 1})
+		}
+		return alerts
+	}
+
+	for _, user := range users[:MaxAlerts-1] {
+		alerts = append(alerts, &alert{user.DisplayName, 1})
+	}
+
+	rest := users[MaxAlerts-1:]
+	newest := rest[len(rest)-1]
+	return append(alerts, &alert{fmt.Sprintf("%s and %d others", newest.DisplayName, len(rest)-1), len(rest)})
+}
+
+func (w *Worker) announce(eventType stream.EventType, users []*twitch.User) {
+	w.Lock()
+	follows := w.follows
+	w.Unlock()
+
+	for _, a := range summarize(users) {
+		log.Printf("Alert %s: %s\n", stream.EventTypeToString[eventType], a.text)
+
+		if eventType == stream.NewFollower && follows != nil {
+			follows.Follow(a.text, a.follows)
+			continue
+		}
+		w.event.Send(eventType, a.text)
+	}
+}
+
+// newFollowers returns the followers since the last refresh, oldest first.
+// known reports if there was a last refresh to compare with.
+func (w *Worker) newFollowers() (followers []*twitch.User, known bool, err error) {
+	it := w.client.Channel.FollowersIter(context.Background(), w.conf.Twitch.ChannelID)
+	page, err := it.Collect(MaxBackfill)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get current followers with %w", err)
+	}
+	if len(page) == 0 {
+		return nil, false, nil
+	}
+
+	entries := make([]*backfillEntry, 0, len(page))
+	for _, follower := range page {
+		followedAt, _ := time.Parse(time.RFC3339, follower.FollowedAt)
+		entries = append(entries, &backfillEntry{follower.User(), followedAt})
+	}
+
+	state, known := w.backfillState(cache.FollowerBackfillKey, cache.LastFollowerIDKey)
+	followers = state.unseen(entries)
+	state.update(entries)
+
+	if err := w.cache.SetJSON(cache.FollowerBackfillKey, state); err != nil {
+		return nil, false, err
+	}
+
+	newest := entries[0].user
+	w.cache.Set(cache.LastFollowerNameKey, newest.DisplayName)
+	w.cache.Set(cache.LastFollowerIDKey, newest.ID)
+	w.cache.SetInt(cache.TotalFollowerKey, it.Total())
+	return followers, known, nil
+}
+
+// newSubscribers works like newFollowers, subscriptions don't have a date
+// so they are announced in the order Twitch lists them.
+func (w *Worker) newSubscribers() (subscribers []*twitch.User, known bool, err error) {
+	it := w.client.Channel.SubscribersIter(context.Background(), w.conf.Twitch.ChannelID)
+	page, err := it.Collect(MaxBackfill)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get current subscribers with %w", err)
+	}
+	if len(page) == 0 {
+		return nil, false, nil
+	}
+
+	entries := make([]*backfillEntry, 0, len(page))
+	for _, subscription := range page {
+		entries = append(entries, &backfillEntry{user: subscription.User()})
+	}
+
+	state, known := w.backfillState(cache.SubscriberBackfillKey, cache.LastSubscribeIDKey)
+	subscribers = state.unseen(entries)
+	state.update(entries)
+
+	if err := w.cache.SetJSON(cache.SubscriberBackfillKey, state); err != nil {
+		return nil, false, err
+	}
+
+	newest := entries[0].user
+	w.cache.Set(cache.LastSubscribeIDKey, newest.ID)
+	w.cache.Set(cache.LastSubscribeNameKey, newest.DisplayName)
+	w.cache.SetInt(cache.TotalSubscribersKey, it.Total())
+	return subscribers, known, nil
+}
+
+// backfillState loads the state saved at key with the users chat or
+// EventSub announced since the last refresh. Caches saved before the
+// backfill only have the last ID at lastIDKey.
+func (w *Worker) backfillState(key, lastIDKey string) (*backfillState, bool) {
+	w.Lock()
+	announced := w.announced[key]
+	delete(w.announced, key)
+	w.Unlock()
+
+	state := &backfillState{}
+	if err := w.cache.GetJSON(key, state); err == nil {
+		state.announce(announced)
+		return state, true
+	}
+
+	if id, err := w.cache.Get(lastIDKey); err == nil {
+		state.IDs = []string{id}
+		state.announce(announced)
+		return state, true
+	}
+
+	state.announce(announced)
+	return state, false
+}
@@ -0,0 +1,238 @@
+package refresher
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/config"
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+var followedAt = time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)
+
+// entries returns users named by ids, newest first and a minute apart.
+func entries(dated bool, ids ...string) []*backfillEntry {
+	list := make([]*backfillEntry, 0, len(ids))
+	for i, id := range ids {
+		e := &backfillEntry{user: &twitch.User{ID: id, DisplayName: "user_" + id}}
+		if dated {
+			e.at = followedAt.Add(-time.Duration(i) * time.Minute)
+		}
+		list = append(list, e)
+	}
+	return list
+}
+
+func names(users []*twitch.User) string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestUnseen(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		state   *backfillState
+		entries []*backfillEntry
+		want    string
+	}{
+		{
+			name:    "nothing new",
+			state:   &backfillState{IDs: []string{"3", "2", "1"}},
+			entries: entries(false, "3", "2", "1"),
+			want:    "",
+		},
+		{
+			name:    "burst in chronological order",
+			state:   &backfillState{IDs: []string{"2", "1"}},
+			entries: entries(false, "5", "4", "3", "2", "1"),
+			want:    "3,4,5",
+		},
+		{
+			name:    "older followers entering the page aren't new",
+			state:   &backfillState{LastSeen: followedAt.Add(-time.Minute), IDs: []string{"3", "2"}},
+			entries: entries(true, "4", "3", "1"),
+			want:    "4",
+		},
+		{
+			name:    "subscribers after a known one",
+			state:   &backfillState{IDs: []string{"1", "2"}},
+			entries: entries(false, "1", "4", "2", "3"),
+			want:    "3,4",
+		},
+		{
+			name:    "announced users aren't new",
+			state:   &backfillState{IDs: []string{"1"}, Announced: []string{"3"}},
+			entries: entries(false, "1", "3", "2"),
+			want:    "2",
+		},
+		{
+			name:    "first refresh",
+			state:   &backfillState{},
+			entries: entries(false, "2", "1"),
+			want:    "1,2",
+		},
+		{
+			name:    "followers since last seen",
+			state:   &backfillState{LastSeen: followedAt.Add(-2 * time.Minute), IDs: []string{"3", "2", "1"}},
+			entries: entries(true, "5", "4", "3", "2", "1"),
+			want:    "4,5",
+		},
+		{
+			name:    "returning follower doesn't hide the others",
+			state:   &backfillState{LastSeen: followedAt.Add(-2 * time.Minute), IDs: []string{"3", "2", "1", "5"}},
+			entries: entries(true, "5", "4", "3", "2", "1"),
+			want:    "4",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := names(test.state.unseen(test.entries)); got != test.want {
+				t.Errorf("new users don't match want: %s, got: %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	state := &backfillState{}
+	state.update(entries(true, "2", "1"))
+
+	if !state.LastSeen.Equal(followedAt) || strings.Join(state.IDs, ",") != "2,1" {
+		t.Errorf("state doesn't match got: %+v", state)
+	}
+
+	// subscriptions don't move LastSeen
+	state.update(entries(false, "3"))
+	if !state.LastSeen.Equal(followedAt) || strings.Join(state.IDs, ",") != "3" {
+		t.Errorf("state doesn't match got: %+v", state)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	users := func(n int) []*twitch.User {
+		list := make([]*twitch.User, 0, n)
+		for i := 1; i <= n; i++ {
+			list = append(list, &twitch.User{DisplayName: fmt.Sprintf("user_%d", i)})
+		}
+		return list
+	}
+
+	for _, test := range []struct {
+		users int
+		want  string
+	}{
+		{0, ""},
+		{2, "user_1|user_2"},
+		{MaxAlerts, "user_1|user_2|user_3|user_4|user_5"},
+		{16, "user_1|user_2|user_3|user_4|user_16 and 11 others"},
+	} {
+		alerts := summarize(users(test.users))
+		texts := make([]string, 0, len(alerts))
+		follows := 0
+		for _, a := range alerts {
+			texts = append(texts, a.text)
+			follows += a.follows
+		}
+
+		if got := strings.Join(texts, "|"); got != test.want {
+			t.Errorf("alerts don't match want: %s, got: %s", test.want, got)
+		}
+
+		if follows != test.users {
+			t.Errorf("alerts should stand for every user, want: %d, got: %d", test.users, follows)
+		}
+	}
+}
+
+// newBackfillWorker serves responses from path, one per request.
+func newBackfillWorker(t *testing.T, path string, responses []string) (*Worker, *cache.Cache) {
+	mux := http.NewServeMux()
+
+	request := 0
+	mux.HandleFunc(path, func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(rw, responses[request])
+		request++
+	})
+
+	api, c := util.TestCreateClientWithMux(t, mux, nil)
+
+	return New(&config.Config{Twitch: &config.Twitch{ChannelID: "1337"}}, c, api, nil, nil), c
+}
+
+func TestNewFollowers(t *testing.T) {
+	page := `{"total":%d,"data":[%s],"pagination":{}}`
+	follower := `{"user_id":"%[1]s","user_login":"user_%[1]s","user_name":"User_%[1]s","followed_at":"%[2]s"}`
+	responses := []string{
+		fmt.Sprintf(page, 1, fmt.Sprintf(follower, "1", "2021-03-10T15:00:00Z")),
+		fmt.Sprintf(page, 3, strings.Join([]string{
+			fmt.Sprintf(follower, "3", "2021-03-10T15:00:20Z"),
+			fmt.Sprintf(follower, "2", "2021-03-10T15:00:10Z"),
+			fmt.Sprintf(follower, "1", "2021-03-10T15:00:00Z"),
+		}, ",")),
+	}
+
+	w, c := newBackfillWorker(t, "/helix/channels/followers", responses)
+
+	followers, known, err := w.newFollowers()
+	if err != nil || known || names(followers) != "1" {
+		t.Fatalf("first refresh doesn't match got: %s, %t, %v", names(followers), known, err)
+	}
+
+	followers, known, err = w.newFollowers()
+	if err != nil || !known || names(followers) != "2,3" {
+		t.Fatalf("second refresh doesn't match got: %s, %t, %v", names(followers), known, err)
+	}
+
+	if total, _ := c.GetInt(cache.TotalFollowerKey); total != 3 {
+		t.Errorf("total doesn't match want: 3, got: %d", total)
+	}
+
+	if name, _ := c.Get(cache.LastFollowerNameKey); name != "User_3" {
+		t.Errorf("last follower doesn't match want: User_3, got: %s", name)
+	}
+}
+
+func TestNewSubscribersSkipsAnnounced(t *testing.T) {
+	page := `{"total":%d,"data":[%s],"pagination":{}}`
+	subscriber := `{"user_id":"%[1]s","user_login":"user_%[1]s","user_name":"User_%[1]s","tier":"1000"}`
+	list := func(ids ...string) string {
+		subs := make([]string, 0, len(ids))
+		for _, id := range ids {
+			subs = append(subs, fmt.Sprintf(subscriber, id))
+		}
+		return fmt.Sprintf(page, len(ids), strings.Join(subs, ","))
+	}
+
+	w, _ := newBackfillWorker(t, "/helix/subscriptions", []string{
+		list("1"),
+		list("3", "2", "1"),
+		list("4", "3", "2", "1"),
+	})
+
+	if _, _, err := w.newSubscribers(); err != nil {
+		t.Fatalf("failed to get subscribers with %s", err)
+	}
+
+	// chat alerted for 2 and 3, Twitch doesn't list 4 until the refresh
+	// after
+	w.Announced(cache.SubscriberBackfillKey, "2")
+	w.Announced(cache.SubscriberBackfillKey, "3")
+	w.Announced(cache.SubscriberBackfillKey, "4")
+
+	subscribers, _, err := w.newSubscribers()
+	if err != nil || names(subscribers) != "" {
+		t.Fatalf("second refresh doesn't match got: %s, %v", names(subscribers), err)
+	}
+
+	subscribers, _, err = w.newSubscribers()
+	if err != nil || names(subscribers) != "" {
+		t.Fatalf("third refresh doesn't match got: %s, %v", names(subscribers), err)
+	}
+}
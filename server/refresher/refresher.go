@@ -1,16 +1,17 @@
 package refresher
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"os/exec"
 	"runtime"
-	"strconv"
 	"sync"
 	"time"
 
 	"github.com/miguel250/streaming-setup/server/cache"
 	"github.com/miguel250/streaming-setup/server/config"
+	"github.com/miguel250/streaming-setup/server/live"
+	"github.com/miguel250/streaming-setup/server/protection"
 	"github.com/miguel250/streaming-setup/server/stream"
 	"github.com/miguel250/streaming-setup/server/twitch"
 )
@@ -20,13 +21,42 @@ const (
 )
 
 type Worker struct {
-	conf      *config.Config
-	cache     *cache.Cache
-	client    *twitch.API
-	event     *stream.Event
-	isRunning bool
-	once      sync.Once
-	started   sync.Once
+	sync.Mutex
+	conf          *config.Config
+	cache         *cache.Cache
+	client        *twitch.API
+	event         *stream.Event
+	tracker       *live.Tracker
+	follows       protection.FollowHandler
+	isRunning     bool
+	once          sync.Once
+	started       sync.Once
+	authenticated chan struct{}
+	// announced has the users chat and EventSub alerted for by backfill
+	// key, so the refresh doesn't alert again
+	announced map[string][]string
+}
+
+// HandleFollows sends follow alerts to h instead of straight to the
+// overlays.
+func (w *Worker) HandleFollows(h protection.FollowHandler) {
+	w.Lock()
+	defer w.Unlock()
+	w.follows = h
+}
+
+// Announced records that chat or EventSub alerted for the user id, key is
+// cache.FollowerBackfillKey or cache.SubscriberBackfillKey. The next
+// refresh doesn't alert for the user again.
+func (w *Worker) Announced(key, id string) {
+	w.Lock()
+	defer w.Unlock()
+	w.announced[key] = append(w.announced[key], id)
+}
+
+// Authenticated is closed once the Twitch account is authenticated.
+func (w *Worker) Authenticated() <-chan struct{} {
+	return w.authenticated
 }
 
 func (w *Worker) Refresher() {
@@ -36,26 +66,37 @@ func (w *Worker) Refresher() {
 		refreshTime = FollowerRefreshTime * time.Second
 		w.started.Do(func() {
 			log.Println("Authentication completed. Refresh worker started")
+			close(w.authenticated)
 		})
 
-		newFollower, err := w.currentFollower()
+		// EventSub doesn't send the viewer count, the stream is polled
+		// with every event source
+		w.refreshStream()
+
+		// EventSub sends follows and subscriptions as they happen
+		if !w.conf.Twitch.UsePolling() {
+			time.AfterFunc(refreshTime, w.Refresher)
+			return
+		}
+
+		// the first refresh only alerts when the cache was loaded from disk
+		// with the last followers and subscribers
+		newFollowers, resumed, err := w.newFollowers()
 		if err != nil {
-			log.Printf("Failed to get current follower with %v\n", err)
+			log.Printf("Failed to get new followers with %v\n", err)
 		}
 
-		if newFollower != nil && w.isRunning {
-			log.Printf("New follower!! %s\n", newFollower.DisplayName)
-			w.event.Send(stream.NewFollower, newFollower.DisplayName)
+		if w.isRunning || resumed {
+			w.announce(stream.NewFollower, newFollowers)
 		}
 
-		newSubscriber, err := w.currentSubscriber()
+		newSubscribers, resumed, err := w.newSubscribers()
 		if err != nil {
-			log.Printf("failed to get new subscriber with %s", err)
+			log.Printf("failed to get new subscribers with %s", err)
 		}
 
-		if newSubscriber != nil && w.isRunning {
-			log.Printf("New subscriber!! %s\n", newSubscriber.DisplayName)
-			w.event.Send(stream.NewSubscriber, newSubscriber.DisplayName)
+		if w.isRunning || resumed {
+			w.announce(stream.NewSubscriber, newSubscribers)
 		}
 		w.isRunning = true
 	}
@@ -94,62 +135,23 @@ func (w *Worker) apiAuthSuccess() bool {
 	return true
 }
 
-func (w *Worker) currentFollower() (*twitch.User, error) {
-
-	currentFollowers, err := w.client.Channel.Followers(w.conf.Twitch.ChannelID, 1)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to get current followers")
-	}
-	if len(currentFollowers.Follows) == 0 {
-		return nil, nil
-	}
-
-	currentFollower := currentFollowers.Follows[0].User
-	currentFollowerID := currentFollower.ID
-
-	oldFollowerID, _ := w.cache.Get(cache.LastFollowerIDKey)
-
-	if oldFollowerID != currentFollowerID {
-		w.cache.Set(cache.LastFollowerNameKey, currentFollower.DisplayName)
-		w.cache.Set(cache.LastFollowerIDKey, currentFollowerID)
-		w.cache.Set(cache.TotalFollowerKey, strconv.Itoa(currentFollowers.Total))
-		return &currentFollower, nil
-	}
-	return nil, nil
-}
-
-func (w *Worker) currentSubscriber() (*twitch.User, error) {
-
-	currentSubscribers, err := w.client.Channel.Subscribers(w.conf.Twitch.ChannelID, 1)
-
+func (w *Worker) refreshStream() {
+	s, err := w.client.Channel.GetStream(context.Background(), w.conf.Twitch.ChannelID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current followers")
-	}
-	if len(currentSubscribers.Subscriptions) == 0 {
-		return nil, nil
+		log.Printf("failed to get stream status with %s", err)
+		return
 	}
-
-	currentSubscriber := currentSubscribers.Subscriptions[0]
-	currentID := currentSubscriber.User.ID
-
-	oldSubscriberID, _ := w.cache.Get(cache.LastSubscribeIDKey)
-
-	if oldSubscriberID != currentID {
-		w.cache.Set(cache.LastSubscribeIDKey, currentID)
-		w.cache.Set(cache.LastSubscribeNameKey, currentSubscriber.User.DisplayName)
-		w.cache.Set(cache.TotalSubscribersKey, strconv.Itoa(currentSubscribers.Total))
-		return &currentSubscriber.User, nil
-	}
-
-	return nil, nil
+	w.tracker.Update(s)
 }
 
-func New(conf *config.Config, c *cache.Cache, client *twitch.API, event *stream.Event) *Worker {
+func New(conf *config.Config, c *cache.Cache, client *twitch.API, event *stream.Event, tracker *live.Tracker) *Worker {
 	return &Worker{
-		conf:   conf,
-		cache:  c,
-		client: client,
-		event:  event,
+		conf:          conf,
+		cache:         c,
+		client:        client,
+		event:         event,
+		tracker:       tracker,
+		announced:     make(map[string][]string),
+		authenticated: make(chan struct{}),
 	}
 }
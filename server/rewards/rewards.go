@@ -0,0 +1,143 @@
+package rewards
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+// Action types for a reward. Sounds and overlays are played by the
+// redemptions overlay, chat actions send a message to the channel.
+const (
+	SoundAction   = "sound"
+	OverlayAction = "overlay"
+	ChatAction    = "chat"
+)
+
+// statusTimeout bounds updating the redemption status.
+const statusTimeout = 10 * time.Second
+
+var (
+	ErrUnknownAction = errors.New("rewards: unknown action type")
+	ErrMissingReward = errors.New("rewards: redemption without reward")
+)
+
+// Action is what happens when a reward is redeemed.
+type Action struct {
+	RewardID string `json:"reward_id"`
+	Type     string `json:"type"`
+	// Sound is the path of the audio file in the overlays, e.g.
+	// sounds/blip.ogg
+	Sound string `json:"sound,omitempty"`
+	// Overlay is the path of the animation shown by the overlay, e.g.
+	// animation/coffee.json
+	Overlay string `json:"overlay,omitempty"`
+	// Message is sent to chat, {user} and {input} are replaced with the
+	// viewer and the text they entered
+	Message string `json:"message,omitempty"`
+}
+
+// RedemptionEvent is sent to the overlays for every redemption, Action is
+// nil for rewards without an action.
+type RedemptionEvent struct {
+	*twitch.Redemption
+	Action *Action `json:"action,omitempty"`
+}
+
+// Handler runs the action for each redemption and fulfills it, or cancels
+// it to refund the points when the action fails.
+type Handler struct {
+	actions   map[string]*Action
+	api       *twitch.API
+	event     *stream.Event
+	chat      irc.MessageSender
+	channelID string
+	channel   string
+}
+
+// Handle runs the action of the reward. Redemptions of rewards without an
+// action are left in the reward queue for the streamer.
+func (h *Handler) Handle(r *twitch.Redemption) error {
+	if r.Reward == nil {
+		return ErrMissingReward
+	}
+
+	action, ok := h.actions[r.Reward.ID]
+
+	err := h.send(&RedemptionEvent{Redemption: r, Action: action})
+	if err != nil || !ok {
+		return err
+	}
+
+	status := twitch.RedemptionFulfilled
+	actionErr := h.run(action, r)
+	if actionErr != nil {
+		status = twitch.RedemptionCanceled
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+
+	// rewards that skip the queue are fulfilled by Twitch, EventSub sends
+	// the status in lowercase
+	if strings.EqualFold(r.Status, twitch.RedemptionUnfulfilled) {
+		_, err = h.api.Channel.UpdateRedemptionStatus(ctx, h.channelID, r.Reward.ID, r.ID, status)
+		if err != nil {
+			return fmt.Errorf("failed to mark redemption as %s with %w", status, err)
+		}
+	}
+
+	if actionErr != nil {
+		return fmt.Errorf("failed to run %s action for %s with %w", action.Type, r.Reward.Title, actionErr)
+	}
+	return nil
+}
+
+// run does the part of the action that isn't done by the overlay. Chat
+// messages wait in the send queue until they go out, so the redemption is
+// only fulfilled once the viewer can see the message.
+func (h *Handler) run(action *Action, r *twitch.Redemption) error {
+	switch action.Type {
+	case SoundAction, OverlayAction:
+		return nil
+	case ChatAction:
+		replacer := strings.NewReplacer("{user}", r.UserName, "{input}", r.UserInput)
+		return h.chat.SendMessageToWait(context.Background(), h.channel, replacer.Replace(action.Message))
+	}
+	return ErrUnknownAction
+}
+
+func (h *Handler) send(e *RedemptionEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode redemption with %w", err)
+	}
+
+	h.event.Send(stream.NewRedemption, string(b))
+	return nil
+}
+
+// New creates the handler for the rewards of channelID, chat messages are
+// sent to channel.
+func New(actions []*Action, api *twitch.API, event *stream.Event, chat irc.MessageSender, channelID, channel string) *Handler {
+	byReward := make(map[string]*Action, len(actions))
+	for _, action := range actions {
+		byReward[action.RewardID] = action
+	}
+
+	return &Handler{
+		actions:   byReward,
+		api:       api,
+		event:     event,
+		chat:      chat,
+		channelID: channelID,
+		channel:   channel,
+	}
+}
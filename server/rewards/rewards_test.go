@@ -0,0 +1,188 @@
+package rewards
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/irc/util"
+	"github.com/miguel250/streaming-setup/server/stream"
+	"github.com/miguel250/streaming-setup/server/twitch"
+	twitch_util "github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+// newTestAPI stands in for Helix and records the redemption status
+// updates.
+func newTestAPI(t *testing.T) (*twitch.API, *[]string) {
+	var (
+		mu       sync.Mutex
+		statuses []string
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/channel_points/custom_rewards/redemptions", func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("broadcaster_id") != "1337" || query.Get("reward_id") != "reward-1" || query.Get("id") != "redemption-1" {
+			t.Errorf("query doesn't match got: %s", req.URL.RawQuery)
+		}
+
+		body := map[string]string{}
+		json.NewDecoder(req.Body).Decode(&body)
+
+		mu.Lock()
+		statuses = append(statuses, body["status"])
+		mu.Unlock()
+
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"data": []map[string]string{{"id": "redemption-1", "status": body["status"]}},
+		})
+	})
+
+	api, _ := twitch_util.TestCreateClientWithMux(t, mux, nil)
+	return api, &statuses
+}
+
+func newRedemption(rewardID, status string) *twitch.Redemption {
+	return &twitch.Redemption{
+		ID:        "redemption-1",
+		UserName:  "Cooler_User",
+		UserInput: "pogchamp",
+		Status:    status,
+		Reward:    &twitch.CustomReward{ID: rewardID, Title: "Hydrate"},
+	}
+}
+
+func TestHandle(t *testing.T) {
+	actions := []*Action{
+		{RewardID: "reward-1", Type: SoundAction, Sound: "sounds/blip.ogg"},
+		{RewardID: "reward-2", Type: ChatAction, Message: "{user} says {input}"},
+		{RewardID: "reward-3", Type: "dance"},
+	}
+
+	for _, test := range []struct {
+		name         string
+		redemption   *twitch.Redemption
+		chatErr      error
+		actions      []*Action
+		wantErr      bool
+		wantStatuses []string
+		wantMessages []string
+		wantAction   bool
+	}{
+		{
+			name:         "sound is fulfilled",
+			redemption:   newRedemption("reward-1", "unfulfilled"),
+			actions:      actions,
+			wantStatuses: []string{twitch.RedemptionFulfilled},
+			wantAction:   true,
+		},
+		{
+			name:         "chat message is fulfilled",
+			redemption:   newRedemption("reward-1", "unfulfilled"),
+			actions:      []*Action{{RewardID: "reward-1", Type: ChatAction, Message: "{user} says {input}"}},
+			wantStatuses: []string{twitch.RedemptionFulfilled},
+			wantMessages: []string{"miguelcodetv: Cooler_User says pogchamp"},
+			wantAction:   true,
+		},
+		{
+			name:         "failed chat message is canceled",
+			redemption:   newRedemption("reward-1", "unfulfilled"),
+			actions:      []*Action{{RewardID: "reward-1", Type: ChatAction, Message: "hi"}},
+			chatErr:      errors.New("chat is down"),
+			wantErr:      true,
+			wantStatuses: []string{twitch.RedemptionCanceled},
+			wantAction:   true,
+		},
+		{
+			name:         "unknown action is canceled",
+			redemption:   newRedemption("reward-1", "UNFULFILLED"),
+			actions:      []*Action{{RewardID: "reward-1", Type: "dance"}},
+			wantErr:      true,
+			wantStatuses: []string{twitch.RedemptionCanceled},
+			wantAction:   true,
+		},
+		{
+			name:       "reward skipped the queue",
+			redemption: newRedemption("reward-1", "fulfilled"),
+			actions:    actions,
+			wantAction: true,
+		},
+		{
+			name:       "reward without action stays in the queue",
+			redemption: newRedemption("reward-9", "unfulfilled"),
+			actions:    actions,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			api, statuses := newTestAPI(t)
+			chat := &util.MockSender{Err: test.chatErr}
+			event := stream.New()
+			h := New(test.actions, api, event, chat, "1337", "miguelcodetv")
+
+			done := make(chan error)
+			go func() {
+				done <- h.Handle(test.redemption)
+			}()
+
+			var msg stream.Message
+			select {
+			case msg = <-event.Message:
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for redemption event")
+			}
+
+			if err := <-done; (err != nil) != test.wantErr {
+				t.Errorf("error doesn't match want error: %t, got: %v", test.wantErr, err)
+			}
+
+			if msg.Type != stream.NewRedemption {
+				t.Errorf("event type doesn't match got: %s", stream.EventTypeToString[msg.Type])
+			}
+
+			got := &RedemptionEvent{}
+			if err := json.Unmarshal([]byte(msg.Text), got); err != nil {
+				t.Fatalf("failed to parse event with %s", err)
+			}
+
+			if (got.Action != nil) != test.wantAction {
+				t.Errorf("action doesn't match want action: %t, got: %+v", test.wantAction, got.Action)
+			}
+
+			if got.ID != "redemption-1" || got.Reward == nil {
+				t.Errorf("redemption doesn't match got: %s", msg.Text)
+			}
+
+			if len(*statuses) != len(test.wantStatuses) {
+				t.Fatalf("statuses don't match want: %v, got: %v", test.wantStatuses, *statuses)
+			}
+
+			for i := range test.wantStatuses {
+				if (*statuses)[i] != test.wantStatuses[i] {
+					t.Errorf("status doesn't match want: %s, got: %s", test.wantStatuses[i], (*statuses)[i])
+				}
+			}
+
+			messages := chat.Messages()
+			if len(messages) != len(test.wantMessages) {
+				t.Fatalf("chat messages don't match want: %v, got: %v", test.wantMessages, messages)
+			}
+
+			for i := range test.wantMessages {
+				if messages[i] != test.wantMessages[i] {
+					t.Errorf("chat message doesn't match want: %s, got: %s", test.wantMessages[i], messages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHandleMissingReward(t *testing.T) {
+	h := New(nil, nil, stream.New(), &util.MockSender{}, "1337", "miguelcodetv")
+
+	if err := h.Handle(&twitch.Redemption{ID: "redemption-1"}); err != ErrMissingReward {
+		t.Errorf("expected missing reward error, got: %v", err)
+	}
+}
@@ -15,6 +15,24 @@ const (
 	NewFollower EventType = iota
 	NewSubscriber
 	NewChatMessage
+	NewResubscription
+	NewGiftSubscription
+	NewMysteryGift
+	NewRaid
+	NewRitual
+	NewBitsBadgeTier
+	NewCheer
+	ClearChat
+	DeleteMessage
+	UserBanned
+	RoomStateChanged
+	EmoteUsed
+	EmoteCombo
+	NewRedemption
+	StreamOnline
+	StreamOffline
+	ViewerCount
+	GoalsUpdated
 )
 
 type Event struct {
@@ -35,9 +53,27 @@ type Message struct {
 }
 
 var EventTypeToString = map[EventType]string{
-	NewFollower:    "new_follower",
-	NewSubscriber:  "new_subscriber",
-	NewChatMessage: "new_chat_message",
+	NewFollower:         "new_follower",
+	NewSubscriber:       "new_subscriber",
+	NewChatMessage:      "new_chat_message",
+	NewResubscription:   "new_resubscription",
+	NewGiftSubscription: "new_gift_subscription",
+	NewMysteryGift:      "new_mystery_gift",
+	NewRaid:             "new_raid",
+	NewRitual:           "new_ritual",
+	NewBitsBadgeTier:    "new_bits_badge_tier",
+	NewCheer:            "new_cheer",
+	ClearChat:           "clear_chat",
+	DeleteMessage:       "delete_message",
+	UserBanned:          "user_banned",
+	RoomStateChanged:    "room_state",
+	EmoteUsed:           "emote_used",
+	EmoteCombo:          "emote_combo",
+	NewRedemption:       "new_redemption",
+	StreamOnline:        "stream_online",
+	StreamOffline:       "stream_offline",
+	ViewerCount:         "viewer_count",
+	GoalsUpdated:        "goals_updated",
 }
 
 func (e *Event) Start() error {
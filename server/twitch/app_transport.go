@@ -0,0 +1,72 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// appTokenExpiryMargin renews the app token before Twitch expires it so
+// requests in flight don't fail.
+const appTokenExpiryMargin = time.Minute
+
+// appTransport adds an app access token to requests for endpoints that
+// don't need a user token. The token is requested with the client
+// credentials flow and kept in memory.
+type appTransport struct {
+	sync.Mutex
+	api       *API
+	tr        http.RoundTripper
+	token     string
+	expiresAt time.Time
+	now       func() time.Time
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.appToken(req.Context())
+
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	return t.tr.RoundTrip(req)
+}
+
+// appToken returns the current token or requests a new one. The lock is
+// held while requesting so concurrent requests wait for the same token
+// instead of each asking for one.
+func (t *appTransport) appToken(ctx context.Context) (string, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.token != "" && t.now().Add(appTokenExpiryMargin).Before(t.expiresAt) {
+		return t.token, nil
+	}
+
+	resp, err := t.api.AppToken(ctx)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get app access token with %w", err)
+	}
+
+	t.token = resp.AccessToken
+	t.expiresAt = t.now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	return t.token, nil
+}
+
+// invalidate drops the token so the next request gets a new one.
+func (t *appTransport) invalidate() {
+	t.Lock()
+	defer t.Unlock()
+	t.token = ""
+}
+
+func newAppTransport(api *API) *appTransport {
+	return &appTransport{
+		api: api,
+		tr:  http.DefaultTransport,
+		now: time.Now,
+	}
+}
@@ -0,0 +1,153 @@
+package twitch_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/twitch"
+)
+
+type oauthServer struct {
+	*httptest.Server
+	tokenRequests int32
+	expiresIn     int64
+}
+
+// newOAuthServer issues app-token-<n> tokens and only serves users to
+// requests with the latest one.
+func newOAuthServer(t *testing.T, expiresIn int64) *oauthServer {
+	ts := &oauthServer{expiresIn: expiresIn}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/oauth2/token", func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		if query.Get("grant_type") != "client_credentials" {
+			t.Errorf("grant type doesn't match want: client_credentials, got: %s", query.Get("grant_type"))
+		}
+
+		if query.Get("client_id") != "test_client_id" || query.Get("client_secret") != "test_secret" {
+			http.Error(rw, "", http.StatusForbidden)
+			return
+		}
+
+		// slow down the token so concurrent requests overlap
+		time.Sleep(20 * time.Millisecond)
+		count := atomic.AddInt32(&ts.tokenRequests, 1)
+		fmt.Fprintf(rw, `{"access_token":"app-token-%d","expires_in":%d,"token_type":"bearer"}`, count, ts.expiresIn)
+	})
+
+	mux.HandleFunc("/helix/users", func(rw http.ResponseWriter, req *http.Request) {
+		want := fmt.Sprintf("Bearer app-token-%d", atomic.LoadInt32(&ts.tokenRequests))
+
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("authorization doesn't match want: %s, got: %s", want, got)
+			http.Error(rw, "", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(rw, `{"data":[{"id":"239246205","login":"attackkopter","display_name":"AttackKopter"}]}`)
+	})
+
+	ts.Server = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *oauthServer) createClient(t *testing.T) *twitch.API {
+	api, err := twitch.New(&twitch.Config{
+		TwitchURL:   ts.URL,
+		AuthURL:     ts.URL,
+		RedirectURL: "http://localhost/api/auth",
+		ClientID:    "test_client_id",
+		Secret:      "test_secret",
+	}, cache.New())
+
+	if err != nil {
+		t.Fatalf("Failed to create API struct %v", err)
+	}
+	return api
+}
+
+func TestAppToken(t *testing.T) {
+	ts := newOAuthServer(t, 3600)
+	api := ts.createClient(t)
+
+	for i := 0; i < 3; i++ {
+		user, err := api.GetUser(context.Background(), "239246205")
+		if err != nil {
+			t.Fatalf("failed to get user without a user token with %s", err)
+		}
+
+		if user.DisplayName != "AttackKopter" {
+			t.Errorf("display name doesn't match want: AttackKopter, got: %s", user.DisplayName)
+		}
+	}
+
+	if got := atomic.LoadInt32(&ts.tokenRequests); got != 1 {
+		t.Errorf("app token should be reused, token requests: %d", got)
+	}
+}
+
+func TestAppTokenSingleFlight(t *testing.T) {
+	ts := newOAuthServer(t, 3600)
+	api := ts.createClient(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := api.GetUser(context.Background(), "239246205"); err != nil {
+				t.Errorf("failed to get user with %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&ts.tokenRequests); got != 1 {
+		t.Errorf("concurrent requests should share one token request, token requests: %d", got)
+	}
+}
+
+func TestAppTokenRenew(t *testing.T) {
+	// tokens that expire in less than a minute are renewed before use
+	ts := newOAuthServer(t, 30)
+	api := ts.createClient(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := api.GetUser(context.Background(), "239246205"); err != nil {
+			t.Fatalf("failed to get user with %s", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&ts.tokenRequests); got != 2 {
+		t.Errorf("expiring token should be renewed, token requests: %d", got)
+	}
+}
+
+func TestAppTokenInvalidCredentials(t *testing.T) {
+	ts := newOAuthServer(t, 3600)
+
+	api, err := twitch.New(&twitch.Config{
+		TwitchURL:   ts.URL,
+		AuthURL:     ts.URL,
+		RedirectURL: "http://localhost/api/auth",
+		ClientID:    "test_client_id",
+		Secret:      "wrong_secret",
+	}, cache.New())
+
+	if err != nil {
+		t.Fatalf("Failed to create API struct %v", err)
+	}
+
+	if _, err := api.GetUser(context.Background(), "239246205"); err == nil {
+		t.Error("expected an error when the app token can't be created")
+	}
+}
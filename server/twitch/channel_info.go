@@ -0,0 +1,143 @@
+package twitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	channelsPath         = "/helix/channels"
+	searchCategoriesPath = "/helix/search/categories"
+)
+
+// categoryMatches is how many close matches CategoryNotFoundError lists.
+const categoryMatches = 3
+
+var ErrCategoryNotFound = errors.New("twitch category not found")
+
+// CategoryNotFoundError is returned when no category has the exact name,
+// Matches has the closest ones so the user can pick one.
+type CategoryNotFoundError struct {
+	Name    string
+	Matches []*Category
+}
+
+func (e *CategoryNotFoundError) Error() string {
+	if len(e.Matches) == 0 {
+		return fmt.Sprintf("twitch category %s not found", e.Name)
+	}
+	return fmt.Sprintf("twitch category %s not found, did you mean %s", e.Name, strings.Join(e.MatchNames(), ", "))
+}
+
+func (e *CategoryNotFoundError) Unwrap() error {
+	return ErrCategoryNotFound
+}
+
+// MatchNames returns the names of the closest matches.
+func (e *CategoryNotFoundError) MatchNames() []string {
+	names := make([]string, 0, len(e.Matches))
+	for _, category := range e.Matches {
+		names = append(names, category.Name)
+	}
+	return names
+}
+
+type ChannelInformation struct {
+	BroadcasterID       string   `json:"broadcaster_id"`
+	BroadcasterLogin    string   `json:"broadcaster_login"`
+	BroadcasterName     string   `json:"broadcaster_name"`
+	BroadcasterLanguage string   `json:"broadcaster_language"`
+	GameID              string   `json:"game_id"`
+	GameName            string   `json:"game_name"`
+	Title               string   `json:"title"`
+	Tags                []string `json:"tags"`
+}
+
+type channelInformationResponse struct {
+	Data []*ChannelInformation `json:"data"`
+}
+
+// ChannelUpdate has the fields to change, empty fields are left as they
+// are. Tags points to an empty slice to remove every tag.
+type ChannelUpdate struct {
+	GameID string    `json:"game_id,omitempty"`
+	Title  string    `json:"title,omitempty"`
+	Tags   *[]string `json:"tags,omitempty"`
+}
+
+// Category is a game or other category a stream can be in.
+type Category struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	BoxArtURL string `json:"box_art_url"`
+}
+
+type categoriesResponse struct {
+	Data []*Category `json:"data"`
+}
+
+// GetInformation returns the title, category and tags of the channel.
+func (c *Channel) GetInformation(ctx context.Context, channelID string) (*ChannelInformation, error) {
+	responseData := &channelInformationResponse{}
+	err := c.api.get(ctx, c.api.appClient, channelsPath, map[string]string{"broadcaster_id": channelID}, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel information with %w", err)
+	}
+
+	if len(responseData.Data) == 0 {
+		return nil, fmt.Errorf("failed to get channel information for %s with %w", channelID, ErrUserNotFound)
+	}
+	return responseData.Data[0], nil
+}
+
+// ModifyInformation changes the title, category or tags of the channel, it
+// needs a user token with the channel:manage:broadcast scope.
+func (c *Channel) ModifyInformation(ctx context.Context, channelID string, update *ChannelUpdate) error {
+	err := c.api.do(ctx, c.api.authClient, "PATCH", channelsPath, map[string]string{"broadcaster_id": channelID}, update, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to modify channel information with %w", err)
+	}
+	return nil
+}
+
+// SearchCategories returns the categories matching query, the best
+// matches first.
+func (api *API) SearchCategories(ctx context.Context, query string, limit int) ([]*Category, error) {
+	queryParam := map[string]string{
+		"query": query,
+		"first": strconv.Itoa(limit),
+	}
+
+	responseData := &categoriesResponse{}
+	err := api.get(ctx, api.appClient, searchCategoriesPath, queryParam, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search categories with %w", err)
+	}
+	return responseData.Data, nil
+}
+
+// FindCategory returns the category named name, a CategoryNotFoundError
+// with the closest matches when none has the exact name.
+func (api *API) FindCategory(ctx context.Context, name string) (*Category, error) {
+	categories, err := api.SearchCategories(ctx, name, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) {
+			return category, nil
+		}
+	}
+
+	if len(categories) > categoryMatches {
+		categories = categories[:categoryMatches]
+	}
+	return nil, &CategoryNotFoundError{Name: name, Matches: categories}
+}
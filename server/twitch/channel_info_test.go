@@ -0,0 +1,135 @@
+package twitch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+func TestGetChannelInformation(t *testing.T) {
+	channelID := "141981764"
+	queryParams := map[string]string{
+		"broadcaster_id": channelID,
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "channel_information_response", "/helix/channels", channelID, queryParams, nil, 3600)
+	defer ts.Close()
+
+	info, err := api.Channel.GetInformation(context.Background(), channelID)
+	if err != nil {
+		t.Fatalf("failed to get channel information with %s", err)
+	}
+
+	if info.Title != "TwitchDev Monthly Update // May 6, 2021" || info.GameName != "Science & Technology" {
+		t.Errorf("channel information doesn't match got: %+v", info)
+	}
+
+	if len(info.Tags) != 1 || info.Tags[0] != "DevsInTheKnow" {
+		t.Errorf("tags don't match got: %v", info.Tags)
+	}
+}
+
+func TestModifyChannelInformation(t *testing.T) {
+	channelID := "141981764"
+	queryParams := map[string]string{
+		"broadcaster_id": channelID,
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer test_access_token",
+		"Content-Type":  "application/json",
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "channel_information_response", "/helix/channels", channelID, queryParams, headers, 3600)
+	defer ts.Close()
+
+	err := api.Channel.ModifyInformation(context.Background(), channelID, &twitch.ChannelUpdate{Title: "New title"})
+	if err != nil {
+		t.Fatalf("failed to modify channel information with %s", err)
+	}
+}
+
+func TestChannelUpdateClearsTags(t *testing.T) {
+	b, err := json.Marshal(&twitch.ChannelUpdate{Tags: &[]string{}})
+	if err != nil {
+		t.Fatalf("failed to encode channel update with %s", err)
+	}
+
+	if string(b) != `{"tags":[]}` {
+		t.Errorf("channel update doesn't match want: {\"tags\":[]}, got: %s", b)
+	}
+}
+
+func TestFindCategory(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		query  string
+		wantID string
+	}{
+		{"exact match", "science & technology", "509670"},
+		{"no exact match", "Science", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			queryParams := map[string]string{
+				"query": test.query,
+			}
+			api, ts := util.TestCreateClientQueryParams(t, "search_categories_response", "/helix/search/categories", "", queryParams, nil, 3600)
+			defer ts.Close()
+
+			category, err := api.FindCategory(context.Background(), test.query)
+			if test.wantID == "" {
+				var notFound *twitch.CategoryNotFoundError
+				if !errors.As(err, &notFound) || !errors.Is(err, twitch.ErrCategoryNotFound) {
+					t.Fatalf("expected a CategoryNotFoundError, got: %v", err)
+				}
+
+				if names := strings.Join(notFound.MatchNames(), ", "); names != "Fortnite, Science & Technology" {
+					t.Errorf("matches don't match want: Fortnite, Science & Technology, got: %s", names)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to find category with %s", err)
+			}
+
+			if category.ID != test.wantID {
+				t.Errorf("category doesn't match want: %s, got: %s", test.wantID, category.ID)
+			}
+		})
+	}
+}
+
+func TestAuthURLScopes(t *testing.T) {
+	api, ts := util.TestCreateClient(t, "user_response", "/helix/users", "")
+	defer ts.Close()
+
+	u, err := url.Parse(api.AuthURL())
+	if err != nil {
+		t.Fatalf("failed to parse auth url with %s", err)
+	}
+
+	scopes := strings.Split(u.Query().Get("scope"), " ")
+	for _, want := range []string{
+		"channel:read:subscriptions",
+		"moderator:read:followers",
+		"bits:read",
+		"channel:manage:redemptions",
+		"channel:manage:broadcast",
+		"moderator:manage:chat_settings",
+	} {
+		found := false
+		for _, scope := range scopes {
+			if scope == want {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("scope %s is missing from %v", want, scopes)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+)
+
+const chatSettingsPath = "/helix/chat/settings"
+
+// ChatSettingsUpdate has the chat modes to change, nil fields are left as
+// they are. FollowerModeDuration is how many minutes users have to follow
+// before they can chat.
+type ChatSettingsUpdate struct {
+	EmoteMode            *bool `json:"emote_mode,omitempty"`
+	FollowerMode         *bool `json:"follower_mode,omitempty"`
+	FollowerModeDuration *int  `json:"follower_mode_duration,omitempty"`
+}
+
+// UpdateChatSettings changes the chat modes of the channel, it needs a user
+// token for moderatorID with the moderator:manage:chat_settings scope.
+// moderatorID is channelID when the token is the broadcaster's.
+func (c *Channel) UpdateChatSettings(ctx context.Context, channelID, moderatorID string, update *ChatSettingsUpdate) error {
+	queryParam := map[string]string{
+		"broadcaster_id": channelID,
+		"moderator_id":   moderatorID,
+	}
+
+	err := c.api.do(ctx, c.api.authClient, "PATCH", chatSettingsPath, queryParam, update, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to update chat settings with %w", err)
+	}
+	return nil
+}
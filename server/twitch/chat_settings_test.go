@@ -0,0 +1,44 @@
+package twitch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+func TestUpdateChatSettings(t *testing.T) {
+	var body string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/chat/settings", func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if req.Method != "PATCH" || query.Get("broadcaster_id") != "713936733" || query.Get("moderator_id") != "713936733" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+
+		b, _ := ioutil.ReadAll(req.Body)
+		body = string(b)
+
+		response, _ := ioutil.ReadFile("testdata/chat_settings_response.json")
+		rw.Write(response)
+	})
+
+	api, _ := util.TestCreateClientWithMux(t, mux, nil)
+
+	on, minutes := true, 10
+	err := api.Channel.UpdateChatSettings(context.Background(), "713936733", "713936733", &twitch.ChatSettingsUpdate{
+		FollowerMode:         &on,
+		FollowerModeDuration: &minutes,
+	})
+	if err != nil {
+		t.Fatalf("failed to update chat settings with %s", err)
+	}
+
+	if want := `{"follower_mode":true,"follower_mode_duration":10}`; body != want {
+		t.Errorf("body doesn't match want: %s, got: %s", want, body)
+	}
+}
@@ -1,24 +1,37 @@
 package twitch
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrNilConf            = errors.New("twitch client config can't be nil")
 	ErrMissingTwitchURL   = errors.New("twitch API url can't be empty")
-	ErrMissingBadgeURL    = errors.New("twitch badge API url can't be empty")
 	ErrMissingClientID    = errors.New("twitch client_id can't be empty")
 	ErrMissingAuthURL     = errors.New("twitch auth url can't be empty")
 	ErrMissingRedirectURL = errors.New("twitch redirect url can't be empty")
 	ErrMissingSecret      = errors.New("twitch secret can't be empty")
+	ErrUserNotFound       = fmt.Errorf("twitch user not found with %w", ErrNotFound)
 )
 
 type Config struct {
 	TwitchURL   string
 	ClientID    string
-	BadgeURL    string
 	Secret      string
 	AuthURL     string
 	RedirectURL string
+
+	// Timeout for every request, 10 seconds when it isn't set.
+	Timeout time.Duration
+	// MaxRetries is how many times rate limited requests and server
+	// errors of GET, PUT and DELETE requests are retried, 3 when it isn't
+	// set.
+	MaxRetries int
+	// RetryDelay is the first delay between retries, it doubles on every
+	// retry.
+	RetryDelay time.Duration
 }
 
 func (conf *Config) validate() error {
@@ -27,10 +40,6 @@ func (conf *Config) validate() error {
 		return ErrMissingTwitchURL
 	}
 
-	if conf.BadgeURL == "" {
-		return ErrMissingBadgeURL
-	}
-
 	if conf.ClientID == "" {
 		return ErrMissingClientID
 	}
@@ -24,15 +24,6 @@ func TestConfigValidation(t *testing.T) {
 		TwitchURL: "invalid_url",
 	}, cache.New())
 
-	if err.Error() != twitch.ErrMissingBadgeURL.Error() {
-		t.Errorf("error didn't match what we expected %s", err)
-	}
-
-	_, err = twitch.New(&twitch.Config{
-		TwitchURL: "invalid_url",
-		BadgeURL:  "::////super_invalid_url",
-	}, cache.New())
-
 	if err.Error() != twitch.ErrMissingClientID.Error() {
 		t.Errorf("error didn't match what we expected %s", err)
 	}
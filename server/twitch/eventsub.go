@@ -0,0 +1,112 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const eventSubSubscriptionsPath = "/helix/eventsub/subscriptions"
+
+// EventSub transport methods, webhooks are created with the app token and
+// websockets with the user token.
+const (
+	WebhookMethod   = "webhook"
+	WebSocketMethod = "websocket"
+)
+
+// EventSubTransport is where Twitch sends notifications. Callback and
+// Secret are only used by webhooks and SessionID by websockets.
+type EventSubTransport struct {
+	Method    string `json:"method"`
+	Callback  string `json:"callback,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// EventSubSubscription is a subscription to a type of event, Condition
+// depends on the type, e.g. broadcaster_user_id for channel.subscribe.
+type EventSubSubscription struct {
+	ID        string            `json:"id,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+	Transport EventSubTransport `json:"transport"`
+	CreatedAt string            `json:"created_at,omitempty"`
+	Cost      int               `json:"cost,omitempty"`
+}
+
+type EventSubResponse struct {
+	Data         []*EventSubSubscription `json:"data"`
+	Total        int                     `json:"total"`
+	TotalCost    int                     `json:"total_cost"`
+	MaxTotalCost int                     `json:"max_total_cost"`
+	Pagination   Pagination              `json:"pagination"`
+}
+
+// CreateEventSubSubscription subscribes to the event, webhook
+// subscriptions stay pending until the callback answers the challenge.
+func (api *API) CreateEventSubSubscription(ctx context.Context, sub *EventSubSubscription) (*EventSubSubscription, error) {
+	responseData := &EventSubResponse{}
+	err := api.do(ctx, api.eventSubClient(sub.Transport.Method), "POST", eventSubSubscriptionsPath, nil, sub, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s subscription with %w", sub.Type, err)
+	}
+
+	if len(responseData.Data) == 0 {
+		return nil, fmt.Errorf("failed to create %s subscription with empty response", sub.Type)
+	}
+	return responseData.Data[0], nil
+}
+
+// EventSubSubscriptions returns the subscriptions for the transport method,
+// status filters them when it isn't empty, e.g. enabled.
+func (api *API) EventSubSubscriptions(ctx context.Context, method, status string) ([]*EventSubSubscription, error) {
+	subscriptions := make([]*EventSubSubscription, 0)
+	cursor := ""
+
+	for {
+		queryParam := map[string]string{}
+		if status != "" {
+			queryParam["status"] = status
+		}
+
+		if cursor != "" {
+			queryParam["after"] = cursor
+		}
+
+		responseData := &EventSubResponse{}
+		err := api.get(ctx, api.eventSubClient(method), eventSubSubscriptionsPath, queryParam, responseData)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to get eventsub subscriptions with %w", err)
+		}
+
+		subscriptions = append(subscriptions, responseData.Data...)
+		cursor = responseData.Pagination.Cursor
+
+		if cursor == "" || len(responseData.Data) == 0 {
+			return subscriptions, nil
+		}
+	}
+}
+
+// DeleteEventSubSubscription removes a subscription created with the
+// transport method.
+func (api *API) DeleteEventSubSubscription(ctx context.Context, method, id string) error {
+	err := api.do(ctx, api.eventSubClient(method), "DELETE", eventSubSubscriptionsPath, map[string]string{"id": id}, nil, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete eventsub subscription %s with %w", id, err)
+	}
+	return nil
+}
+
+func (api *API) eventSubClient(method string) *http.Client {
+	if method == WebSocketMethod {
+		return api.authClient
+	}
+	return api.appClient
+}
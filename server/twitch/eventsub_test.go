@@ -0,0 +1,109 @@
+package twitch_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+func newEventSubServer(t *testing.T) *twitch.API {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/helix/eventsub/subscriptions", func(rw http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Authorization"); got != "Bearer app-token" {
+			t.Errorf("webhooks should use the app token, got: %s", got)
+		}
+
+		switch req.Method {
+		case "POST":
+			if got := req.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("content type doesn't match want: application/json, got: %s", got)
+			}
+
+			sub := &twitch.EventSubSubscription{}
+			if err := json.NewDecoder(req.Body).Decode(sub); err != nil {
+				t.Errorf("failed to decode subscription with %s", err)
+			}
+
+			sub.ID = "f1c2a387-161a-49f9-a165-0f21d7a4e1c4"
+			sub.Status = "webhook_callback_verification_pending"
+			sub.Transport.Secret = ""
+
+			rw.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(rw).Encode(map[string]interface{}{"data": []*twitch.EventSubSubscription{sub}})
+		case "GET":
+			if got := req.URL.Query().Get("status"); got != "enabled" {
+				t.Errorf("status doesn't match want: enabled, got: %s", got)
+			}
+
+			if req.URL.Query().Get("after") == "" {
+				fmt.Fprint(rw, `{"data":[{"id":"1","type":"channel.follow","status":"enabled"}],"pagination":{"cursor":"next"}}`)
+				return
+			}
+			fmt.Fprint(rw, `{"data":[{"id":"2","type":"channel.raid","status":"enabled"}],"pagination":{}}`)
+		case "DELETE":
+			if got := req.URL.Query().Get("id"); got != "1" {
+				t.Errorf("id doesn't match want: 1, got: %s", got)
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", req.Method)
+		}
+	})
+
+	api, _ := util.TestCreateClientWithMux(t, mux, nil)
+	return api
+}
+
+func TestCreateEventSubSubscription(t *testing.T) {
+	api := newEventSubServer(t)
+
+	sub, err := api.CreateEventSubSubscription(context.Background(), &twitch.EventSubSubscription{
+		Type:      "channel.follow",
+		Version:   "2",
+		Condition: map[string]string{"broadcaster_user_id": "1234", "moderator_user_id": "1234"},
+		Transport: twitch.EventSubTransport{
+			Method:   twitch.WebhookMethod,
+			Callback: "https://example.com/eventsub",
+			Secret:   "s3cre7s3cre7",
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("failed to create subscription with %s", err)
+	}
+
+	if sub.ID == "" || sub.Status != "webhook_callback_verification_pending" {
+		t.Errorf("subscription doesn't match got: %+v", sub)
+	}
+
+	if sub.Condition["broadcaster_user_id"] != "1234" {
+		t.Errorf("condition doesn't match got: %v", sub.Condition)
+	}
+}
+
+func TestEventSubSubscriptions(t *testing.T) {
+	api := newEventSubServer(t)
+
+	subs, err := api.EventSubSubscriptions(context.Background(), twitch.WebhookMethod, "enabled")
+	if err != nil {
+		t.Fatalf("failed to get subscriptions with %s", err)
+	}
+
+	if len(subs) != 2 || subs[0].Type != "channel.follow" || subs[1].Type != "channel.raid" {
+		t.Errorf("subscriptions from every page should be returned, got: %v", subs)
+	}
+}
+
+func TestDeleteEventSubSubscription(t *testing.T) {
+	api := newEventSubServer(t)
+
+	if err := api.DeleteEventSubSubscription(context.Background(), twitch.WebhookMethod, "1"); err != nil {
+		t.Fatalf("failed to delete subscription with %s", err)
+	}
+}
@@ -0,0 +1,171 @@
+package twitch
+
+import "context"
+
+// pageSize is the largest page Helix returns for list endpoints.
+const pageSize = 100
+
+// pageIterator walks the pages of a Helix list endpoint. fetch loads the
+// page after cursor and returns how many items it has and the cursor of
+// the next page.
+type pageIterator struct {
+	ctx    context.Context
+	fetch  func(ctx context.Context, cursor string) (int, string, error)
+	index  int
+	size   int
+	cursor string
+	last   bool
+	err    error
+}
+
+func (p *pageIterator) next() bool {
+	if p.err != nil {
+		return false
+	}
+
+	p.index++
+
+	for p.index >= p.size {
+		if p.last {
+			return false
+		}
+
+		size, cursor, err := p.fetch(p.ctx, p.cursor)
+		if err != nil {
+			p.err = err
+			return false
+		}
+
+		p.index = 0
+		p.size = size
+		p.cursor = cursor
+		// Helix omits the cursor on the last page, but it can also send one
+		// with an empty page
+		p.last = cursor == "" || size == 0
+	}
+	return true
+}
+
+// FollowersIterator goes through every follower of a channel, newest first.
+//
+//	it := api.Channel.FollowersIter(ctx, channelID)
+//	for it.Next() {
+//		follower := it.Follower()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type FollowersIterator struct {
+	pageIterator
+	page  []*Follower
+	total int
+}
+
+// FollowersIter returns an iterator that requests the next page of
+// followers when the current one runs out.
+func (c *Channel) FollowersIter(ctx context.Context, channelID string) *FollowersIterator {
+	it := &FollowersIterator{}
+	it.pageIterator = pageIterator{
+		ctx:   ctx,
+		index: -1,
+		fetch: func(ctx context.Context, cursor string) (int, string, error) {
+			resp, err := c.followersPage(ctx, channelID, pageSize, cursor)
+			if err != nil {
+				return 0, "", err
+			}
+
+			it.page = resp.Follows
+			it.total = resp.Total
+			return len(resp.Follows), resp.Pagination.Cursor, nil
+		},
+	}
+	return it
+}
+
+// Next moves to the next follower, it returns false when there are no more
+// followers or a request failed.
+func (it *FollowersIterator) Next() bool {
+	return it.next()
+}
+
+func (it *FollowersIterator) Follower() *Follower {
+	return it.page[it.index]
+}
+
+// Total is how many followers the channel has, it is set after the first
+// call to Next.
+func (it *FollowersIterator) Total() int {
+	return it.total
+}
+
+func (it *FollowersIterator) Err() error {
+	return it.err
+}
+
+// Collect returns up to max followers, every follower when max is zero.
+func (it *FollowersIterator) Collect(max int) ([]*Follower, error) {
+	followers := make([]*Follower, 0)
+
+	for (max <= 0 || len(followers) < max) && it.Next() {
+		followers = append(followers, it.Follower())
+	}
+	return followers, it.Err()
+}
+
+// SubscribersIterator goes through every subscription of a channel.
+type SubscribersIterator struct {
+	pageIterator
+	page  []*Subscription
+	total int
+}
+
+// SubscribersIter returns an iterator that requests the next page of
+// subscriptions when the current one runs out.
+func (c *Channel) SubscribersIter(ctx context.Context, channelID string) *SubscribersIterator {
+	it := &SubscribersIterator{}
+	it.pageIterator = pageIterator{
+		ctx:   ctx,
+		index: -1,
+		fetch: func(ctx context.Context, cursor string) (int, string, error) {
+			resp, err := c.subscribersPage(ctx, channelID, pageSize, cursor)
+			if err != nil {
+				return 0, "", err
+			}
+
+			it.page = resp.Subscriptions
+			it.total = resp.Total
+			return len(resp.Subscriptions), resp.Pagination.Cursor, nil
+		},
+	}
+	return it
+}
+
+// Next moves to the next subscription, it returns false when there are no
+// more subscriptions or a request failed.
+func (it *SubscribersIterator) Next() bool {
+	return it.next()
+}
+
+func (it *SubscribersIterator) Subscription() *Subscription {
+	return it.page[it.index]
+}
+
+// Total is how many subscriptions the channel has, it is set after the
+// first call to Next.
+func (it *SubscribersIterator) Total() int {
+	return it.total
+}
+
+func (it *SubscribersIterator) Err() error {
+	return it.err
+}
+
+// Collect returns up to max subscriptions, every subscription when max is
+// zero.
+func (it *SubscribersIterator) Collect(max int) ([]*Subscription, error) {
+	subscriptions := make([]*Subscription, 0)
+
+	for (max <= 0 || len(subscriptions) < max) && it.Next() {
+		subscriptions = append(subscriptions, it.Subscription())
+	}
+	return subscriptions, it.Err()
+}
@@ -0,0 +1,177 @@
+package twitch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+// newPagingServer serves total followers and subscriptions in pages using
+// the index of the next item as cursor, failAfter breaks the page with
+// that cursor.
+func newPagingServer(t *testing.T, total int, failAfter string) (*twitch.API, *int32) {
+	var requests int32
+
+	handler := func(key string) http.HandlerFunc {
+		return func(rw http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			query := req.URL.Query()
+
+			if query.Get("broadcaster_id") != "558843277" {
+				t.Errorf("broadcaster id doesn't match got: %s", query.Get("broadcaster_id"))
+			}
+
+			if query.Get("first") != "100" {
+				t.Errorf("page size doesn't match want: 100, got: %s", query.Get("first"))
+			}
+
+			cursor := query.Get("after")
+			if failAfter != "" && cursor == failAfter {
+				http.Error(rw, "", http.StatusNotFound)
+				return
+			}
+
+			start, _ := strconv.Atoi(cursor)
+			end := start + 100
+			if end > total {
+				end = total
+			}
+
+			data := make([]map[string]string, 0, end-start)
+			for i := start; i < end; i++ {
+				data = append(data, map[string]string{
+					"user_id":   strconv.Itoa(i),
+					"user_name": fmt.Sprintf("user%d", i),
+				})
+			}
+
+			pagination := map[string]string{}
+			if end < total {
+				pagination["cursor"] = strconv.Itoa(end)
+			}
+
+			json.NewEncoder(rw).Encode(map[string]interface{}{
+				key:          data,
+				"total":      total,
+				"pagination": pagination,
+			})
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/channels/followers", handler("data"))
+	mux.HandleFunc("/helix/subscriptions", handler("data"))
+
+	api, _ := util.TestCreateClientWithMux(t, mux, nil)
+	return api, &requests
+}
+
+func TestFollowersIter(t *testing.T) {
+	api, requests := newPagingServer(t, 250, "")
+	it := api.Channel.FollowersIter(context.Background(), "558843277")
+
+	count := 0
+	for it.Next() {
+		follower := it.Follower()
+
+		if follower.UserID != strconv.Itoa(count) {
+			t.Fatalf("follower doesn't match want: %d, got: %s", count, follower.UserID)
+		}
+		count++
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatalf("failed to iterate followers with %s", err)
+	}
+
+	if count != 250 {
+		t.Errorf("followers don't match want: 250, got: %d", count)
+	}
+
+	if it.Total() != 250 {
+		t.Errorf("total doesn't match want: 250, got: %d", it.Total())
+	}
+
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Errorf("requests don't match want: 3, got: %d", got)
+	}
+
+	if it.Next() {
+		t.Error("Next should keep returning false after the last page")
+	}
+}
+
+func TestFollowersCollect(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		total        int
+		max          int
+		want         int
+		wantRequests int32
+	}{
+		{"everything", 250, 0, 250, 3},
+		{"capped", 250, 150, 150, 2},
+		{"cap on page boundary", 250, 100, 100, 1},
+		{"empty channel", 0, 0, 0, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			api, requests := newPagingServer(t, test.total, "")
+
+			followers, err := api.Channel.FollowersIter(context.Background(), "558843277").Collect(test.max)
+			if err != nil {
+				t.Fatalf("failed to collect followers with %s", err)
+			}
+
+			if len(followers) != test.want {
+				t.Errorf("followers don't match want: %d, got: %d", test.want, len(followers))
+			}
+
+			if got := atomic.LoadInt32(requests); got != test.wantRequests {
+				t.Errorf("requests don't match want: %d, got: %d", test.wantRequests, got)
+			}
+		})
+	}
+}
+
+func TestSubscribersIterError(t *testing.T) {
+	api, _ := newPagingServer(t, 250, "100")
+
+	subscriptions, err := api.Channel.SubscribersIter(context.Background(), "558843277").Collect(0)
+
+	if !errors.Is(err, twitch.ErrNotFound) {
+		t.Fatalf("expected the error from the second page, got: %v", err)
+	}
+
+	if len(subscriptions) != 100 {
+		t.Errorf("subscriptions from the first page should be returned, got: %d", len(subscriptions))
+	}
+}
+
+func TestFollowersIterEmptyPage(t *testing.T) {
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/channels/followers", func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(rw, `{"total":0,"data":[],"pagination":{"cursor":"next"}}`)
+	})
+
+	api, _ := util.TestCreateClientWithMux(t, mux, nil)
+
+	followers, err := api.Channel.FollowersIter(context.Background(), "558843277").Collect(0)
+	if err != nil || len(followers) != 0 {
+		t.Fatalf("expected no followers got: %d, %v", len(followers), err)
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("an empty page should be the last one, requests: %d", got)
+	}
+}
@@ -0,0 +1,241 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 3
+	defaultRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 30 * time.Second
+)
+
+// Errors for responses from Twitch, match them with errors.Is since they
+// are wrapped in an APIError.
+var (
+	ErrUnauthorized = errors.New("twitch: unauthorized")
+	ErrRateLimited  = errors.New("twitch: rate limited")
+	ErrNotFound     = errors.New("twitch: not found")
+)
+
+// APIError is returned when Twitch responds with an error status code.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("twitch responded with status code %d - %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// tokenInvalidator is implemented by transports that can get a new token
+// when Twitch rejects the current one.
+type tokenInvalidator interface {
+	invalidate()
+}
+
+// rateLimit tracks the Helix rate limit bucket from the Ratelimit headers
+// so requests wait for the reset instead of being rejected.
+type rateLimit struct {
+	sync.Mutex
+	remaining int
+	reset     time.Time
+}
+
+func (r *rateLimit) update(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.remaining = remaining
+	r.reset = time.Unix(reset, 0)
+}
+
+// delay returns how long to wait before the bucket has points again.
+func (r *rateLimit) delay() time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.remaining > 0 || r.reset.IsZero() {
+		return 0
+	}
+
+	if d := time.Until(r.reset); d > 0 {
+		return d
+	}
+	return 0
+}
+
+type request struct {
+	client      *http.Client
+	method      string
+	path        string
+	url         *url.URL
+	queryParams map[string]string
+	headers     map[string]string
+	body        []byte
+}
+
+// handleRequest sends the request retrying rate limits and the server
+// errors of idempotent requests with backoff, and retrying once with a new
+// token when the token was rejected.
+func (c *API) handleRequest(ctx context.Context, req *request) (*http.Response, error) {
+	client := req.client
+	if client == nil {
+		client = c.client
+	}
+
+	refreshedToken := false
+
+	for attempt := 0; ; attempt++ {
+		if err := sleep(ctx, c.limits.delay()); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.send(ctx, client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		c.limits.update(resp.Header)
+
+		// creating EventSub subscriptions responds with 202 and deleting
+		// them with 204
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			return resp, nil
+		}
+
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: string(b)}
+
+		var delay time.Duration
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			invalidator, ok := client.Transport.(tokenInvalidator)
+			if !ok || refreshedToken {
+				return nil, apiErr
+			}
+
+			invalidator.invalidate()
+			refreshedToken = true
+			continue
+		case resp.StatusCode == http.StatusTooManyRequests:
+			delay = c.limits.delay()
+		case resp.StatusCode >= http.StatusInternalServerError && isIdempotent(req.method):
+		default:
+			return nil, apiErr
+		}
+
+		if attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		if delay == 0 {
+			delay = c.retryDelay(attempt)
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isIdempotent reports whether a request can be sent again after a server
+// error, Twitch could have already created the reward or subscription of a
+// POST or changed the state of a PATCH before failing.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (c *API) send(ctx context.Context, client *http.Client, req *request) (*http.Response, error) {
+	u := *req.url
+	q := u.Query()
+
+	for key, val := range req.queryParams {
+		q.Set(key, val)
+	}
+
+	u.RawQuery = q.Encode()
+	u.Path = req.path
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(req.body))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request with %w", err)
+	}
+
+	httpReq.Header.Add("Client-Id", c.clientID)
+
+	for key, val := range req.headers {
+		httpReq.Header.Add(key, val)
+	}
+
+	resp, err := client.Do(httpReq)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request with %w", err)
+	}
+	return resp, nil
+}
+
+// retryDelay doubles the delay on every attempt.
+func (c *API) retryDelay(attempt int) time.Duration {
+	delay := c.baseRetryDelay << uint(attempt)
+
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,192 @@
+package twitch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/cache"
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+// newRetryServer answers /helix/users with the status codes in order and
+// with 200 once they run out.
+func newRetryServer(t *testing.T, statusCodes ...int) (*twitch.API, *int32, *int32) {
+	var requests, tokenRequests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(rw http.ResponseWriter, req *http.Request) {
+		count := atomic.AddInt32(&tokenRequests, 1)
+		fmt.Fprintf(rw, `{"access_token":"app-token-%d","expires_in":3600}`, count)
+	})
+
+	mux.HandleFunc("/helix/users", func(rw http.ResponseWriter, req *http.Request) {
+		count := int(atomic.AddInt32(&requests, 1))
+
+		if count <= len(statusCodes) {
+			status := statusCodes[count-1]
+
+			if status == http.StatusTooManyRequests {
+				rw.Header().Set("Ratelimit-Remaining", "0")
+				rw.Header().Set("Ratelimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			}
+			http.Error(rw, http.StatusText(status), status)
+			return
+		}
+
+		rw.Header().Set("Ratelimit-Remaining", "799")
+		rw.Header().Set("Ratelimit-Reset", strconv.FormatInt(time.Now().Unix()+60, 10))
+		fmt.Fprint(rw, `{"data":[{"id":"239246205","login":"attackkopter","display_name":"AttackKopter"}]}`)
+	})
+
+	api, _ := util.TestCreateClientWithMux(t, mux, func(conf *twitch.Config) {
+		conf.MaxRetries = 2
+		conf.RetryDelay = time.Millisecond
+		conf.Timeout = time.Second
+	})
+	return api, &requests, &tokenRequests
+}
+
+func TestRequestRetries(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		statusCodes       []int
+		wantErr           error
+		wantStatus        int
+		wantRequests      int32
+		wantTokenRequests int32
+	}{
+		{"success", nil, nil, 0, 1, 1},
+		{"server errors are retried", []int{500, 503}, nil, 0, 3, 1},
+		{"gives up after max retries", []int{502, 502, 502}, nil, 502, 3, 1},
+		{"rate limits are retried", []int{429}, nil, 0, 2, 1},
+		{"rate limited after max retries", []int{429, 429, 429}, twitch.ErrRateLimited, 429, 3, 1},
+		{"unauthorized gets a new token", []int{401}, nil, 0, 2, 2},
+		{"unauthorized is only retried once", []int{401, 401}, twitch.ErrUnauthorized, 401, 2, 2},
+		{"not found isn't retried", []int{404}, twitch.ErrNotFound, 404, 1, 1},
+		{"bad requests aren't retried", []int{400}, nil, 400, 1, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			api, requests, tokenRequests := newRetryServer(t, test.statusCodes...)
+
+			user, err := api.GetUser(context.Background(), "239246205")
+
+			if test.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("failed to get user with %s", err)
+				}
+
+				if user.DisplayName != "AttackKopter" {
+					t.Errorf("display name doesn't match want: AttackKopter, got: %s", user.DisplayName)
+				}
+			} else {
+				var apiErr *twitch.APIError
+
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("expected an APIError, got: %v", err)
+				}
+
+				if apiErr.StatusCode != test.wantStatus {
+					t.Errorf("status code doesn't match want: %d, got: %d", test.wantStatus, apiErr.StatusCode)
+				}
+			}
+
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("error doesn't match want: %s, got: %v", test.wantErr, err)
+			}
+
+			if got := atomic.LoadInt32(requests); got != test.wantRequests {
+				t.Errorf("requests don't match want: %d, got: %d", test.wantRequests, got)
+			}
+
+			if got := atomic.LoadInt32(tokenRequests); got != test.wantTokenRequests {
+				t.Errorf("token requests don't match want: %d, got: %d", test.wantTokenRequests, got)
+			}
+		})
+	}
+}
+
+func TestRequestContextCanceled(t *testing.T) {
+	api, _, _ := newRetryServer(t, 503, 503)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := api.GetUser(ctx, "239246205")
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, got: %v", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-req.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	api, err := twitch.New(&twitch.Config{
+		TwitchURL:   ts.URL,
+		AuthURL:     ts.URL,
+		RedirectURL: "http://localhost/api/auth",
+		ClientID:    "test_client_id",
+		Secret:      "test_secret",
+		Timeout:     50 * time.Millisecond,
+	}, cache.New())
+
+	if err != nil {
+		t.Fatalf("Failed to create API struct %v", err)
+	}
+
+	start := time.Now()
+	if _, err := api.AppToken(context.Background()); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("request should time out, took %s", elapsed)
+	}
+}
+
+func TestRequestServerErrorNotRetriedForPost(t *testing.T) {
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/helix/channel_points/custom_rewards", func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	})
+
+	api, _ := util.TestCreateClientWithMux(t, mux, func(conf *twitch.Config) {
+		conf.MaxRetries = 2
+		conf.RetryDelay = time.Millisecond
+	})
+
+	_, err := api.Channel.CreateCustomReward(context.Background(), "274637212", &twitch.CustomReward{Title: "hydrate", Cost: 100})
+
+	var apiErr *twitch.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503 APIError, got: %v", err)
+	}
+
+	// the reward could have been created before the server failed
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("requests don't match want: 1, got: %d", got)
+	}
+}
+
+func TestUserNotFoundIsNotFound(t *testing.T) {
+	if !errors.Is(twitch.ErrUserNotFound, twitch.ErrNotFound) {
+		t.Error("ErrUserNotFound should match ErrNotFound")
+	}
+}
@@ -0,0 +1,115 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	customRewardsPath     = "/helix/channel_points/custom_rewards"
+	rewardRedemptionsPath = "/helix/channel_points/custom_rewards/redemptions"
+)
+
+// Redemption statuses, only rewards created with the same client ID can
+// have their redemptions fulfilled or canceled.
+const (
+	RedemptionUnfulfilled = "UNFULFILLED"
+	RedemptionFulfilled   = "FULFILLED"
+	RedemptionCanceled    = "CANCELED"
+)
+
+type CustomReward struct {
+	ID                                string `json:"id,omitempty"`
+	Title                             string `json:"title,omitempty"`
+	Prompt                            string `json:"prompt,omitempty"`
+	Cost                              int    `json:"cost,omitempty"`
+	BackgroundColor                   string `json:"background_color,omitempty"`
+	IsEnabled                         *bool  `json:"is_enabled,omitempty"`
+	IsPaused                          *bool  `json:"is_paused,omitempty"`
+	IsUserInputRequired               bool   `json:"is_user_input_required,omitempty"`
+	ShouldRedemptionsSkipRequestQueue bool   `json:"should_redemptions_skip_request_queue,omitempty"`
+}
+
+type customRewardsResponse struct {
+	Data []*CustomReward `json:"data"`
+}
+
+// Redemption is a viewer spending channel points on a custom reward, both
+// Helix and EventSub use the same fields.
+type Redemption struct {
+	ID         string        `json:"id"`
+	UserID     string        `json:"user_id"`
+	UserLogin  string        `json:"user_login"`
+	UserName   string        `json:"user_name"`
+	UserInput  string        `json:"user_input"`
+	Status     string        `json:"status"`
+	RedeemedAt string        `json:"redeemed_at"`
+	Reward     *CustomReward `json:"reward"`
+}
+
+type redemptionsResponse struct {
+	Data []*Redemption `json:"data"`
+}
+
+// CustomRewards returns the rewards of the channel, onlyManageable limits
+// them to the rewards created by this client.
+func (c *Channel) CustomRewards(ctx context.Context, channelID string, onlyManageable bool) ([]*CustomReward, error) {
+	queryParam := map[string]string{"broadcaster_id": channelID}
+	if onlyManageable {
+		queryParam["only_manageable_rewards"] = "true"
+	}
+
+	responseData := &customRewardsResponse{}
+	err := c.api.get(ctx, c.api.authClient, customRewardsPath, queryParam, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom rewards with %w", err)
+	}
+	return responseData.Data, nil
+}
+
+// CreateCustomReward creates a reward, Title and Cost are required.
+func (c *Channel) CreateCustomReward(ctx context.Context, channelID string, reward *CustomReward) (*CustomReward, error) {
+	return c.saveCustomReward(ctx, "POST", map[string]string{"broadcaster_id": channelID}, reward)
+}
+
+// UpdateCustomReward changes the fields of the reward that are set.
+func (c *Channel) UpdateCustomReward(ctx context.Context, channelID, rewardID string, reward *CustomReward) (*CustomReward, error) {
+	return c.saveCustomReward(ctx, "PATCH", map[string]string{"broadcaster_id": channelID, "id": rewardID}, reward)
+}
+
+func (c *Channel) saveCustomReward(ctx context.Context, method string, query map[string]string, reward *CustomReward) (*CustomReward, error) {
+	responseData := &customRewardsResponse{}
+	err := c.api.do(ctx, c.api.authClient, method, customRewardsPath, query, reward, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save custom reward with %w", err)
+	}
+
+	if len(responseData.Data) == 0 {
+		return nil, fmt.Errorf("failed to save custom reward with empty response")
+	}
+	return responseData.Data[0], nil
+}
+
+// UpdateRedemptionStatus marks a redemption as FULFILLED or CANCELED,
+// canceling refunds the channel points.
+func (c *Channel) UpdateRedemptionStatus(ctx context.Context, channelID, rewardID, redemptionID, status string) (*Redemption, error) {
+	queryParam := map[string]string{
+		"broadcaster_id": channelID,
+		"reward_id":      rewardID,
+		"id":             redemptionID,
+	}
+
+	responseData := &redemptionsResponse{}
+	err := c.api.do(ctx, c.api.authClient, "PATCH", rewardRedemptionsPath, queryParam, map[string]string{"status": status}, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update redemption %s with %w", redemptionID, err)
+	}
+
+	if len(responseData.Data) == 0 {
+		return nil, fmt.Errorf("failed to update redemption %s with empty response", redemptionID)
+	}
+	return responseData.Data[0], nil
+}
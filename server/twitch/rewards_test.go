@@ -0,0 +1,92 @@
+package twitch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitch"
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+func TestCustomRewards(t *testing.T) {
+	channelID := "274637212"
+	queryParams := map[string]string{
+		"broadcaster_id":          channelID,
+		"only_manageable_rewards": "true",
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "custom_rewards_response", "/helix/channel_points/custom_rewards", channelID, queryParams, nil, 3600)
+	defer ts.Close()
+
+	rewards, err := api.Channel.CustomRewards(context.Background(), channelID, true)
+	if err != nil {
+		t.Fatalf("failed to get custom rewards with %s", err)
+	}
+
+	if len(rewards) != 1 {
+		t.Fatalf("rewards don't match want: 1, got: %d", len(rewards))
+	}
+
+	reward := rewards[0]
+	if reward.ID != "92af127c-7326-4483-a52b-b0da0be61c01" || reward.Title != "game analysis" || reward.Cost != 50000 {
+		t.Errorf("reward doesn't match got: %+v", reward)
+	}
+
+	if reward.IsEnabled == nil || !*reward.IsEnabled {
+		t.Error("reward should be enabled")
+	}
+}
+
+func TestUpdateCustomReward(t *testing.T) {
+	channelID := "274637212"
+	queryParams := map[string]string{
+		"broadcaster_id": channelID,
+		"id":             "92af127c-7326-4483-a52b-b0da0be61c01",
+	}
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "custom_rewards_response", "/helix/channel_points/custom_rewards", channelID, queryParams, headers, 3600)
+	defer ts.Close()
+
+	paused := false
+	reward, err := api.Channel.UpdateCustomReward(context.Background(), channelID, "92af127c-7326-4483-a52b-b0da0be61c01", &twitch.CustomReward{IsPaused: &paused})
+
+	if err != nil {
+		t.Fatalf("failed to update custom reward with %s", err)
+	}
+
+	if reward.Title != "game analysis" {
+		t.Errorf("reward doesn't match got: %+v", reward)
+	}
+}
+
+func TestUpdateRedemptionStatus(t *testing.T) {
+	channelID := "274637212"
+	queryParams := map[string]string{
+		"broadcaster_id": channelID,
+		"reward_id":      "92af127c-7326-4483-a52b-b0da0be61c01",
+		"id":             "17fa2df1-ad76-4804-bfa5-a40ef63efe63",
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "redemption_response", "/helix/channel_points/custom_rewards/redemptions", channelID, queryParams, nil, 3600)
+	defer ts.Close()
+
+	redemption, err := api.Channel.UpdateRedemptionStatus(
+		context.Background(),
+		channelID,
+		"92af127c-7326-4483-a52b-b0da0be61c01",
+		"17fa2df1-ad76-4804-bfa5-a40ef63efe63",
+		twitch.RedemptionFulfilled,
+	)
+
+	if err != nil {
+		t.Fatalf("failed to update redemption with %s", err)
+	}
+
+	if redemption.Status != twitch.RedemptionFulfilled {
+		t.Errorf("status doesn't match want: %s, got: %s", twitch.RedemptionFulfilled, redemption.Status)
+	}
+
+	if redemption.Reward == nil || redemption.Reward.Title != "game analysis" {
+		t.Errorf("reward doesn't match got: %+v", redemption.Reward)
+	}
+}
@@ -0,0 +1,47 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const streamsPath = "/helix/streams"
+
+// Stream is a live broadcast, Twitch only returns streams that are live.
+type Stream struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	UserLogin    string    `json:"user_login"`
+	UserName     string    `json:"user_name"`
+	GameID       string    `json:"game_id"`
+	GameName     string    `json:"game_name"`
+	Type         string    `json:"type"`
+	Title        string    `json:"title"`
+	ViewerCount  int       `json:"viewer_count"`
+	StartedAt    time.Time `json:"started_at"`
+	Language     string    `json:"language"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Tags         []string  `json:"tags"`
+}
+
+type streamsResponse struct {
+	Data       []*Stream  `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// GetStream returns the live stream of the channel, or nil when the channel
+// is offline.
+func (c *Channel) GetStream(ctx context.Context, channelID string) (*Stream, error) {
+	responseData := &streamsResponse{}
+	err := c.api.get(ctx, c.api.appClient, streamsPath, map[string]string{"user_id": channelID}, responseData)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream with %w", err)
+	}
+
+	if len(responseData.Data) == 0 {
+		return nil, nil
+	}
+	return responseData.Data[0], nil
+}
@@ -0,0 +1,50 @@
+package twitch_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/twitch/util"
+)
+
+func TestGetStream(t *testing.T) {
+	channelID := "141981764"
+	queryParams := map[string]string{
+		"user_id": channelID,
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "streams_response", "/helix/streams", channelID, queryParams, nil, 3600)
+	defer ts.Close()
+
+	s, err := api.Channel.GetStream(context.Background(), channelID)
+	if err != nil {
+		t.Fatalf("failed to get stream with %s", err)
+	}
+
+	if s == nil {
+		t.Fatal("expected a live stream")
+	}
+
+	startedAt := time.Date(2021, 3, 10, 15, 4, 21, 0, time.UTC)
+	if s.ViewerCount != 78365 || !s.StartedAt.Equal(startedAt) || s.GameName != "Science & Technology" {
+		t.Errorf("stream doesn't match got: %+v", s)
+	}
+}
+
+func TestGetStreamOffline(t *testing.T) {
+	channelID := "141981764"
+	queryParams := map[string]string{
+		"user_id": channelID,
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "streams_offline_response", "/helix/streams", channelID, queryParams, nil, 3600)
+	defer ts.Close()
+
+	s, err := api.Channel.GetStream(context.Background(), channelID)
+	if err != nil {
+		t.Fatalf("failed to get stream with %s", err)
+	}
+
+	if s != nil {
+		t.Errorf("expected offline stream got: %+v", s)
+	}
+}
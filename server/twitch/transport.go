@@ -1,6 +1,7 @@
 package twitch
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
@@ -11,30 +12,34 @@ import (
 
 type transport struct {
 	sync.Mutex
-	api   *API
-	cache *cache.Cache
-	tr    http.RoundTripper
+	api          *API
+	cache        *cache.Cache
+	tr           http.RoundTripper
+	forceRefresh bool
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	token, err := t.token()
+	token, err := t.token(req.Context())
 
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("OAuth %s", token))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	return t.tr.RoundTrip(req)
 }
 
-func (t *transport) token() (string, error) {
+// invalidate refreshes the user token on the next request, Twitch can
+// revoke tokens before they expire.
+func (t *transport) invalidate() {
 	t.Lock()
 	defer t.Unlock()
-	expiresAtStr, err := t.cache.Get(cache.UserAccessExpiresAt)
-	if err != nil {
-		return "", err
-	}
+	t.forceRefresh = true
+}
 
-	expiresAt, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", expiresAtStr)
+func (t *transport) token(ctx context.Context) (string, error) {
+	t.Lock()
+	defer t.Unlock()
+	expiresAt, err := t.cache.GetTime(cache.UserAccessExpiresAt)
 	if err != nil {
 		return "", err
 	}
@@ -44,16 +49,17 @@ func (t *transport) token() (string, error) {
 		return "", err
 	}
 
-	if expiresAt.Add(-time.Minute).Before(time.Now()) {
+	if t.forceRefresh || expiresAt.Add(-time.Minute).Before(time.Now()) {
 		refreshToken, err := t.cache.Get(cache.UserRefreshCode)
 		if err != nil {
 			return "", err
 		}
-		resp, err := t.api.AuthTokenRefresh(refreshToken)
+		resp, err := t.api.AuthTokenRefresh(ctx, refreshToken)
 
 		if err != nil {
 			return "", fmt.Errorf("failed to refresh token with %w", err)
 		}
+		t.forceRefresh = false
 		token = resp.AccessToken
 		t.cache.SetAccessToken(token, resp.RefreshToken, resp.ExpiresIn)
 	}
@@ -1,38 +1,52 @@
 package twitch
 
-//TODO: Add timeout to http clients to prevent requests blocking forever
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/miguel250/streaming-setup/server/cache"
 )
 
 const (
-	channelPath       = "/kraken/channels"
-	userPath          = "/kraken/users"
-	channelFollows    = "/follows"
-	globalBadgesPath  = "/v1/badges/global/display"
-	channelBadgesPath = "/v1/badges/channels"
-	authPath          = "/oauth2/token"
+	usersPath              = "/helix/users"
+	followersPath          = "/helix/channels/followers"
+	subscriptionsPath      = "/helix/subscriptions"
+	globalBadgesPath       = "/helix/chat/badges/global"
+	channelBadgesPath      = "/helix/chat/badges"
+	globalEmotesPath       = "/helix/chat/emotes/global"
+	channelEmotesPath      = "/helix/chat/emotes"
+	cheermotesPath         = "/helix/bits/cheermotes"
+	authPath               = "/oauth2/token"
+	authorizePath          = "/oauth2/authorize"
+	followersScope         = "moderator:read:followers"
+	readSubscriptionsScope = "channel:read:subscriptions"
+	readBitsScope          = "bits:read"
+	manageRedemptionsScope = "channel:manage:redemptions"
+	manageBroadcastScope   = "channel:manage:broadcast"
+	manageChatScope        = "moderator:manage:chat_settings"
 )
 
 type API struct {
 	client      *http.Client
 	authClient  *http.Client
+	appClient   *http.Client
 	clientID    string
 	secret      string
 	url         *url.URL
 	authURL     *url.URL
-	badgeURL    *url.URL
 	redirectURL *url.URL
 	Channel     *Channel
+
+	limits         *rateLimit
+	maxRetries     int
+	baseRetryDelay time.Duration
 }
 
 type Channel struct {
@@ -45,33 +59,52 @@ type AuthResponse struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
+// Pagination has the cursor for the next page of a Helix response, Cursor
+// is empty on the last page.
+type Pagination struct {
+	Cursor string `json:"cursor"`
+}
+
 type User struct {
-	ID          string `json:"_id"`
-	DisplayName string `json:"display_name"`
-	Logo        string `json:"logo"`
+	ID              string `json:"id"`
+	Login           string `json:"login"`
+	DisplayName     string `json:"display_name"`
+	ProfileImageURL string `json:"profile_image_url"`
 }
 
-func (api *API) AuthUser(code string) (*AuthResponse, error) {
-	return api.auth(code, "authorization_code")
+type usersResponse struct {
+	Data []*User `json:"data"`
 }
 
-func (api *API) AuthTokenRefresh(refreshToken string) (*AuthResponse, error) {
-	return api.auth(refreshToken, "refresh_token")
+func (api *API) AuthUser(ctx context.Context, code string) (*AuthResponse, error) {
+	return api.auth(ctx, code, "authorization_code")
 }
 
-func (api *API) auth(code string, grantType string) (*AuthResponse, error) {
+func (api *API) AuthTokenRefresh(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	return api.auth(ctx, refreshToken, "refresh_token")
+}
+
+// AppToken gets an app access token with the client credentials flow, it
+// can only be used for endpoints that don't need user authorization.
+func (api *API) AppToken(ctx context.Context) (*AuthResponse, error) {
+	return api.auth(ctx, "", "client_credentials")
+}
+
+func (api *API) auth(ctx context.Context, code string, grantType string) (*AuthResponse, error) {
 	queryParams := map[string]string{
 		"client_id":     api.clientID,
 		"client_secret": api.secret,
 		"grant_type":    grantType,
-		"redirect_uri":  api.redirectURL.String(),
 	}
 
 	switch grantType {
 	case "authorization_code":
 		queryParams["code"] = code
+		queryParams["redirect_uri"] = api.redirectURL.String()
 	case "refresh_token":
 		queryParams["refresh_token"] = code
+		queryParams["redirect_uri"] = api.redirectURL.String()
+	case "client_credentials":
 	default:
 		return nil, fmt.Errorf("invalid grant type %s", grantType)
 	}
@@ -83,7 +116,7 @@ func (api *API) auth(code string, grantType string) (*AuthResponse, error) {
 		queryParams: queryParams,
 	}
 
-	resp, err := api.handleRequest(req)
+	resp, err := api.handleRequest(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get access token with %w", err)
 	}
@@ -92,13 +125,13 @@ func (api *API) auth(code string, grantType string) (*AuthResponse, error) {
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse body for followers with %w", err)
+		return nil, fmt.Errorf("failed to read token response with %w", err)
 	}
 
 	authResp := &AuthResponse{}
 	err = json.Unmarshal(body, authResp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse json with %w", err)
+		return nil, fmt.Errorf("failed to parse token response with %w", err)
 	}
 
 	return authResp, nil
@@ -111,118 +144,143 @@ func (api *API) AuthURL() string {
 	q.Set("response_type", "code")
 	q.Set("client_id", api.clientID)
 	q.Set("redirect_uri", api.redirectURL.String())
-	q.Set("scope", strings.Join([]string{"channel_subscriptions", "channel_read"}, " "))
+	q.Set("scope", strings.Join([]string{
+		readSubscriptionsScope,
+		followersScope,
+		readBitsScope,
+		manageRedemptionsScope,
+		manageBroadcastScope,
+		manageChatScope,
+	}, " "))
 
 	u.RawQuery = q.Encode()
-	u.Path = "/oauth2/authorize"
+	u.Path = authorizePath
 	return u.String()
 }
 
-func (api *API) GetUser(id string) (*User, error) {
-	path := fmt.Sprintf("%s/%s", userPath, id)
-
-	resp, err := api.makeGetRequest(api.url, path, nil)
+func (api *API) GetUser(ctx context.Context, id string) (*User, error) {
+	users := &usersResponse{}
+	err := api.get(ctx, api.appClient, usersPath, map[string]string{"id": id}, users)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to twitch with %s", err)
+		return nil, fmt.Errorf("failed to get user with %w", err)
 	}
 
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse body for followers with %w", err)
+	if len(users.Data) == 0 {
+		return nil, fmt.Errorf("failed to get user %s with %w", id, ErrUserNotFound)
 	}
 
-	user := &User{}
-	err = json.Unmarshal(body, user)
+	return users.Data[0], nil
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse json for followers with %w", err)
-	}
+type FollowersResponse struct {
+	Total      int         `json:"total"`
+	Follows    []*Follower `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
 
-	return user, nil
+type Follower struct {
+	UserID     string `json:"user_id"`
+	UserLogin  string `json:"user_login"`
+	UserName   string `json:"user_name"`
+	FollowedAt string `json:"followed_at"`
 }
 
-type TwitchChannelResponse struct {
-	Total   int        `json:"_total"`
-	Follows []UserInfo `json:"follows"`
+// User returns the follower as a User, followers don't include the
+// profile image.
+func (f *Follower) User() *User {
+	return &User{
+		ID:          f.UserID,
+		Login:       f.UserLogin,
+		DisplayName: f.UserName,
+	}
 }
 
-type UserInfo struct {
-	User User `json:"user"`
+// Followers returns the newest followers of the channel first, it needs a
+// user token with the moderator:read:followers scope.
+func (c *Channel) Followers(ctx context.Context, channelID string, limit int) (*FollowersResponse, error) {
+	return c.followersPage(ctx, channelID, limit, "")
 }
 
-func (c *Channel) Followers(channelID string, limit int) (*TwitchChannelResponse, error) {
-	path := fmt.Sprintf("%s/%s%s", channelPath, channelID, channelFollows)
+func (c *Channel) followersPage(ctx context.Context, channelID string, limit int, cursor string) (*FollowersResponse, error) {
 	queryParam := map[string]string{
-		"limit": strconv.Itoa(limit),
+		"broadcaster_id": channelID,
+		"first":          strconv.Itoa(limit),
 	}
 
-	resp, err := c.api.makeGetRequest(c.api.url, path, queryParam)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request to twitch with %s", err)
+	if cursor != "" {
+		queryParam["after"] = cursor
 	}
 
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse body for followers with %w", err)
-	}
+	responseData := &FollowersResponse{}
+	err := c.api.get(ctx, c.api.authClient, followersPath, queryParam, responseData)
 
-	responseData := &TwitchChannelResponse{}
-	err = json.Unmarshal(body, responseData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse json for followers with %w", err)
+		return nil, fmt.Errorf("failed to get followers with %w", err)
 	}
 
 	return responseData, nil
 }
 
 type SubscribersResponse struct {
-	Total         int `json:"_total"`
-	Subscriptions []*UserInfo
+	Total         int             `json:"total"`
+	Subscriptions []*Subscription `json:"data"`
+	Pagination    Pagination      `json:"pagination"`
 }
 
-func (c *Channel) Subscribers(channelID string, limit int) (*SubscribersResponse, error) {
-	path := fmt.Sprintf("%s/%s/subscriptions", channelPath, channelID)
-	req := &request{
-		client: c.api.authClient,
-		method: "GET",
-		url:    c.api.url,
-		path:   path,
-		queryParams: map[string]string{
-			"limit":     strconv.Itoa(limit),
-			"direction": "desc",
-		},
-	}
+type Subscription struct {
+	UserID     string `json:"user_id"`
+	UserLogin  string `json:"user_login"`
+	UserName   string `json:"user_name"`
+	Tier       string `json:"tier"`
+	PlanName   string `json:"plan_name"`
+	IsGift     bool   `json:"is_gift"`
+	GifterID   string `json:"gifter_id"`
+	GifterName string `json:"gifter_name"`
+}
 
-	resp, err := c.api.handleRequest(req)
-	if err != nil {
-		return nil, fmt.Errorf("twitch failed to make request with %w", err)
+// User returns the subscriber as a User.
+func (s *Subscription) User() *User {
+	return &User{
+		ID:          s.UserID,
+		Login:       s.UserLogin,
+		DisplayName: s.UserName,
 	}
+}
 
-	defer resp.Body.Close()
+// Subscribers returns the subscriptions of the channel, it needs a user
+// token with the channel:read:subscriptions scope.
+func (c *Channel) Subscribers(ctx context.Context, channelID string, limit int) (*SubscribersResponse, error) {
+	return c.subscribersPage(ctx, channelID, limit, "")
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse body for subscriptions with %w", err)
+func (c *Channel) subscribersPage(ctx context.Context, channelID string, limit int, cursor string) (*SubscribersResponse, error) {
+	queryParam := map[string]string{
+		"broadcaster_id": channelID,
+		"first":          strconv.Itoa(limit),
+	}
+
+	if cursor != "" {
+		queryParam["after"] = cursor
 	}
 
 	subResp := &SubscribersResponse{}
-	err = json.Unmarshal(body, subResp)
+	err := c.api.get(ctx, c.api.authClient, subscriptionsPath, queryParam, subResp)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response for subscriptions with %w", err)
+		return nil, fmt.Errorf("failed to get subscriptions with %w", err)
 	}
 
 	return subResp, nil
 }
 
 type BadgesResponse struct {
-	BadgeSet map[string]*BadgeVersion `json:"badge_sets"`
+	Data []*BadgeSet `json:"data"`
+}
+
+type BadgeSet struct {
+	SetID    string   `json:"set_id"`
+	Versions []*Badge `json:"versions"`
 }
 
 type BadgeVersion struct {
@@ -230,104 +288,184 @@ type BadgeVersion struct {
 }
 
 type Badge struct {
+	ID      string `json:"id"`
 	Title   string `json:"title"`
 	Image1X string `json:"image_url_1x"`
 	Image2X string `json:"image_url_2x"`
 	Image4X string `json:"image_url_4x"`
 }
 
-func (c *Channel) GetBadges(channelID string) (map[string]*BadgeVersion, error) {
-	path := fmt.Sprintf("%s/%s/%s", channelBadgesPath, channelID, "display")
-	return getBadges(c.api, path)
+// BadgeSets returns the badges by set and version like they are sent in
+// the badges tag of chat messages, e.g. subscriber/12.
+func (r *BadgesResponse) BadgeSets() map[string]*BadgeVersion {
+	sets := make(map[string]*BadgeVersion, len(r.Data))
+
+	for _, set := range r.Data {
+		versions := make(map[string]*Badge, len(set.Versions))
+
+		for _, badge := range set.Versions {
+			versions[badge.ID] = badge
+		}
+		sets[set.SetID] = &BadgeVersion{Versions: versions}
+	}
+	return sets
 }
 
-func (api *API) GetGlobalBadges() (map[string]*BadgeVersion, error) {
-	return getBadges(api, globalBadgesPath)
+func (c *Channel) GetBadges(ctx context.Context, channelID string) (map[string]*BadgeVersion, error) {
+	return getBadges(ctx, c.api, channelBadgesPath, map[string]string{"broadcaster_id": channelID})
 }
 
-func getBadges(api *API, path string) (map[string]*BadgeVersion, error) {
+func (api *API) GetGlobalBadges(ctx context.Context) (map[string]*BadgeVersion, error) {
+	return getBadges(ctx, api, globalBadgesPath, nil)
+}
+
+func getBadges(ctx context.Context, api *API, path string, query map[string]string) (map[string]*BadgeVersion, error) {
 	badgesResponse := &BadgesResponse{}
-	resp, err := api.makeGetRequest(api.badgeURL, path, nil)
+	err := api.get(ctx, api.appClient, path, query, badgesResponse)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get twitch badges with %s", err)
+		return nil, fmt.Errorf("failed to get twitch badges with %w", err)
 	}
+	return badgesResponse.BadgeSets(), nil
+}
 
-	defer resp.Body.Close()
+type EmotesResponse struct {
+	Data     []*Emote `json:"data"`
+	Template string   `json:"template"`
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+type Emote struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Images     EmoteImages `json:"images"`
+	Tier       string      `json:"tier,omitempty"`
+	EmoteType  string      `json:"emote_type,omitempty"`
+	EmoteSetID string      `json:"emote_set_id,omitempty"`
+	Format     []string    `json:"format"`
+	Scale      []string    `json:"scale"`
+	ThemeMode  []string    `json:"theme_mode"`
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse body for badges with %w", err)
-	}
+type EmoteImages struct {
+	URL1X string `json:"url_1x"`
+	URL2X string `json:"url_2x"`
+	URL4X string `json:"url_4x"`
+}
+
+func (api *API) GetGlobalEmotes(ctx context.Context) ([]*Emote, error) {
+	return getEmotes(ctx, api, globalEmotesPath, nil)
+}
+
+func (c *Channel) GetEmotes(ctx context.Context, channelID string) ([]*Emote, error) {
+	return getEmotes(ctx, c.api, channelEmotesPath, map[string]string{"broadcaster_id": channelID})
+}
 
-	err = json.Unmarshal(body, &badgesResponse)
+func getEmotes(ctx context.Context, api *API, path string, query map[string]string) ([]*Emote, error) {
+	emotesResponse := &EmotesResponse{}
+	err := api.get(ctx, api.appClient, path, query, emotesResponse)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse json for badges with %w", err)
+		return nil, fmt.Errorf("failed to get twitch emotes with %w", err)
 	}
-	return badgesResponse.BadgeSet, nil
+	return emotesResponse.Data, nil
 }
 
-func (c *API) makeGetRequest(requestURL *url.URL, path string, query map[string]string) (*http.Response, error) {
-	return c.handleRequest(&request{
-		method:      "GET",
-		path:        path,
-		url:         requestURL,
-		queryParams: query,
-	})
+type CheermotesResponse struct {
+	Data []*Cheermote `json:"data"`
 }
 
-type request struct {
-	client      *http.Client
-	method      string
-	path        string
-	url         *url.URL
-	queryParams map[string]string
-	headers     map[string]string
-	body        io.Reader
+type Cheermote struct {
+	Prefix string           `json:"prefix"`
+	Tiers  []*CheermoteTier `json:"tiers"`
 }
 
-func (c *API) handleRequest(req *request) (*http.Response, error) {
-	u := *req.url
-	q := u.Query()
+// CheermoteTier images are keyed by background (dark or light), format
+// (animated or static) and scale (1, 1.5, 2, 3 or 4).
+type CheermoteTier struct {
+	ID      string                                  `json:"id"`
+	MinBits int                                     `json:"min_bits"`
+	Color   string                                  `json:"color"`
+	Images  map[string]map[string]map[string]string `json:"images"`
+}
 
-	for key, val := range req.queryParams {
-		q.Set(key, val)
+// Tier returns the highest tier unlocked by amount of bits.
+func (c *Cheermote) Tier(amount int) *CheermoteTier {
+	var tier *CheermoteTier
+	for _, t := range c.Tiers {
+		if t.MinBits <= amount && (tier == nil || t.MinBits > tier.MinBits) {
+			tier = t
+		}
 	}
+	return tier
+}
 
-	u.RawQuery = q.Encode()
-	u.Path = req.path
+// GetCheermotes returns the cheermotes available in a channel, which
+// includes the global ones.
+func (api *API) GetCheermotes(ctx context.Context, channelID string) ([]*Cheermote, error) {
+	queryParam := map[string]string{}
+	if channelID != "" {
+		queryParam["broadcaster_id"] = channelID
+	}
 
-	httpReq, err := http.NewRequest(req.method, u.String(), req.body)
+	responseData := &CheermotesResponse{}
+	err := api.get(ctx, api.appClient, cheermotesPath, queryParam, responseData)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request with %w", err)
+		return nil, fmt.Errorf("failed to get cheermotes with %w", err)
 	}
 
-	httpReq.Header.Add("Accept", "application/vnd.twitchtv.v5+json")
-	httpReq.Header.Add("Client-ID", c.clientID)
+	return responseData.Data, nil
+}
 
-	for key, val := range req.headers {
-		httpReq.Header.Add(key, val)
+// get makes a request to Helix and decodes the body into v. client is
+// authClient for endpoints that need the user token and appClient for
+// everything else.
+func (api *API) get(ctx context.Context, client *http.Client, path string, query map[string]string, v interface{}) error {
+	return api.do(ctx, client, "GET", path, query, nil, v)
+}
+
+// do sends body encoded as JSON when it isn't nil and decodes the response
+// into v when v isn't nil.
+func (api *API) do(ctx context.Context, client *http.Client, method, path string, query map[string]string, body, v interface{}) error {
+	req := &request{
+		client:      client,
+		method:      method,
+		url:         api.url,
+		path:        path,
+		queryParams: query,
 	}
 
-	client := req.client
-	if client == nil {
-		client = c.client
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode json with %w", err)
+		}
+		req.body = b
+		req.headers = map[string]string{"Content-Type": "application/json"}
 	}
 
-	resp, err := client.Do(httpReq)
+	resp, err := api.handleRequest(ctx, req)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request with %w", err)
+		return fmt.Errorf("failed to make request to twitch with %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		b, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to make with status code %d - %s", resp.StatusCode, string(b))
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read body with %w", err)
+	}
+
+	if v == nil {
+		return nil
 	}
-	return resp, nil
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return fmt.Errorf("failed to parse json with %w", err)
+	}
+	return nil
 }
 
 func New(conf *Config, c *cache.Cache) (*API, error) {
@@ -345,36 +483,48 @@ func New(conf *Config, c *cache.Cache) (*API, error) {
 		return nil, fmt.Errorf("twitch: Invalid URL %w", err)
 	}
 
-	badgeURL, err := url.Parse(conf.BadgeURL)
+	authURL, err := url.Parse(conf.AuthURL)
 
 	if err != nil {
 		return nil, fmt.Errorf("twitch: Invalid URL %w", err)
 	}
 
-	authURL, err := url.Parse(conf.AuthURL)
+	redirectURL, err := url.Parse(conf.RedirectURL)
 
 	if err != nil {
 		return nil, fmt.Errorf("twitch: Invalid URL %w", err)
 	}
 
-	redirectURL, err := url.Parse(conf.RedirectURL)
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("twitch: Invalid URL %w", err)
+	maxRetries := conf.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	retryDelay := conf.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultRetryDelay
 	}
 
 	api := &API{
-		url:         u,
-		secret:      conf.Secret,
-		clientID:    conf.ClientID,
-		badgeURL:    badgeURL,
-		authURL:     authURL,
-		redirectURL: redirectURL,
-		client:      &http.Client{},
+		url:            u,
+		secret:         conf.Secret,
+		clientID:       conf.ClientID,
+		authURL:        authURL,
+		redirectURL:    redirectURL,
+		client:         &http.Client{Timeout: timeout},
+		limits:         &rateLimit{},
+		maxRetries:     maxRetries,
+		baseRetryDelay: retryDelay,
 	}
 
 	api.Channel = &Channel{api: api}
 	tr := newTransport(api, c)
-	api.authClient = &http.Client{Transport: tr}
+	api.authClient = &http.Client{Transport: tr, Timeout: timeout}
+	api.appClient = &http.Client{Transport: newAppTransport(api), Timeout: timeout}
 	return api, nil
 }
@@ -2,9 +2,9 @@ package twitch_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
-	"fmt"
 	"io/ioutil"
 	"testing"
 
@@ -17,9 +17,11 @@ var update = flag.Bool("update", false, "update golden files")
 
 func TestInvalidURL(t *testing.T) {
 	_, err := twitch.New(&twitch.Config{
-		TwitchURL: "::////super_invalid_url",
-		BadgeURL:  "::////super_invalid_url",
-		ClientID:  "client-id",
+		TwitchURL:   "::////super_invalid_url",
+		ClientID:    "client-id",
+		Secret:      "secret",
+		AuthURL:     "http://localhost",
+		RedirectURL: "http://localhost/api/auth",
 	}, cache.New())
 
 	if err == nil {
@@ -27,9 +29,11 @@ func TestInvalidURL(t *testing.T) {
 	}
 
 	_, err = twitch.New(&twitch.Config{
-		TwitchURL: "invalid_url",
-		BadgeURL:  "::////super_invalid_url",
-		ClientID:  "client-id",
+		TwitchURL:   "http://localhost",
+		ClientID:    "client-id",
+		Secret:      "secret",
+		AuthURL:     "::////super_invalid_url",
+		RedirectURL: "http://localhost/api/auth",
 	}, cache.New())
 
 	if err == nil {
@@ -39,11 +43,13 @@ func TestInvalidURL(t *testing.T) {
 
 func TestGetUser(t *testing.T) {
 	channeID := "558843277"
-	testEndpoint := "/kraken/users/1"
-	api, ts := util.TestCreateClient(t, "user_response", testEndpoint, channeID)
+	queryParams := map[string]string{
+		"id": "239246205",
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "user_response", "/helix/users", channeID, queryParams, nil, 3600)
 	defer ts.Close()
 
-	user, err := api.GetUser("1")
+	user, err := api.GetUser(context.Background(), "239246205")
 
 	if err != nil {
 		t.Fatalf("failed to run GetUser with %s", err)
@@ -54,12 +60,15 @@ func TestGetUser(t *testing.T) {
 
 func TestTwitchChannel(t *testing.T) {
 	channeID := "558843277"
-	testEndpoint := fmt.Sprintf("/kraken/channels/%s/follows", channeID)
+	queryParams := map[string]string{
+		"broadcaster_id": channeID,
+		"first":          "1",
+	}
 
-	api, ts := util.TestCreateClient(t, "follower_response", testEndpoint, channeID)
+	api, ts := util.TestCreateClientQueryParams(t, "follower_response", "/helix/channels/followers", channeID, queryParams, nil, 3600)
 	defer ts.Close()
 
-	data, err := api.Channel.Followers(channeID, 1)
+	data, err := api.Channel.Followers(context.Background(), channeID, 1)
 
 	if err != nil {
 		t.Fatalf("Failed to %v", err)
@@ -69,23 +78,27 @@ func TestTwitchChannel(t *testing.T) {
 		t.Fatalf("Followers is not equal to 1, got: %d", len(data.Follows))
 	}
 
-	follower := data.Follows[0]
+	follower := data.Follows[0].User()
 	wantFollowerID := "565688138"
 
-	if follower.User.ID != wantFollowerID {
-		t.Errorf("Follower ID don't match, want: %s got: %s", wantFollowerID, follower.User.ID)
+	if follower.ID != wantFollowerID {
+		t.Errorf("Follower ID don't match, want: %s got: %s", wantFollowerID, follower.ID)
 	}
 
 	wantDisplayName := "angelicahill95"
 
-	if follower.User.DisplayName != wantDisplayName {
-		t.Errorf("Follower DisplayName don't match, want: %s got: %s", wantDisplayName, follower.User.DisplayName)
+	if follower.DisplayName != wantDisplayName {
+		t.Errorf("Follower DisplayName don't match, want: %s got: %s", wantDisplayName, follower.DisplayName)
+	}
+
+	if data.Total != 23 {
+		t.Errorf("Followers total don't match, want: 23 got: %d", data.Total)
 	}
 }
 
 func TestSubscriptionChannel(t *testing.T) {
 	channeID := "558843277"
-	testEndpoint := fmt.Sprintf("/kraken/channels/%s/subscriptions", channeID)
+	testEndpoint := "/helix/subscriptions"
 
 	for _, test := range []struct {
 		name        string
@@ -95,14 +108,14 @@ func TestSubscriptionChannel(t *testing.T) {
 		{
 			"valid token",
 			map[string]string{
-				"Authorization": "OAuth test_access_token",
+				"Authorization": "Bearer test_access_token",
 			},
 			3600,
 		},
 		{
 			"refresh token",
 			map[string]string{
-				"Authorization": "OAuth asdfasdf",
+				"Authorization": "Bearer asdfasdf",
 			},
 			-900,
 		},
@@ -111,7 +124,7 @@ func TestSubscriptionChannel(t *testing.T) {
 			api, ts := util.TestCreateClientAuth(t, "subscriptions_response", testEndpoint, channeID, test.wantHeaders, test.expiredAt)
 			defer ts.Close()
 
-			data, err := api.Channel.Subscribers(channeID, 1)
+			data, err := api.Channel.Subscribers(context.Background(), channeID, 1)
 
 			if err != nil {
 				t.Fatalf("Failed to %v", err)
@@ -121,17 +134,17 @@ func TestSubscriptionChannel(t *testing.T) {
 				t.Fatalf("Followers is not equal to 1, got: %d", len(data.Subscriptions))
 			}
 
-			subscriber := data.Subscriptions[0]
+			subscriber := data.Subscriptions[0].User()
 			wantFollowerID := "202015635"
 
-			if subscriber.User.ID != wantFollowerID {
-				t.Errorf("Follower ID don't match, want: %s got: %s", wantFollowerID, subscriber.User.ID)
+			if subscriber.ID != wantFollowerID {
+				t.Errorf("Follower ID don't match, want: %s got: %s", wantFollowerID, subscriber.ID)
 			}
 
 			wantDisplayName := "williamconnelly"
 
-			if subscriber.User.DisplayName != wantDisplayName {
-				t.Errorf("Follower DisplayName don't match, want: %s got: %s", wantDisplayName, subscriber.User.DisplayName)
+			if subscriber.DisplayName != wantDisplayName {
+				t.Errorf("Follower DisplayName don't match, want: %s got: %s", wantDisplayName, subscriber.DisplayName)
 			}
 		})
 	}
@@ -139,11 +152,13 @@ func TestSubscriptionChannel(t *testing.T) {
 
 func TestChannelGetBadges(t *testing.T) {
 	channeID := "558843277"
-	testEndpoint := fmt.Sprintf("/v1/badges/channels/%s/display", channeID)
-	api, ts := util.TestCreateClient(t, "channel_badges_response", testEndpoint, channeID)
+	queryParams := map[string]string{
+		"broadcaster_id": channeID,
+	}
+	api, ts := util.TestCreateClientQueryParams(t, "channel_badges_response", "/helix/chat/badges", channeID, queryParams, nil, 3600)
 	defer ts.Close()
 
-	badges, err := api.Channel.GetBadges(channeID)
+	badges, err := api.Channel.GetBadges(context.Background(), channeID)
 
 	if err != nil {
 		t.Fatalf("failed to get channel badges %s", err)
@@ -155,11 +170,11 @@ func TestChannelGetBadges(t *testing.T) {
 
 func TestGetGlobalBadges(t *testing.T) {
 	channeID := "558843277"
-	testEndpoint := "/v1/badges/global/display"
+	testEndpoint := "/helix/chat/badges/global"
 	api, ts := util.TestCreateClient(t, "global_badges_response", testEndpoint, channeID)
 	defer ts.Close()
 
-	badges, err := api.GetGlobalBadges()
+	badges, err := api.GetGlobalBadges(context.Background())
 
 	if err != nil {
 		t.Fatalf("failed to get channel badges %s", err)
@@ -171,14 +186,13 @@ func TestGetGlobalBadges(t *testing.T) {
 
 func TestBadRequest(t *testing.T) {
 	clientID := "test_client_id"
-	endpoint := "/kraken/users/1"
+	endpoint := "/helix/users"
 	ts := util.TestServer(clientID, endpoint, "user_response", t)
 
 	conf := &twitch.Config{
 		TwitchURL:   ts.URL,
 		Secret:      "not_really_secret",
 		ClientID:    "test",
-		BadgeURL:    ts.URL,
 		AuthURL:     ts.URL,
 		RedirectURL: ts.URL,
 	}
@@ -189,7 +203,7 @@ func TestBadRequest(t *testing.T) {
 		t.Fatalf("Failed to create API struct %v", err)
 	}
 
-	_, err = api.GetUser("1")
+	_, err = api.GetUser(context.Background(), "1")
 
 	if err == nil {
 		t.Error("expected an error but didn't get one")
@@ -209,7 +223,7 @@ func TestAuthUser(t *testing.T) {
 	api, ts := util.TestCreateClientQueryParams(t, "auth_response", testEndpoint, "", queryParams, nil, 100)
 	defer ts.Close()
 
-	resp, err := api.AuthUser(code)
+	resp, err := api.AuthUser(context.Background(), code)
 
 	if err != nil {
 		t.Fatalf("Failed to get user access token with: %s", err)
@@ -235,7 +249,7 @@ func TestAuthTokenRefresh(t *testing.T) {
 	api, ts := util.TestCreateClientQueryParams(t, "refresh_response", testEndpoint, "", queryParams, nil, 100)
 	defer ts.Close()
 
-	resp, err := api.AuthTokenRefresh(code)
+	resp, err := api.AuthTokenRefresh(context.Background(), code)
 
 	if err != nil {
 		t.Fatalf("Failed to get user access token with: %s", err)
@@ -272,3 +286,122 @@ func testCompareGoldenFiles(wantFilename string, v interface{}, t *testing.T) {
 		t.Errorf("User doesn't match got (%s), want (%s)", string(got), string(want))
 	}
 }
+
+func TestGetCheermotes(t *testing.T) {
+	channeID := "558843277"
+	queryParams := map[string]string{
+		"broadcaster_id": channeID,
+	}
+
+	api, ts := util.TestCreateClientQueryParams(t, "cheermotes_response", "/helix/bits/cheermotes", channeID, queryParams, nil, 3600)
+	defer ts.Close()
+
+	cheermotes, err := api.GetCheermotes(context.Background(), channeID)
+	if err != nil {
+		t.Fatalf("failed to get cheermotes with %s", err)
+	}
+
+	if len(cheermotes) != 2 {
+		t.Fatalf("cheermotes length doesn't match want: 2, got: %d", len(cheermotes))
+	}
+
+	for _, test := range []struct {
+		amount      int
+		wantMinBits int
+	}{
+		{1, 1},
+		{99, 1},
+		{100, 100},
+		{999, 100},
+		{5000, 1000},
+	} {
+		tier := cheermotes[0].Tier(test.amount)
+		if tier == nil {
+			t.Fatalf("expected a tier for %d bits", test.amount)
+		}
+
+		if tier.MinBits != test.wantMinBits {
+			t.Errorf("tier for %d bits doesn't match want: %d, got: %d", test.amount, test.wantMinBits, tier.MinBits)
+		}
+	}
+
+	if tier := cheermotes[0].Tier(0); tier != nil {
+		t.Errorf("expected no tier for 0 bits, got %d", tier.MinBits)
+	}
+
+	wantImage := "https://d3aqoihi2n8ty8.cloudfront.net/actions/cheer/dark/animated/100/2.gif"
+	if got := cheermotes[0].Tier(100).Images["dark"]["animated"]["2"]; got != wantImage {
+		t.Errorf("image doesn't match want: %s, got: %s", wantImage, got)
+	}
+}
+
+func TestGetEmotes(t *testing.T) {
+	channeID := "558843277"
+
+	for _, test := range []struct {
+		name         string
+		responsePath string
+		endpoint     string
+		queryParams  map[string]string
+		wantID       string
+		wantName     string
+		wantImage    string
+	}{
+		{
+			"global emotes",
+			"global_emotes_response",
+			"/helix/chat/emotes/global",
+			nil,
+			"25",
+			"Kappa",
+			"https://static-cdn.jtvnw.net/emoticons/v2/25/static/light/2.0",
+		},
+		{
+			"channel emotes",
+			"channel_emotes_response",
+			"/helix/chat/emotes",
+			map[string]string{"broadcaster_id": channeID},
+			"303365132",
+			"miguel156Hero",
+			"https://static-cdn.jtvnw.net/emoticons/v2/303365132/static/light/2.0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			api, ts := util.TestCreateClientQueryParams(t, test.responsePath, test.endpoint, channeID, test.queryParams, nil, 3600)
+			defer ts.Close()
+
+			var (
+				emotes []*twitch.Emote
+				err    error
+			)
+
+			if test.queryParams == nil {
+				emotes, err = api.GetGlobalEmotes(context.Background())
+			} else {
+				emotes, err = api.Channel.GetEmotes(context.Background(), channeID)
+			}
+
+			if err != nil {
+				t.Fatalf("failed to get emotes with %s", err)
+			}
+
+			if len(emotes) != 1 {
+				t.Fatalf("emotes length doesn't match want: 1, got: %d", len(emotes))
+			}
+
+			emote := emotes[0]
+
+			if emote.ID != test.wantID {
+				t.Errorf("emote ID doesn't match want: %s, got: %s", test.wantID, emote.ID)
+			}
+
+			if emote.Name != test.wantName {
+				t.Errorf("emote name doesn't match want: %s, got: %s", test.wantName, emote.Name)
+			}
+
+			if emote.Images.URL2X != test.wantImage {
+				t.Errorf("emote image doesn't match want: %s, got: %s", test.wantImage, emote.Images.URL2X)
+			}
+		})
+	}
+}
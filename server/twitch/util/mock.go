@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/miguel250/streaming-setup/server/cache"
@@ -28,7 +29,6 @@ func TestCreateClientQueryParams(t *testing.T, responsePath string, endpoint str
 	conf := &twitch.Config{
 		TwitchURL:   ts.URL,
 		ClientID:    clientID,
-		BadgeURL:    ts.URL,
 		AuthURL:     ts.URL,
 		RedirectURL: "http://localhost/api/auth",
 		Secret:      "nyo51xcdrerl8z9m56w9w6wg",
@@ -49,6 +49,42 @@ func TestCreateClientQueryParams(t *testing.T, responsePath string, endpoint str
 	return api, ts
 }
 
+// TestCreateClientWithMux creates a client for the handlers in mux with a
+// user token that expires in an hour, the server is closed when the test
+// ends. /oauth2/token issues app-token when mux doesn't handle it, configure
+// can change the configuration before the client is created.
+func TestCreateClientWithMux(t *testing.T, mux *http.ServeMux, configure func(conf *twitch.Config)) (*twitch.API, *cache.Cache) {
+	if _, pattern := mux.Handler(&http.Request{Method: "POST", URL: &url.URL{Path: "/oauth2/token"}}); pattern == "" {
+		mux.HandleFunc("/oauth2/token", func(rw http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(rw, `{"access_token":"app-token","expires_in":3600,"token_type":"bearer"}`)
+		})
+	}
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	conf := &twitch.Config{
+		TwitchURL:   ts.URL,
+		AuthURL:     ts.URL,
+		RedirectURL: "http://localhost/api/auth",
+		ClientID:    "test_client_id",
+		Secret:      "test_secret",
+	}
+
+	if configure != nil {
+		configure(conf)
+	}
+
+	c := cache.New()
+	c.SetAccessToken("test_access_token", "test_refresh_token", 3600)
+
+	api, err := twitch.New(conf, c)
+	if err != nil {
+		t.Fatalf("Failed to create API struct %v", err)
+	}
+	return api, c
+}
+
 func TestServer(clientID string, endpoint string, responsePath string, t *testing.T) *httptest.Server {
 	return TestServerQueryParam(clientID, endpoint, responsePath, t, nil, nil)
 }
@@ -74,8 +110,7 @@ func handler(t *testing.T, responsePath string, clientID string, wantQueryParams
 		}
 
 		wantHanders := map[string]string{
-			"Accept":    "application/vnd.twitchtv.v5+json",
-			"Client-ID": clientID,
+			"Client-Id": clientID,
 		}
 
 		for key, val := range wantHanders {
@@ -0,0 +1,79 @@
+package twitchemotes
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	BTTVURL = "https://api.betterttv.net"
+
+	bttvGlobalPath  = "/3/cached/emotes/global"
+	bttvChannelPath = "/3/cached/users/twitch/%s"
+	bttvImageURL    = "https://cdn.betterttv.net/emote/%s/2x"
+)
+
+// BTTV loads emotes from BetterTTV.
+type BTTV struct {
+	url *url.URL
+}
+
+type bttvEmote struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+}
+
+type bttvChannel struct {
+	ChannelEmotes []*bttvEmote `json:"channelEmotes"`
+	SharedEmotes  []*bttvEmote `json:"sharedEmotes"`
+}
+
+func (b *BTTV) Name() string {
+	return "bttv"
+}
+
+func (b *BTTV) GlobalEmotes() ([]*Emote, error) {
+	emotes := make([]*bttvEmote, 0)
+
+	if err := getJSON(b.url, bttvGlobalPath, &emotes); err != nil {
+		return nil, fmt.Errorf("bttv: failed to get global emotes with %w", err)
+	}
+	return b.convert(emotes), nil
+}
+
+func (b *BTTV) ChannelEmotes(channelID string) ([]*Emote, error) {
+	channel := &bttvChannel{}
+	err := getJSON(b.url, fmt.Sprintf(bttvChannelPath, channelID), channel)
+
+	if err == errNotFound {
+		return []*Emote{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("bttv: failed to get emotes for channel %s with %w", channelID, err)
+	}
+	return b.convert(append(channel.ChannelEmotes, channel.SharedEmotes...)), nil
+}
+
+func (b *BTTV) convert(emotes []*bttvEmote) []*Emote {
+	result := make([]*Emote, 0, len(emotes))
+
+	for _, emote := range emotes {
+		result = append(result, &Emote{
+			ID:       emote.ID,
+			Code:     emote.Code,
+			URL:      fmt.Sprintf(bttvImageURL, emote.ID),
+			Provider: b.Name(),
+		})
+	}
+	return result
+}
+
+// NewBTTV creates a BetterTTV provider, urlStr is usually BTTVURL.
+func NewBTTV(urlStr string) (*BTTV, error) {
+	u, err := parseProviderURL("bttv", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return &BTTV{url: u}, nil
+}
@@ -0,0 +1,110 @@
+package twitchemotes
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+const (
+	FFZURL = "https://api.frankerfacez.com"
+
+	ffzGlobalPath  = "/v1/set/global"
+	ffzChannelPath = "/v1/room/id/%s"
+)
+
+// FFZ loads emotes from FrankerFaceZ.
+type FFZ struct {
+	url *url.URL
+}
+
+type ffzEmote struct {
+	ID   int               `json:"id"`
+	Name string            `json:"name"`
+	URLs map[string]string `json:"urls"`
+}
+
+type ffzSet struct {
+	Emoticons []*ffzEmote `json:"emoticons"`
+}
+
+type ffzGlobal struct {
+	DefaultSets []int              `json:"default_sets"`
+	Sets        map[string]*ffzSet `json:"sets"`
+}
+
+type ffzRoom struct {
+	Room struct {
+		Set int `json:"set"`
+	} `json:"room"`
+	Sets map[string]*ffzSet `json:"sets"`
+}
+
+func (f *FFZ) Name() string {
+	return "ffz"
+}
+
+// GlobalEmotes only returns the default sets, the other global sets are
+// for specific users.
+func (f *FFZ) GlobalEmotes() ([]*Emote, error) {
+	global := &ffzGlobal{}
+
+	if err := getJSON(f.url, ffzGlobalPath, global); err != nil {
+		return nil, fmt.Errorf("ffz: failed to get global emotes with %w", err)
+	}
+
+	sort.Ints(global.DefaultSets)
+	emotes := make([]*Emote, 0)
+
+	for _, id := range global.DefaultSets {
+		emotes = append(emotes, f.convert(global.Sets[strconv.Itoa(id)])...)
+	}
+	return emotes, nil
+}
+
+func (f *FFZ) ChannelEmotes(channelID string) ([]*Emote, error) {
+	room := &ffzRoom{}
+	err := getJSON(f.url, fmt.Sprintf(ffzChannelPath, channelID), room)
+
+	if err == errNotFound {
+		return []*Emote{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("ffz: failed to get emotes for channel %s with %w", channelID, err)
+	}
+	return f.convert(room.Sets[strconv.Itoa(room.Room.Set)]), nil
+}
+
+func (f *FFZ) convert(set *ffzSet) []*Emote {
+	if set == nil {
+		return []*Emote{}
+	}
+
+	result := make([]*Emote, 0, len(set.Emoticons))
+
+	for _, emote := range set.Emoticons {
+		image := emote.URLs["2"]
+		if image == "" {
+			image = emote.URLs["1"]
+		}
+
+		result = append(result, &Emote{
+			ID:       strconv.Itoa(emote.ID),
+			Code:     emote.Name,
+			URL:      image,
+			Provider: f.Name(),
+		})
+	}
+	return result
+}
+
+// NewFFZ creates a FrankerFaceZ provider, urlStr is usually FFZURL.
+func NewFFZ(urlStr string) (*FFZ, error) {
+	u, err := parseProviderURL("ffz", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return &FFZ{url: u}, nil
+}
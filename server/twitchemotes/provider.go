@@ -0,0 +1,81 @@
+package twitchemotes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// errNotFound is returned by providers when a channel doesn't use the
+// service, it is treated as a channel without emotes.
+var errNotFound = errors.New("twitch emotes: not found")
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Emote from a third-party service that is used by writing its code in chat.
+type Emote struct {
+	ID       string `json:"id"`
+	Code     string `json:"code"`
+	URL      string `json:"url"`
+	Provider string `json:"provider"`
+}
+
+// Provider loads emotes from a third-party emote service.
+type Provider interface {
+	Name() string
+	GlobalEmotes() ([]*Emote, error)
+	ChannelEmotes(channelID string) ([]*Emote, error)
+}
+
+func parseProviderURL(name, urlStr string) (*url.URL, error) {
+	u, err := url.Parse(urlStr)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: Invalid URL %w", name, err)
+	}
+	return u, nil
+}
+
+func getJSON(base *url.URL, path string, data interface{}) error {
+	u := *base
+	u.Path = path
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to create request with %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("failed to get emotes with %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get emotes with status code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse body for emotes with %w", err)
+	}
+
+	err = json.Unmarshal(body, data)
+
+	if err != nil {
+		return fmt.Errorf("failed to parse json for emotes with %w", err)
+	}
+	return nil
+}
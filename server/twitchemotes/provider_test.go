@@ -0,0 +1,139 @@
+package twitchemotes_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/miguel250/streaming-setup/server/twitchemotes"
+	"github.com/miguel250/streaming-setup/server/twitchemotes/util"
+)
+
+const channelID = "558843277"
+
+func createProvider(t *testing.T, name string) twitchemotes.Provider {
+	var (
+		provider twitchemotes.Provider
+		err      error
+	)
+
+	switch name {
+	case "bttv":
+		ts := util.TestCreateProviderServer(t, map[string]string{
+			"/3/cached/emotes/global":             "bttv_global_response",
+			"/3/cached/users/twitch/" + channelID: "bttv_channel_response",
+		})
+		provider, err = twitchemotes.NewBTTV(ts.URL)
+	case "ffz":
+		ts := util.TestCreateProviderServer(t, map[string]string{
+			"/v1/set/global":           "ffz_global_response",
+			"/v1/room/id/" + channelID: "ffz_channel_response",
+		})
+		provider, err = twitchemotes.NewFFZ(ts.URL)
+	case "7tv":
+		ts := util.TestCreateProviderServer(t, map[string]string{
+			"/v3/emote-sets/global":         "seventv_global_response",
+			"/v3/users/twitch/" + channelID: "seventv_channel_response",
+		})
+		provider, err = twitchemotes.NewSevenTV(ts.URL)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to create %s provider with %s", name, err)
+	}
+	return provider
+}
+
+func TestProviders(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		wantGlobal  []*twitchemotes.Emote
+		wantChannel []*twitchemotes.Emote
+	}{
+		{
+			"bttv",
+			[]*twitchemotes.Emote{
+				{ID: "54fa925e01e468494b85b54d", Code: "OhMyGoodness", URL: "https://cdn.betterttv.net/emote/54fa925e01e468494b85b54d/2x", Provider: "bttv"},
+				{ID: "54fa8f1401e468494b85b537", Code: ":tf:", URL: "https://cdn.betterttv.net/emote/54fa8f1401e468494b85b537/2x", Provider: "bttv"},
+			},
+			[]*twitchemotes.Emote{
+				{ID: "5f5b8f1b6b2b3a0b2c5e1a3c", Code: "miguelDance", URL: "https://cdn.betterttv.net/emote/5f5b8f1b6b2b3a0b2c5e1a3c/2x", Provider: "bttv"},
+				{ID: "5e76d338d6581c3724c0f0b2", Code: "catJAM", URL: "https://cdn.betterttv.net/emote/5e76d338d6581c3724c0f0b2/2x", Provider: "bttv"},
+			},
+		},
+		{
+			"ffz",
+			[]*twitchemotes.Emote{
+				{ID: "25927", Code: "CatBag", URL: "https://cdn.frankerfacez.com/emote/25927/2", Provider: "ffz"},
+			},
+			[]*twitchemotes.Emote{
+				{ID: "381875", Code: "miguelPog", URL: "https://cdn.frankerfacez.com/emote/381875/1", Provider: "ffz"},
+				{ID: "25928", Code: "OhMyGoodness", URL: "https://cdn.frankerfacez.com/emote/25928/2", Provider: "ffz"},
+			},
+		},
+		{
+			"7tv",
+			[]*twitchemotes.Emote{
+				{ID: "60ae958e229664e8667aea38", Code: "EZ", URL: "https://cdn.7tv.app/emote/60ae958e229664e8667aea38/2x.webp", Provider: "7tv"},
+			},
+			[]*twitchemotes.Emote{
+				{ID: "603cb219c20d020014423c34", Code: "catJAM", URL: "https://cdn.7tv.app/emote/603cb219c20d020014423c34/2x.webp", Provider: "7tv"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			provider := createProvider(t, test.name)
+
+			if provider.Name() != test.name {
+				t.Errorf("Provider name doesn't match want: %s, got: %s", test.name, provider.Name())
+			}
+
+			global, err := provider.GlobalEmotes()
+			if err != nil {
+				t.Fatalf("failed to get global emotes with %s", err)
+			}
+
+			if !reflect.DeepEqual(global, test.wantGlobal) {
+				t.Errorf("Global emotes don't match")
+				for _, emote := range global {
+					t.Logf("got: %+v", emote)
+				}
+			}
+
+			channel, err := provider.ChannelEmotes(channelID)
+			if err != nil {
+				t.Fatalf("failed to get channel emotes with %s", err)
+			}
+
+			if !reflect.DeepEqual(channel, test.wantChannel) {
+				t.Errorf("Channel emotes don't match")
+				for _, emote := range channel {
+					t.Logf("got: %+v", emote)
+				}
+			}
+
+			missing, err := provider.ChannelEmotes("0001")
+			if err != nil {
+				t.Fatalf("channel without emotes shouldn't fail, got: %s", err)
+			}
+
+			if len(missing) != 0 {
+				t.Errorf("Expected no emotes for unknown channel got: %d", len(missing))
+			}
+		})
+	}
+}
+
+func TestProviderInvalidResponse(t *testing.T) {
+	ts := util.TestCreateProviderServer(t, map[string]string{
+		"/3/cached/emotes/global": "invalid_response",
+	})
+
+	provider, err := twitchemotes.NewBTTV(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to create bttv provider with %s", err)
+	}
+
+	if _, err := provider.GlobalEmotes(); err == nil {
+		t.Error("expected an error for invalid json but got none")
+	}
+}
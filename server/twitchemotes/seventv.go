@@ -0,0 +1,99 @@
+package twitchemotes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	SevenTVURL = "https://7tv.io"
+
+	sevenTVGlobalPath  = "/v3/emote-sets/global"
+	sevenTVChannelPath = "/v3/users/twitch/%s"
+	sevenTVImageFile   = "2x.webp"
+)
+
+// SevenTV loads emotes from 7TV.
+type SevenTV struct {
+	url *url.URL
+}
+
+type sevenTVEmote struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Data struct {
+		Host struct {
+			URL string `json:"url"`
+		} `json:"host"`
+	} `json:"data"`
+}
+
+type sevenTVSet struct {
+	Emotes []*sevenTVEmote `json:"emotes"`
+}
+
+type sevenTVUser struct {
+	EmoteSet *sevenTVSet `json:"emote_set"`
+}
+
+func (s *SevenTV) Name() string {
+	return "7tv"
+}
+
+func (s *SevenTV) GlobalEmotes() ([]*Emote, error) {
+	set := &sevenTVSet{}
+
+	if err := getJSON(s.url, sevenTVGlobalPath, set); err != nil {
+		return nil, fmt.Errorf("7tv: failed to get global emotes with %w", err)
+	}
+	return s.convert(set), nil
+}
+
+func (s *SevenTV) ChannelEmotes(channelID string) ([]*Emote, error) {
+	user := &sevenTVUser{}
+	err := getJSON(s.url, fmt.Sprintf(sevenTVChannelPath, channelID), user)
+
+	if err == errNotFound {
+		return []*Emote{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("7tv: failed to get emotes for channel %s with %w", channelID, err)
+	}
+	return s.convert(user.EmoteSet), nil
+}
+
+func (s *SevenTV) convert(set *sevenTVSet) []*Emote {
+	if set == nil {
+		return []*Emote{}
+	}
+
+	result := make([]*Emote, 0, len(set.Emotes))
+
+	for _, emote := range set.Emotes {
+		host := emote.Data.Host.URL
+
+		// 7TV returns protocol relative URLs like //cdn.7tv.app/emote/id
+		if strings.HasPrefix(host, "//") {
+			host = "https:" + host
+		}
+
+		result = append(result, &Emote{
+			ID:       emote.ID,
+			Code:     emote.Name,
+			URL:      fmt.Sprintf("%s/%s", host, sevenTVImageFile),
+			Provider: s.Name(),
+		})
+	}
+	return result
+}
+
+// NewSevenTV creates a 7TV provider, urlStr is usually SevenTVURL.
+func NewSevenTV(urlStr string) (*SevenTV, error) {
+	u, err := parseProviderURL("7tv", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return &SevenTV{url: u}, nil
+}
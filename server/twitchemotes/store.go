@@ -0,0 +1,197 @@
+package twitchemotes
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is how often the store reloads emotes, channels
+// change their emote sets while streaming.
+const DefaultRefreshInterval = 30 * time.Minute
+
+// providerEmotes are the emotes loaded from one provider by code.
+type providerEmotes struct {
+	global   map[string]*Emote
+	channels map[string]map[string]*Emote
+}
+
+// Store keeps the emotes from every provider in memory so chat messages
+// can be matched without calling the services.
+type Store struct {
+	sync.RWMutex
+	providers []Provider
+	emotes    map[string]*providerEmotes
+	channels  []string
+	shutdown  chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStore creates a store for the providers, when two providers have an
+// emote with the same code the first provider wins.
+func NewStore(providers []Provider, channelIDs ...string) *Store {
+	store := &Store{
+		providers: providers,
+		emotes:    make(map[string]*providerEmotes),
+		shutdown:  make(chan struct{}),
+	}
+
+	for _, provider := range providers {
+		store.emotes[provider.Name()] = &providerEmotes{
+			global:   make(map[string]*Emote),
+			channels: make(map[string]map[string]*Emote),
+		}
+	}
+
+	for _, channelID := range channelIDs {
+		store.addChannel(channelID)
+	}
+	return store
+}
+
+// Lookup finds an emote by its exact code, channel emotes take priority
+// over global emotes.
+func (s *Store) Lookup(channelID, code string) (*Emote, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, provider := range s.providers {
+		if emote, ok := s.emotes[provider.Name()].channels[channelID][code]; ok {
+			return emote, true
+		}
+	}
+
+	for _, provider := range s.providers {
+		if emote, ok := s.emotes[provider.Name()].global[code]; ok {
+			return emote, true
+		}
+	}
+	return nil, false
+}
+
+// AddChannel loads the emotes of a channel that wasn't known when the
+// store was created.
+func (s *Store) AddChannel(channelID string) {
+	if !s.addChannel(channelID) {
+		return
+	}
+
+	go func() {
+		for _, provider := range s.providers {
+			if err := s.loadChannel(provider, channelID); err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+}
+
+func (s *Store) addChannel(channelID string) bool {
+	if channelID == "" {
+		return false
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, id := range s.channels {
+		if id == channelID {
+			return false
+		}
+	}
+
+	s.channels = append(s.channels, channelID)
+	return true
+}
+
+// Load gets the global and channel emotes from every provider. A provider
+// that fails keeps the emotes from the last successful load.
+func (s *Store) Load() error {
+	s.RLock()
+	channels := append([]string{}, s.channels...)
+	s.RUnlock()
+
+	errs := make([]string, 0)
+
+	for _, provider := range s.providers {
+		if err := s.loadGlobal(provider); err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		for _, channelID := range channels {
+			if err := s.loadChannel(provider, channelID); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("twitch emotes: failed to load emotes with %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Start reloads the emotes every interval until Close is called.
+func (s *Store) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Load(); err != nil {
+					log.Println(err)
+				}
+			case <-s.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Store) Close() {
+	s.closeOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+func (s *Store) loadGlobal(provider Provider) error {
+	emotes, err := provider.GlobalEmotes()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.emotes[provider.Name()].global = byCode(emotes)
+	return nil
+}
+
+func (s *Store) loadChannel(provider Provider, channelID string) error {
+	emotes, err := provider.ChannelEmotes(channelID)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.emotes[provider.Name()].channels[channelID] = byCode(emotes)
+	return nil
+}
+
+func byCode(emotes []*Emote) map[string]*Emote {
+	result := make(map[string]*Emote, len(emotes))
+
+	for _, emote := range emotes {
+		if _, ok := result[emote.Code]; !ok {
+			result[emote.Code] = emote
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package twitchemotes_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miguel250/streaming-setup/server/twitchemotes"
+)
+
+func TestStoreLookup(t *testing.T) {
+	store := twitchemotes.NewStore([]twitchemotes.Provider{
+		createProvider(t, "bttv"),
+		createProvider(t, "ffz"),
+		createProvider(t, "7tv"),
+	}, channelID)
+
+	if err := store.Load(); err != nil {
+		t.Fatalf("failed to load emotes with %s", err)
+	}
+
+	for _, test := range []struct {
+		name, channelID, code, wantProvider, wantID string
+	}{
+		{"global emote", channelID, "EZ", "7tv", "60ae958e229664e8667aea38"},
+		{"channel emote wins over global", channelID, "OhMyGoodness", "ffz", "25928"},
+		{"first provider wins", channelID, "catJAM", "bttv", "5e76d338d6581c3724c0f0b2"},
+		{"other channels only get globals", "0001", "OhMyGoodness", "bttv", "54fa925e01e468494b85b54d"},
+		{"codes are case sensitive", channelID, "ez", "", ""},
+		{"missing emote", channelID, "Kappa", "", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			emote, ok := store.Lookup(test.channelID, test.code)
+
+			if test.wantProvider == "" {
+				if ok {
+					t.Fatalf("expected no emote for %s, got: %+v", test.code, emote)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("expected emote for %s", test.code)
+			}
+
+			if emote.Provider != test.wantProvider || emote.ID != test.wantID {
+				t.Errorf("Emote doesn't match want: %s/%s, got: %s/%s", test.wantProvider, test.wantID, emote.Provider, emote.ID)
+			}
+		})
+	}
+}
+
+func TestStoreKeepsEmotesOnFailure(t *testing.T) {
+	var failing int32
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(rw, "", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeFile(rw, req, "testdata/seventv_global_response.json")
+	}))
+	defer ts.Close()
+
+	provider, err := twitchemotes.NewSevenTV(ts.URL)
+	if err != nil {
+		t.Fatalf("failed to create 7tv provider with %s", err)
+	}
+
+	store := twitchemotes.NewStore([]twitchemotes.Provider{provider})
+
+	if err := store.Load(); err != nil {
+		t.Fatalf("failed to load emotes with %s", err)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+
+	if err := store.Load(); err == nil {
+		t.Fatal("expected an error when the provider fails")
+	}
+
+	if _, ok := store.Lookup(channelID, "EZ"); !ok {
+		t.Error("emotes from the last successful load should be kept")
+	}
+}
+
+func TestStoreAddChannel(t *testing.T) {
+	store := twitchemotes.NewStore([]twitchemotes.Provider{createProvider(t, "ffz")})
+	store.AddChannel(channelID)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := store.Lookup(channelID, "miguelPog"); ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("channel emotes weren't loaded after adding the channel")
+}
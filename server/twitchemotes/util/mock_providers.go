@@ -0,0 +1,39 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCreateProviderServer serves testdata/<name>.json for every path in
+// responses, any other path returns not found like the emote services do
+// for channels without emotes.
+func TestCreateProviderServer(t *testing.T, responses map[string]string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		responsePath, ok := responses[req.URL.Path]
+
+		if !ok {
+			http.Error(rw, "", http.StatusNotFound)
+			return
+		}
+
+		body, err := ioutil.ReadFile(fmt.Sprintf("testdata/%s.json", responsePath))
+
+		if err != nil {
+			t.Errorf("Failed to get response with %s", err)
+			http.Error(rw, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprintln(rw, string(body))
+	}))
+
+	t.Cleanup(func() {
+		ts.Close()
+	})
+
+	return ts
+}